@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -161,3 +162,81 @@ func (a *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
 		panic("channel is full")
 	}
 }
+
+// gatherAccumulator wraps the accumulator of a single gather, dropping the
+// metrics added after the gather was abandoned.
+type gatherAccumulator struct {
+	telegraf.Accumulator
+	abandoned int32
+}
+
+func (a *gatherAccumulator) abandon() {
+	atomic.StoreInt32(&a.abandoned, 1)
+}
+
+func (a *gatherAccumulator) isAbandoned() bool {
+	return atomic.LoadInt32(&a.abandoned) == 1
+}
+
+func (a *gatherAccumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	if !a.isAbandoned() {
+		a.Accumulator.AddFields(measurement, fields, tags, t...)
+	}
+}
+
+func (a *gatherAccumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	if !a.isAbandoned() {
+		a.Accumulator.AddGauge(measurement, fields, tags, t...)
+	}
+}
+
+func (a *gatherAccumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	if !a.isAbandoned() {
+		a.Accumulator.AddCounter(measurement, fields, tags, t...)
+	}
+}
+
+func (a *gatherAccumulator) AddSummary(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	if !a.isAbandoned() {
+		a.Accumulator.AddSummary(measurement, fields, tags, t...)
+	}
+}
+
+func (a *gatherAccumulator) AddHistogram(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	if !a.isAbandoned() {
+		a.Accumulator.AddHistogram(measurement, fields, tags, t...)
+	}
+}
+
+func (a *gatherAccumulator) AddMetric(m telegraf.Metric) {
+	if a.isAbandoned() {
+		m.Drop()
+		return
+	}
+	a.Accumulator.AddMetric(m)
+}
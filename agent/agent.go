@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime"
@@ -13,6 +14,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/redact"
 	"github.com/influxdata/telegraf/models"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
@@ -20,16 +22,43 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	// The state of a running agent, used when reloading the configuration.
+	mu        sync.Mutex
+	ctx       context.Context
+	startTime time.Time
+	iu        *inputUnit
+	ou        *outputUnit
+	pl        *pipelineUnit
+
+	// virtualClock makes the aggregators follow the metric timestamps
+	// instead of the wall clock.
+	virtualClock bool
+
+	// started is closed once Run has started all plugins.
+	started chan struct{}
+
+	// abandoned holds the result of the abandoned gathers that have not
+	// returned yet, by input.
+	abandonedMu sync.Mutex
+	abandoned   map[*models.RunningInput]<-chan error
 }
 
 // NewAgent returns an Agent for the given Config.
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
-		Config: config,
+		Config:  config,
+		started: make(chan struct{}),
 	}
 	return a, nil
 }
 
+// Started returns a channel that is closed once Run has initialized and
+// started all plugins.
+func (a *Agent) Started() <-chan struct{} {
+	return a.started
+}
+
 // inputUnit is a group of input plugins and the shared channel they write to.
 //
 // ┌───────┐
@@ -42,14 +71,21 @@ func NewAgent(config *config.Config) (*Agent, error) {
 // │ Input │───┘
 // └───────┘
 type inputUnit struct {
-	dst    chan<- telegraf.Metric
-	inputs []*models.RunningInput
+	sync.Mutex
+	dst     chan<- telegraf.Metric
+	inputs  []*models.RunningInput
+	runners map[*models.RunningInput]*runner
+
+	// removing counts the inputs removed by a reload that are still
+	// stopping, dst is closed only after they stopped.
+	removing sync.WaitGroup
 }
 
 //  ______     ┌───────────┐     ______
 // ()_____)──▶ │ Processor │──▶ ()_____)
 //             └───────────┘
 type processorUnit struct {
+	sync.Mutex
 	src       <-chan telegraf.Metric
 	dst       chan<- telegraf.Metric
 	processor *models.RunningProcessor
@@ -77,8 +113,31 @@ type aggregatorUnit struct {
 	aggregators []*models.RunningAggregator
 }
 
+// pipelineUnit is the chain of processors and aggregators between two
+// channels.  Metrics are processed, aggregated and the aggregates processed
+// again by a copy of the processors.
+//
+//  ______     ┌────────────┐    ┌─────────────┐    ┌────────────┐     ______
+// ()_____)──▶ │ Processors │──▶ │ Aggregators │──▶ │ Processors │──▶ ()_____)
+//             └────────────┘    └─────────────┘    └────────────┘
+type pipelineUnit struct {
+	pu  []*processorUnit
+	au  *aggregatorUnit
+	apu []*processorUnit
+}
+
+// outputPipeline is a named pipeline of the outputs subscribed to it.
+type outputPipeline struct {
+	name string
+	src  chan<- telegraf.Metric
+	dst  <-chan telegraf.Metric
+	unit *pipelineUnit
+}
+
 // outputUnit is a group of Outputs and their source channel.  Metrics on the
-// channel are written to all outputs.
+// channel are written to all outputs without a pipeline and to each named
+// pipeline, which in turn writes to the outputs subscribed to it.  Outputs
+// used as dead-letter output only receive the metrics rejected by others.
 //
 //                            ┌────────┐
 //                       ┌──▶ │ Output │
@@ -86,12 +145,35 @@ type aggregatorUnit struct {
 //  ______     ┌─────┐   │    ┌────────┐
 // ()_____)──▶ │ Fan │───┼──▶ │ Output │
 //             └─────┘   │    └────────┘
-//                       │    ┌────────┐
-//                       └──▶ │ Output │
-//                            └────────┘
+//                       │    ┌──────────┐    ┌────────┐
+//                       └──▶ │ Pipeline │──▶ │ Output │
+//                            └──────────┘    └────────┘
 type outputUnit struct {
-	src     <-chan telegraf.Metric
-	outputs []*models.RunningOutput
+	sync.RWMutex
+	src       <-chan telegraf.Metric
+	outputs   []*models.RunningOutput
+	runners   map[*models.RunningOutput]*runner
+	pipelines []*outputPipeline
+
+	// deadLetters are the outputs receiving only rejected metrics.
+	deadLetters map[*models.RunningOutput]bool
+
+	// ctx is done once all metrics have been received from the source.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// runner is the goroutine running a single input or output, it can be
+// stopped on its own when the configuration is reloaded.
+type runner struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stop cancels the runner and waits for it to return.
+func (r *runner) stop() {
+	r.cancel()
+	<-r.done
 }
 
 // Run starts and runs the Agent until the context is done.
@@ -107,6 +189,11 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
+	err = a.initOutputs(a.Config.Outputs)
+	if err != nil {
+		return err
+	}
+
 	startTime := time.Now()
 
 	log.Printf("D! [agent] Connecting outputs")
@@ -115,29 +202,9 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		aggC := next
-		if len(a.Config.AggProcessors) != 0 {
-			aggC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au, err = a.startAggregators(aggC, next, a.Config.Aggregators)
-		if err != nil {
-			return err
-		}
-	}
-
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
+	next, pl, err := a.startPipeline(next, "")
+	if err != nil {
+		return err
 	}
 
 	iu, err := a.startInputs(next, a.Config.Inputs)
@@ -145,46 +212,44 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
+	// Start the flush loops before the agent can be reloaded, so that
+	// outputs removed by a reload get their final flush.
+	ou.Lock()
+	for _, output := range ou.outputs {
+		a.runOutput(ou, output)
+	}
+	ou.Unlock()
+
+	a.mu.Lock()
+	a.ctx = ctx
+	a.startTime = startTime
+	a.iu, a.ou, a.pl = iu, ou, pl
+	a.mu.Unlock()
+	if a.started != nil {
+		close(a.started)
+	}
+
+	defer func() {
+		a.mu.Lock()
+		a.iu, a.ou, a.pl = nil, nil, nil
+		a.mu.Unlock()
+	}()
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := a.runOutputs(ou)
+		err := a.runOutputs(startTime, ou)
 		if err != nil {
 			log.Printf("E! [agent] Error running outputs: %v", err)
 		}
 	}()
 
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(apu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runAggregators(startTime, au)
-			if err != nil {
-				log.Printf("E! [agent] Error running aggregators: %v", err)
-			}
-		}()
-	}
-
-	if pu != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(pu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runPipeline(startTime, pl)
+	}()
 
 	wg.Add(1)
 	go func() {
@@ -231,7 +296,14 @@ func (a *Agent) initPlugins() error {
 				processor.Config.Name, err)
 		}
 	}
-	for _, output := range a.Config.Outputs {
+	return nil
+}
+
+// initOutputs runs the Init function on outputs, which also opens their
+// buffers.  It is not run in test mode, so that the disk buffers of a
+// running agent are not opened.
+func (a *Agent) initOutputs(outputs []*models.RunningOutput) error {
+	for _, output := range outputs {
 		err := output.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize output %s: %v",
@@ -248,31 +320,15 @@ func (a *Agent) startInputs(
 	log.Printf("D! [agent] Starting service inputs")
 
 	unit := &inputUnit{
-		dst: dst,
+		dst:     dst,
+		runners: make(map[*models.RunningInput]*runner),
 	}
 
 	for _, input := range inputs {
-		if si, ok := input.Input.(telegraf.ServiceInput); ok {
-			// Service input plugins are not normally subject to timestamp
-			// rounding except for when precision is set on the input plugin.
-			//
-			// This only applies to the accumulator passed to Start(), the
-			// Gather() accumulator does apply rounding according to the
-			// precision and interval agent/plugin settings.
-			var interval time.Duration
-			var precision time.Duration
-			if input.Config.Precision != 0 {
-				precision = input.Config.Precision
-			}
-
-			acc := NewAccumulator(input, dst)
-			acc.SetPrecision(getPrecision(precision, interval))
-
-			err := si.Start(acc)
-			if err != nil {
-				stopServiceInputs(unit.inputs)
-				return nil, fmt.Errorf("starting input %s: %w", input.LogName(), err)
-			}
+		err := a.startServiceInput(dst, input)
+		if err != nil {
+			stopServiceInputs(unit.inputs)
+			return nil, err
 		}
 		unit.inputs = append(unit.inputs, input)
 	}
@@ -280,6 +336,35 @@ func (a *Agent) startInputs(
 	return unit, nil
 }
 
+// startServiceInput calls Start if the input is a service input.
+func (a *Agent) startServiceInput(
+	dst chan<- telegraf.Metric,
+	input *models.RunningInput,
+) error {
+	if si, ok := input.Input.(telegraf.ServiceInput); ok {
+		// Service input plugins are not normally subject to timestamp
+		// rounding except for when precision is set on the input plugin.
+		//
+		// This only applies to the accumulator passed to Start(), the
+		// Gather() accumulator does apply rounding according to the
+		// precision and interval agent/plugin settings.
+		var interval time.Duration
+		var precision time.Duration
+		if input.Config.Precision != 0 {
+			precision = input.Config.Precision
+		}
+
+		acc := NewAccumulator(input, dst)
+		acc.SetPrecision(getPrecision(precision, interval))
+
+		err := si.Start(acc)
+		if err != nil {
+			return fmt.Errorf("starting input %s: %w", input.LogName(), err)
+		}
+	}
+	return nil
+}
+
 // runInputs starts and triggers the periodic gather for Inputs.
 //
 // When the context is done the timers are stopped and this function returns
@@ -289,55 +374,83 @@ func (a *Agent) runInputs(
 	startTime time.Time,
 	unit *inputUnit,
 ) error {
-	var wg sync.WaitGroup
+	unit.Lock()
 	for _, input := range unit.inputs {
-		// Overwrite agent interval if this plugin has its own.
-		interval := a.Config.Agent.Interval.Duration
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
-
-		// Overwrite agent precision if this plugin has its own.
-		precision := a.Config.Agent.Precision.Duration
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
-
-		// Overwrite agent collection_jitter if this plugin has its own.
-		jitter := a.Config.Agent.CollectionJitter.Duration
-		if input.Config.CollectionJitter != 0 {
-			jitter = input.Config.CollectionJitter
-		}
-
-		var ticker Ticker
-		if a.Config.Agent.RoundInterval {
-			ticker = NewAlignedTicker(startTime, interval, jitter)
-		} else {
-			ticker = NewUnalignedTicker(interval, jitter)
-		}
-		defer ticker.Stop()
+		a.runInput(ctx, startTime, unit, input)
+	}
+	unit.Unlock()
 
-		acc := NewAccumulator(input, unit.dst)
-		acc.SetPrecision(getPrecision(precision, interval))
+	<-ctx.Done()
 
-		wg.Add(1)
-		go func(input *models.RunningInput) {
-			defer wg.Done()
-			a.gatherLoop(ctx, acc, input, ticker, interval)
-		}(input)
+	unit.Lock()
+	for _, r := range unit.runners {
+		<-r.done
 	}
 
-	wg.Wait()
-
 	log.Printf("D! [agent] Stopping service inputs")
 	stopServiceInputs(unit.inputs)
+	unit.Unlock()
 
+	unit.removing.Wait()
 	close(unit.dst)
 	log.Printf("D! [agent] Input channel closed")
 
 	return nil
 }
 
+// runInput starts the periodic gather for a single input, unless it is
+// already running.  The unit must be locked by the caller.
+func (a *Agent) runInput(
+	ctx context.Context,
+	startTime time.Time,
+	unit *inputUnit,
+	input *models.RunningInput,
+) {
+	if _, ok := unit.runners[input]; ok {
+		return
+	}
+
+	// Overwrite agent interval if this plugin has its own.
+	interval := a.Config.Agent.Interval.Duration
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
+
+	// Overwrite agent precision if this plugin has its own.
+	precision := a.Config.Agent.Precision.Duration
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
+
+	// Overwrite agent collection_jitter if this plugin has its own.
+	jitter := a.Config.Agent.CollectionJitter.Duration
+	if input.Config.CollectionJitter != 0 {
+		jitter = input.Config.CollectionJitter
+	}
+
+	var ticker Ticker
+	if input.Config.Schedule != nil {
+		ticker = NewScheduledTicker(input.Config.Schedule, jitter)
+	} else if a.Config.Agent.RoundInterval {
+		ticker = NewAlignedTicker(startTime, interval, jitter)
+	} else {
+		ticker = NewUnalignedTicker(interval, jitter)
+	}
+
+	acc := NewAccumulator(input, unit.dst)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &runner{cancel: cancel, done: make(chan struct{})}
+	unit.runners[input] = r
+
+	go func() {
+		defer close(r.done)
+		defer ticker.Stop()
+		a.gatherLoop(ctx, acc, input, ticker, interval)
+	}()
+}
+
 // testStartInputs is a variation of startInputs for use in --test and --once
 // mode.  It differs by logging Start errors and returning only plugins
 // successfully started.
@@ -471,17 +584,74 @@ func (a *Agent) gatherLoop(
 	}
 }
 
+// setAbandoned records the result channel of an abandoned gather.
+func (a *Agent) setAbandoned(input *models.RunningInput, done <-chan error) {
+	a.abandonedMu.Lock()
+	defer a.abandonedMu.Unlock()
+	if a.abandoned == nil {
+		a.abandoned = make(map[*models.RunningInput]<-chan error)
+	}
+	a.abandoned[input] = done
+}
+
+// gatherInFlight returns true if an abandoned gather of the input has not
+// returned yet.
+func (a *Agent) gatherInFlight(input *models.RunningInput) bool {
+	a.abandonedMu.Lock()
+	defer a.abandonedMu.Unlock()
+	done, ok := a.abandoned[input]
+	if !ok {
+		return false
+	}
+	select {
+	case <-done:
+		delete(a.abandoned, input)
+		return false
+	default:
+		return true
+	}
+}
+
+// gatherAbandonDelay is how long a context aware input may take to return
+// after its gather_timeout expired before the gather is abandoned.
+var gatherAbandonDelay = time.Second
+
 // gatherOnce runs the input's Gather function once, logging a warning each
-// interval it fails to complete before.
+// interval it fails to complete before.  If the input has a gather_timeout
+// the gather is canceled once it expires, and abandoned if the input does not
+// return.
 func (a *Agent) gatherOnce(
 	acc telegraf.Accumulator,
 	input *models.RunningInput,
 	ticker Ticker,
 	interval time.Duration,
 ) error {
-	done := make(chan error)
+	// Inputs are not gathered concurrently, so a new gather waits until
+	// the abandoned one returns.
+	if a.gatherInFlight(input) {
+		input.GathersSkipped.Incr(1)
+		log.Printf("W! [%s] Abandoned collection has not returned; scheduled collection skipped",
+			input.LogName())
+		return nil
+	}
+
+	ctx := context.Background()
+	timeout := input.Config.GatherTimeout
+	var gacc *gatherAccumulator
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		// Metrics added by an abandoned gather are dropped.
+		gacc = &gatherAccumulator{Accumulator: acc}
+		acc = gacc
+	}
+
+	// Buffered so an abandoned gather can still finish.
+	done := make(chan error, 1)
 	go func() {
-		done <- input.Gather(acc)
+		done <- input.Gather(ctx, acc)
 	}()
 
 	// Only warn after interval seconds, even if the interval is started late.
@@ -490,10 +660,32 @@ func (a *Agent) gatherOnce(
 	slowWarning := time.NewTicker(interval)
 	defer slowWarning.Stop()
 
+	expired := ctx.Done()
+	var abandon <-chan time.Time
 	for {
 		select {
 		case err := <-done:
 			return err
+		case <-expired:
+			expired = nil
+			input.GatherTimeouts.Incr(1)
+			if _, ok := input.Input.(telegraf.ContextGatherer); ok {
+				log.Printf("W! [%s] Collection did not complete within gather_timeout of %s; canceling",
+					input.LogName(), timeout)
+				abandonTimer := time.NewTimer(gatherAbandonDelay)
+				defer abandonTimer.Stop()
+				abandon = abandonTimer.C
+				continue
+			}
+			gacc.abandon()
+			input.GathersAbandoned.Incr(1)
+			a.setAbandoned(input, done)
+			return fmt.Errorf("collection did not complete within gather_timeout of %s; abandoned", timeout)
+		case <-abandon:
+			gacc.abandon()
+			input.GathersAbandoned.Incr(1)
+			a.setAbandoned(input, done)
+			return fmt.Errorf("collection did not return after gather_timeout of %s was canceled; abandoned", timeout)
 		case <-slowWarning.C:
 			log.Printf("W! [%s] Collection took longer than expected; not complete after interval of %s",
 				input.LogName(), interval)
@@ -504,6 +696,104 @@ func (a *Agent) gatherOnce(
 	}
 }
 
+// startPipeline starts the processors and aggregators of the named pipeline,
+// or the ones applying to all outputs for the empty name, writing to dst.  It
+// returns the source channel of the pipeline.
+func (a *Agent) startPipeline(
+	dst chan<- telegraf.Metric,
+	name string,
+) (chan<- telegraf.Metric, *pipelineUnit, error) {
+	unit := &pipelineUnit{}
+	next := dst
+
+	aggregators := pipelineAggregators(a.Config.Aggregators, name)
+	if len(aggregators) != 0 {
+		aggC := next
+		if processors := pipelineProcessors(a.Config.AggProcessors, name); len(processors) != 0 {
+			var err error
+			aggC, unit.apu, err = a.startProcessors(next, processors)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		var err error
+		next, unit.au, err = a.startAggregators(aggC, next, aggregators)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if processors := pipelineProcessors(a.Config.Processors, name); len(processors) != 0 {
+		var err error
+		next, unit.pu, err = a.startProcessors(next, processors)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return next, unit, nil
+}
+
+// runPipeline runs the processors and aggregators of the pipeline until its
+// source channel is closed and all metrics have been written.
+func (a *Agent) runPipeline(startTime time.Time, unit *pipelineUnit) {
+	var wg sync.WaitGroup
+	if unit.au != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := a.runProcessors(unit.apu)
+			if err != nil {
+				log.Printf("E! [agent] Error running processors: %v", err)
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := a.runAggregators(startTime, unit.au)
+			if err != nil {
+				log.Printf("E! [agent] Error running aggregators: %v", err)
+			}
+		}()
+	}
+
+	if unit.pu != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := a.runProcessors(unit.pu)
+			if err != nil {
+				log.Printf("E! [agent] Error running processors: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// pipelineProcessors returns the processors of the named pipeline.
+func pipelineProcessors(processors models.RunningProcessors, name string) models.RunningProcessors {
+	var selected models.RunningProcessors
+	for _, processor := range processors {
+		if processor.Config.Pipeline == name {
+			selected = append(selected, processor)
+		}
+	}
+	return selected
+}
+
+// pipelineAggregators returns the aggregators of the named pipeline.
+func pipelineAggregators(aggregators []*models.RunningAggregator, name string) []*models.RunningAggregator {
+	var selected []*models.RunningAggregator
+	for _, aggregator := range aggregators {
+		if aggregator.Config.Pipeline == name {
+			selected = append(selected, aggregator)
+		}
+	}
+	return selected
+}
+
 // startProcessors sets up the processor chain and calls Start on all
 // processors.  If an error occurs any started processors are Stopped.
 func (a *Agent) startProcessors(
@@ -554,14 +844,27 @@ func (a *Agent) runProcessors(
 		go func(unit *processorUnit) {
 			defer wg.Done()
 
-			acc := NewAccumulator(unit.processor, unit.dst)
+			unit.Lock()
+			processor := unit.processor
+			unit.Unlock()
+
+			acc := NewAccumulator(processor, unit.dst)
 			for m := range unit.src {
-				if err := unit.processor.Add(m, acc); err != nil {
+				unit.Lock()
+				// The processor is replaced when the config is reloaded.
+				if unit.processor != processor {
+					processor = unit.processor
+					acc = NewAccumulator(processor, unit.dst)
+				}
+				if err := processor.Add(m, acc); err != nil {
 					acc.AddError(err)
 					m.Drop()
 				}
+				unit.Unlock()
 			}
+			unit.Lock()
 			unit.processor.Stop()
+			unit.Unlock()
 			close(unit.dst)
 			log.Printf("D! [agent] Processor channel closed")
 		}(unit)
@@ -593,11 +896,15 @@ func (a *Agent) runAggregators(
 	startTime time.Time,
 	unit *aggregatorUnit,
 ) error {
+	if a.virtualClock {
+		return a.runAggregatorsVirtual(unit)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Before calling Add, initialize the aggregation window.  This ensures
 	// that any metric created after start time will be aggregated.
-	for _, agg := range a.Config.Aggregators {
+	for _, agg := range unit.aggregators {
 		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
 		agg.UpdateWindow(since, until)
 	}
@@ -608,7 +915,7 @@ func (a *Agent) runAggregators(
 		defer wg.Done()
 		for metric := range unit.src {
 			var dropOriginal bool
-			for _, agg := range a.Config.Aggregators {
+			for _, agg := range unit.aggregators {
 				if ok := agg.Add(metric); ok {
 					dropOriginal = true
 				}
@@ -623,7 +930,7 @@ func (a *Agent) runAggregators(
 		cancel()
 	}()
 
-	for _, agg := range a.Config.Aggregators {
+	for _, agg := range unit.aggregators {
 		wg.Add(1)
 		go func(agg *models.RunningAggregator) {
 			defer wg.Done()
@@ -648,6 +955,68 @@ func (a *Agent) runAggregators(
 	return nil
 }
 
+// runAggregatorsVirtual aggregates metrics using a virtual clock that is set
+// by the metric timestamps.  The aggregation windows start at the first metric
+// and a period is pushed as soon as a metric after its end arrives, so that
+// periods are closed without waiting.  All aggregators push once more when the
+// source channel is closed.
+func (a *Agent) runAggregatorsVirtual(unit *aggregatorUnit) error {
+	interval := a.Config.Agent.Interval.Duration
+	precision := a.Config.Agent.Precision.Duration
+
+	accs := make([]telegraf.Accumulator, 0, len(unit.aggregators))
+	for _, agg := range unit.aggregators {
+		acc := NewAccumulator(agg, unit.aggC)
+		acc.SetPrecision(getPrecision(precision, interval))
+		accs = append(accs, acc)
+	}
+
+	var now time.Time
+	for metric := range unit.src {
+		if now.IsZero() {
+			for _, agg := range unit.aggregators {
+				since, until := updateWindow(metric.Time(), a.Config.Agent.RoundInterval, agg.Period())
+				agg.UpdateWindow(since, until)
+			}
+		}
+		if metric.Time().After(now) {
+			now = metric.Time()
+		}
+
+		var dropOriginal bool
+		for i, agg := range unit.aggregators {
+			end := agg.EndPeriod()
+			if end.Add(agg.Config.Delay).Before(now) {
+				agg.Push(accs[i])
+
+				// Skip the empty periods up to the one containing now.
+				if skip := now.Sub(end) / agg.Period(); skip > 0 {
+					since := end.Add(skip * agg.Period())
+					agg.UpdateWindow(since, since.Add(agg.Period()))
+				}
+			}
+			if ok := agg.Add(metric); ok {
+				dropOriginal = true
+			}
+		}
+
+		if !dropOriginal {
+			unit.outputC <- metric // keep original.
+		} else {
+			metric.Drop()
+		}
+	}
+
+	for i, agg := range unit.aggregators {
+		agg.Push(accs[i])
+	}
+
+	close(unit.aggC)
+	log.Printf("D! [agent] Aggregator channel closed")
+
+	return nil
+}
+
 func updateWindow(start time.Time, roundInterval bool, period time.Duration) (time.Time, time.Time) {
 	var until time.Time
 	if roundInterval {
@@ -695,7 +1064,11 @@ func (a *Agent) startOutputs(
 	outputs []*models.RunningOutput,
 ) (chan<- telegraf.Metric, *outputUnit, error) {
 	src := make(chan telegraf.Metric, 100)
-	unit := &outputUnit{src: src}
+	unit := &outputUnit{
+		src:     src,
+		runners: make(map[*models.RunningOutput]*runner),
+	}
+	unit.ctx, unit.cancel = context.WithCancel(context.Background())
 	for _, output := range outputs {
 		err := a.connectOutput(ctx, output)
 		if err != nil {
@@ -707,14 +1080,64 @@ func (a *Agent) startOutputs(
 
 		unit.outputs = append(unit.outputs, output)
 	}
+	unit.linkDeadLetters()
+
+	seen := make(map[string]bool)
+	for _, output := range outputs {
+		name := output.Config.Pipeline
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		dst := make(chan telegraf.Metric, 100)
+		psrc, pl, err := a.startPipeline(dst, name)
+		if err != nil {
+			for _, output := range unit.outputs {
+				output.Close()
+			}
+			return nil, nil, fmt.Errorf("starting pipeline %q: %w", name, err)
+		}
+		unit.pipelines = append(unit.pipelines, &outputPipeline{
+			name: name,
+			src:  psrc,
+			dst:  dst,
+			unit: pl,
+		})
+	}
 
 	return src, unit, nil
 }
 
+// linkDeadLetters sets the dead-letter sink of the outputs using a
+// dead-letter output.
+func (unit *outputUnit) linkDeadLetters() {
+	unit.deadLetters = make(map[*models.RunningOutput]bool)
+	for _, output := range unit.outputs {
+		alias := output.Config.DeadLetterOutput
+		if alias == "" {
+			continue
+		}
+
+		for _, target := range unit.outputs {
+			if target.Config.Alias == alias {
+				output.DeadLetter = target
+				unit.deadLetters[target] = true
+				break
+			}
+		}
+	}
+}
+
 // connectOutputs connects to all outputs.
 func (a *Agent) connectOutput(ctx context.Context, output *models.RunningOutput) error {
 	log.Printf("D! [agent] Attempting connection to [%s]", output.LogName())
-	err := output.Output.Connect()
+	err := output.Connect()
+	if err != nil && output.Config.StartupErrorBehavior == "retry" {
+		log.Printf("W! [agent] Failed to connect to [%s], retrying on the next "+
+			"flush, error was '%s'", output.LogName(), err)
+		return nil
+	}
 	if err != nil {
 		log.Printf("E! [agent] Failed to connect to [%s], retrying in 15s, "+
 			"error was '%s'", output.LogName(), err)
@@ -724,7 +1147,7 @@ func (a *Agent) connectOutput(ctx context.Context, output *models.RunningOutput)
 			return err
 		}
 
-		err = output.Output.Connect()
+		err = output.Connect()
 		if err != nil {
 			return fmt.Errorf("Error connecting to output %q: %w", output.LogName(), err)
 		}
@@ -737,57 +1160,151 @@ func (a *Agent) connectOutput(ctx context.Context, output *models.RunningOutput)
 // closed and all metrics have been written.  On shutdown metrics will be
 // written one last time and dropped if unsuccessful.
 func (a *Agent) runOutputs(
+	startTime time.Time,
 	unit *outputUnit,
 ) error {
-	var wg sync.WaitGroup
-
 	// Start flush loop
-	interval := a.Config.Agent.FlushInterval.Duration
-	jitter := a.Config.Agent.FlushJitter.Duration
-
-	ctx, cancel := context.WithCancel(context.Background())
-
+	unit.Lock()
 	for _, output := range unit.outputs {
-		interval := interval
-		// Overwrite agent flush_interval if this plugin has its own.
-		if output.Config.FlushInterval != 0 {
-			interval = output.Config.FlushInterval
-		}
-
-		jitter := jitter
-		// Overwrite agent flush_jitter if this plugin has its own.
-		if output.Config.FlushJitter != 0 {
-			jitter = output.Config.FlushJitter
-		}
+		a.runOutput(unit, output)
+	}
+	unit.Unlock()
 
+	var wg sync.WaitGroup
+	for _, p := range unit.pipelines {
 		wg.Add(1)
-		go func(output *models.RunningOutput) {
+		go func(p *outputPipeline) {
 			defer wg.Done()
+			a.runPipeline(startTime, p.unit)
+		}(p)
 
-			ticker := NewRollingTicker(interval, jitter)
-			defer ticker.Stop()
-
-			a.flushLoop(ctx, output, ticker)
-		}(output)
+		wg.Add(1)
+		go func(p *outputPipeline) {
+			defer wg.Done()
+			for metric := range p.dst {
+				unit.addMetric(metric, p.name, false)
+			}
+		}(p)
 	}
 
 	for metric := range unit.src {
-		for i, output := range unit.outputs {
-			if i == len(a.Config.Outputs)-1 {
-				output.AddMetric(metric)
+		unit.addMetric(metric, "", len(unit.pipelines) != 0)
+
+		// The pipelines are fed without holding the lock, as they may block
+		// while their outputs are being changed.
+		for i, p := range unit.pipelines {
+			if i == len(unit.pipelines)-1 {
+				p.src <- metric
 			} else {
-				output.AddMetric(metric.Copy())
+				p.src <- metric.Copy()
 			}
 		}
 	}
 
-	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
-	cancel()
+	for _, p := range unit.pipelines {
+		close(p.src)
+	}
 	wg.Wait()
 
+	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
+	unit.cancel()
+
+	unit.Lock()
+	defer unit.Unlock()
+	for output, r := range unit.runners {
+		if !unit.deadLetters[output] {
+			<-r.done
+		}
+	}
+
+	// The dead-letter outputs are stopped last, so that they write the
+	// metrics rejected during the final flush of the other outputs.
+	for output, r := range unit.runners {
+		if unit.deadLetters[output] {
+			r.stop()
+		}
+	}
+
+	// Close the outputs so that persistent buffers are synced to disk.
+	for _, output := range unit.outputs {
+		output.Close()
+	}
+
 	return nil
 }
 
+// addMetric adds the metric to the outputs subscribed to the named pipeline,
+// the empty name selects the outputs without pipeline.  Unless keep is set the
+// last output receives the metric itself instead of a copy.
+func (unit *outputUnit) addMetric(metric telegraf.Metric, pipeline string, keep bool) {
+	unit.RLock()
+	defer unit.RUnlock()
+
+	last := -1
+	for i, output := range unit.outputs {
+		if output.Config.Pipeline == pipeline && !unit.deadLetters[output] {
+			last = i
+		}
+	}
+	if last == -1 && !keep {
+		metric.Drop()
+		return
+	}
+
+	for i, output := range unit.outputs {
+		if output.Config.Pipeline != pipeline || unit.deadLetters[output] {
+			continue
+		}
+		if i == last && !keep {
+			output.AddMetric(metric)
+		} else {
+			output.AddMetric(metric.Copy())
+		}
+	}
+}
+
+// runOutput starts the flush loop for a single output, unless it is already
+// running.  The unit must be locked by the caller.
+func (a *Agent) runOutput(
+	unit *outputUnit,
+	output *models.RunningOutput,
+) {
+	if _, ok := unit.runners[output]; ok {
+		return
+	}
+
+	// Overwrite agent flush_interval if this plugin has its own.
+	interval := a.Config.Agent.FlushInterval.Duration
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+
+	// Overwrite agent flush_jitter if this plugin has its own.
+	jitter := a.Config.Agent.FlushJitter.Duration
+	if output.Config.FlushJitter != 0 {
+		jitter = output.Config.FlushJitter
+	}
+
+	// Dead-letter outputs are stopped explicitly once the others are done.
+	parent := unit.ctx
+	if unit.deadLetters[output] {
+		parent = context.Background()
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	r := &runner{cancel: cancel, done: make(chan struct{})}
+	unit.runners[output] = r
+
+	go func() {
+		defer close(r.done)
+
+		ticker := NewRollingTicker(interval, jitter)
+		defer ticker.Stop()
+
+		a.flushLoop(ctx, output, ticker)
+	}()
+}
+
 // flushLoop runs an output's flush function periodically until the context is
 // done.
 func (a *Agent) flushLoop(
@@ -810,14 +1327,14 @@ func (a *Agent) flushLoop(
 		// Favor shutdown over other methods.
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.flushOnce(output, ticker, output.WriteFinal))
 			return
 		default:
 		}
 
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.flushOnce(output, ticker, output.WriteFinal))
 			return
 		case <-ticker.Elapsed():
 			logError(a.flushOnce(output, ticker, output.Write))
@@ -875,7 +1392,7 @@ func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
 		for metric := range src {
 			octets, err := s.Serialize(metric)
 			if err == nil {
-				fmt.Print("> ", string(octets))
+				fmt.Print("> ", redact.String(string(octets)))
 			}
 			metric.Reject()
 		}
@@ -906,31 +1423,10 @@ func (a *Agent) test(ctx context.Context, wait time.Duration, outputC chan<- tel
 
 	startTime := time.Now()
 
-	next := outputC
-
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		procC := next
-		if len(a.Config.AggProcessors) != 0 {
-			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au, err = a.startAggregators(procC, next, a.Config.Aggregators)
-		if err != nil {
-			return err
-		}
-	}
-
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
+	// The pipelines of the outputs are not run as there are no outputs.
+	next, pl, err := a.startPipeline(outputC, "")
+	if err != nil {
+		return err
 	}
 
 	iu, err := a.testStartInputs(next, a.Config.Inputs)
@@ -939,37 +1435,11 @@ func (a *Agent) test(ctx context.Context, wait time.Duration, outputC chan<- tel
 	}
 
 	var wg sync.WaitGroup
-
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(apu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runAggregators(startTime, au)
-			if err != nil {
-				log.Printf("E! [agent] Error running aggregators: %v", err)
-			}
-		}()
-	}
-
-	if pu != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(pu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runPipeline(startTime, pl)
+	}()
 
 	wg.Add(1)
 	go func() {
@@ -987,9 +1457,59 @@ func (a *Agent) test(ctx context.Context, wait time.Duration, outputC chan<- tel
 	return nil
 }
 
+// TestPipeline runs the full agent for a single gather, but instead of writing
+// to the outputs the metrics of each output are printed in its data format.
+// The aggregators use a virtual clock, so their periods are closed as soon as
+// all metrics have been gathered.
+func (a *Agent) TestPipeline(ctx context.Context, wait time.Duration) error {
+	err := a.testPipeline(ctx, wait, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if models.GlobalGatherErrors.Get() != 0 {
+		return fmt.Errorf("input plugins recorded %d errors", models.GlobalGatherErrors.Get())
+	}
+	return nil
+}
+
+func (a *Agent) testPipeline(ctx context.Context, wait time.Duration, w io.Writer) error {
+	a.virtualClock = true
+
+	// The outputs are replaced by printers with the same configuration, so
+	// that filters, pipelines and name modifications are applied.  The
+	// printers keep their metrics in memory and do not touch the files of
+	// the real outputs, which are not initialized.
+	printers := make([]*printOutput, 0, len(a.Config.Outputs))
+	outputs := make([]*models.RunningOutput, 0, len(a.Config.Outputs))
+	for _, output := range a.Config.Outputs {
+		printer := newPrintOutput(output.Output, output.Serializer)
+		printers = append(printers, printer)
+		config := *output.Config
+		config.BufferStrategy = ""
+		config.BufferDirectory = ""
+		config.DeadLetterFile = ""
+		outputs = append(outputs, models.NewRunningOutput(output.Config.Name, printer,
+			&config, output.MetricBatchSize, output.MetricBufferLimit))
+	}
+
+	err := a.once(ctx, wait, outputs)
+	if err != nil {
+		return err
+	}
+
+	for i, output := range a.Config.Outputs {
+		fmt.Fprintf(w, "# %s\n", output.LogName())
+		if _, err := printers[i].WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Once runs the full agent for a single gather.
 func (a *Agent) Once(ctx context.Context, wait time.Duration) error {
-	err := a.once(ctx, wait)
+	err := a.once(ctx, wait, a.Config.Outputs)
 	if err != nil {
 		return err
 	}
@@ -1011,44 +1531,29 @@ func (a *Agent) Once(ctx context.Context, wait time.Duration) error {
 // On runs the agent and performs a single gather sending output to the
 // outputF.  After gathering pauses for the wait duration to allow service
 // inputs to run.
-func (a *Agent) once(ctx context.Context, wait time.Duration) error {
+func (a *Agent) once(ctx context.Context, wait time.Duration, outputs []*models.RunningOutput) error {
 	log.Printf("D! [agent] Initializing plugins")
 	err := a.initPlugins()
 	if err != nil {
 		return err
 	}
 
-	startTime := time.Now()
-
-	log.Printf("D! [agent] Connecting outputs")
-	next, ou, err := a.startOutputs(ctx, a.Config.Outputs)
+	err = a.initOutputs(outputs)
 	if err != nil {
 		return err
 	}
 
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		procC := next
-		if len(a.Config.AggProcessors) != 0 {
-			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
+	startTime := time.Now()
 
-		next, au, err = a.startAggregators(procC, next, a.Config.Aggregators)
-		if err != nil {
-			return err
-		}
+	log.Printf("D! [agent] Connecting outputs")
+	next, ou, err := a.startOutputs(ctx, outputs)
+	if err != nil {
+		return err
 	}
 
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
+	next, pl, err := a.startPipeline(next, "")
+	if err != nil {
+		return err
 	}
 
 	iu, err := a.testStartInputs(next, a.Config.Inputs)
@@ -1060,42 +1565,17 @@ func (a *Agent) once(ctx context.Context, wait time.Duration) error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := a.runOutputs(ou)
+		err := a.runOutputs(startTime, ou)
 		if err != nil {
 			log.Printf("E! [agent] Error running outputs: %v", err)
 		}
 	}()
 
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(apu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runAggregators(startTime, au)
-			if err != nil {
-				log.Printf("E! [agent] Error running aggregators: %v", err)
-			}
-		}()
-	}
-
-	if pu != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(pu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runPipeline(startTime, pl)
+	}()
 
 	wg.Add(1)
 	go func() {
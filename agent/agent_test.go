@@ -1,12 +1,25 @@
 package agent
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/serializers/json"
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -166,3 +179,497 @@ func TestWindow(t *testing.T) {
 		})
 	}
 }
+
+type hungInput struct {
+	release chan struct{}
+}
+
+func (i *hungInput) SampleConfig() string { return "" }
+func (i *hungInput) Description() string  { return "" }
+
+func (i *hungInput) Gather(acc telegraf.Accumulator) error {
+	<-i.release
+	acc.AddFields("hung", map[string]interface{}{"value": 1}, nil)
+	return nil
+}
+
+type contextInput struct {
+	hungInput
+	ignoreContext bool
+}
+
+func (i *contextInput) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
+	if i.ignoreContext {
+		return i.Gather(acc)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func gatherOnce(input telegraf.Input, name string) (*models.RunningInput, <-chan telegraf.Metric, error) {
+	ri := models.NewRunningInput(input, &models.InputConfig{
+		Name:          name,
+		GatherTimeout: 10 * time.Millisecond,
+	})
+	// The stats are registered globally and shared by repeated test runs.
+	ri.GatherTimeouts.Set(0)
+	ri.GathersAbandoned.Set(0)
+	metrics := make(chan telegraf.Metric, 10)
+	ticker := newUnalignedTicker(time.Hour, 0, clock.NewMock())
+	defer ticker.Stop()
+
+	a := &Agent{}
+	err := a.gatherOnce(NewAccumulator(ri, metrics), ri, ticker, time.Hour)
+	return ri, metrics, err
+}
+
+func TestGatherTimeoutAbandoned(t *testing.T) {
+	input := &hungInput{release: make(chan struct{})}
+	ri, metrics, err := gatherOnce(input, "test_abandoned")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "abandoned")
+	require.Equal(t, int64(1), ri.GatherTimeouts.Get())
+	require.Equal(t, int64(1), ri.GathersAbandoned.Get())
+
+	// Metrics of the abandoned gather are discarded.
+	close(input.release)
+	time.Sleep(10 * time.Millisecond)
+	require.Len(t, metrics, 0)
+}
+
+func TestGatherTimeoutAbandonedSkipsTicks(t *testing.T) {
+	input := &hungInput{release: make(chan struct{})}
+	ri := models.NewRunningInput(input, &models.InputConfig{
+		Name:          "test_skipped",
+		GatherTimeout: 10 * time.Millisecond,
+	})
+	ri.GathersAbandoned.Set(0)
+	ri.GathersSkipped.Set(0)
+	metrics := make(chan telegraf.Metric, 10)
+	ticker := newUnalignedTicker(time.Hour, 0, clock.NewMock())
+	defer ticker.Stop()
+	acc := NewAccumulator(ri, metrics)
+
+	a := &Agent{}
+	require.Error(t, a.gatherOnce(acc, ri, ticker, time.Hour))
+	require.Equal(t, int64(1), ri.GathersAbandoned.Get())
+
+	// The input is not gathered again while the abandoned gather runs.
+	require.NoError(t, a.gatherOnce(acc, ri, ticker, time.Hour))
+	require.Equal(t, int64(1), ri.GathersSkipped.Get())
+
+	// Once it returned the input is gathered again.
+	close(input.release)
+	require.Eventually(t, func() bool {
+		return !a.gatherInFlight(ri)
+	}, time.Second, time.Millisecond)
+	require.NoError(t, a.gatherOnce(acc, ri, ticker, time.Hour))
+	require.Equal(t, int64(1), ri.GathersSkipped.Get())
+	require.Len(t, metrics, 1)
+}
+
+func TestGatherTimeoutCanceled(t *testing.T) {
+	input := &contextInput{hungInput: hungInput{release: make(chan struct{})}}
+	ri, _, err := gatherOnce(input, "test_canceled")
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, int64(1), ri.GatherTimeouts.Get())
+	require.Equal(t, int64(0), ri.GathersAbandoned.Get())
+}
+
+func TestGatherTimeoutContextIgnored(t *testing.T) {
+	defer func(d time.Duration) { gatherAbandonDelay = d }(gatherAbandonDelay)
+	gatherAbandonDelay = 10 * time.Millisecond
+
+	input := &contextInput{hungInput: hungInput{release: make(chan struct{})}, ignoreContext: true}
+	defer close(input.release)
+	ri, _, err := gatherOnce(input, "test_ignored")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "abandoned")
+	require.Equal(t, int64(1), ri.GatherTimeouts.Get())
+	require.Equal(t, int64(1), ri.GathersAbandoned.Get())
+}
+
+func TestGatherWithoutTimeout(t *testing.T) {
+	input := &hungInput{release: make(chan struct{})}
+	close(input.release)
+	ri := models.NewRunningInput(input, &models.InputConfig{Name: "test_no_timeout"})
+	metrics := make(chan telegraf.Metric, 10)
+	ticker := newUnalignedTicker(time.Hour, 0, clock.NewMock())
+	defer ticker.Stop()
+
+	a := &Agent{}
+	require.NoError(t, a.gatherOnce(NewAccumulator(ri, metrics), ri, ticker, time.Hour))
+	require.Len(t, metrics, 1)
+}
+
+type staticInput struct{}
+
+func (i *staticInput) SampleConfig() string { return "" }
+func (i *staticInput) Description() string  { return "" }
+
+func (i *staticInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("cpu", map[string]interface{}{"value": 42}, nil)
+	return nil
+}
+
+type renameProcessor struct {
+	name string
+}
+
+func (p *renameProcessor) SampleConfig() string { return "" }
+func (p *renameProcessor) Description() string  { return "" }
+
+func (p *renameProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.SetName(p.name + m.Name())
+	}
+	return in
+}
+
+type countAggregator struct {
+	count int64
+}
+
+func (a *countAggregator) SampleConfig() string   { return "" }
+func (a *countAggregator) Description() string    { return "" }
+func (a *countAggregator) Add(in telegraf.Metric) { a.count++ }
+func (a *countAggregator) Reset()                 { a.count = 0 }
+
+func (a *countAggregator) Push(acc telegraf.Accumulator) {
+	if a.count > 0 {
+		acc.AddFields("count", map[string]interface{}{"value": a.count}, nil)
+	}
+}
+
+type recordingOutput struct {
+	sync.Mutex
+	metrics []telegraf.Metric
+}
+
+func (o *recordingOutput) SampleConfig() string { return "" }
+func (o *recordingOutput) Description() string  { return "" }
+func (o *recordingOutput) Connect() error       { return nil }
+func (o *recordingOutput) Close() error         { return nil }
+
+func (o *recordingOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+func (o *recordingOutput) names() []string {
+	o.Lock()
+	defer o.Unlock()
+	var names []string
+	for _, m := range o.metrics {
+		names = append(names, m.Name())
+	}
+	return names
+}
+
+func TestAgent_Pipelines(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&staticInput{}, &models.InputConfig{Name: "static"}))
+
+	newProcessor := func(prefix, pipeline string) *models.RunningProcessor {
+		return models.NewRunningProcessor(
+			processors.NewStreamingProcessorFromProcessor(&renameProcessor{name: prefix}),
+			&models.ProcessorConfig{Name: "rename", Pipeline: pipeline},
+		)
+	}
+	c.Processors = append(c.Processors, newProcessor("global_", ""), newProcessor("vendor_", "vendor"))
+	c.AggProcessors = append(c.AggProcessors, newProcessor("global_", ""), newProcessor("vendor_", "vendor"))
+	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(&countAggregator{}, &models.AggregatorConfig{
+		Name:         "count",
+		Period:       time.Hour,
+		DropOriginal: true,
+		Pipeline:     "store",
+	}))
+
+	raw, vendor, store := &recordingOutput{}, &recordingOutput{}, &recordingOutput{}
+	for _, o := range []struct {
+		output   *recordingOutput
+		pipeline string
+	}{{raw, ""}, {vendor, "vendor"}, {vendor, "vendor"}, {store, "store"}} {
+		c.Outputs = append(c.Outputs, models.NewRunningOutput("recording", o.output,
+			&models.OutputConfig{Name: "recording", Pipeline: o.pipeline}, 0, 0))
+	}
+	require.NoError(t, c.ValidatePipelines())
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.Once(context.Background(), 0))
+
+	require.Equal(t, []string{"global_cpu"}, raw.names())
+	require.Equal(t, []string{"vendor_global_cpu", "vendor_global_cpu"}, vendor.names())
+	require.Equal(t, []string{"count"}, store.names())
+}
+
+// rejectingOutput permanently rejects all metrics.
+type rejectingOutput struct {
+	recordingOutput
+}
+
+func (o *rejectingOutput) Write(metrics []telegraf.Metric) error {
+	rejected := make([]int, len(metrics))
+	for i := range rejected {
+		rejected[i] = i
+	}
+	return &telegraf.PartialWriteError{
+		Err:           errors.New("type conflict"),
+		MetricsReject: rejected,
+	}
+}
+
+func TestAgent_DeadLetterOutput(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&staticInput{}, &models.InputConfig{Name: "static"}))
+
+	primary, other, rejected := &rejectingOutput{}, &recordingOutput{}, &recordingOutput{}
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("rejecting", primary,
+			&models.OutputConfig{Name: "rejecting", DeadLetterOutput: "rejected"}, 0, 0),
+		models.NewRunningOutput("recording", other,
+			&models.OutputConfig{Name: "recording"}, 0, 0),
+		models.NewRunningOutput("recording", rejected,
+			&models.OutputConfig{Name: "recording", Alias: "rejected"}, 0, 0),
+	)
+	require.NoError(t, c.ValidateDeadLetters())
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.Once(context.Background(), 0))
+
+	require.Empty(t, primary.names())
+	require.Equal(t, []string{"cpu"}, other.names())
+	require.Equal(t, []string{"cpu"}, rejected.names())
+}
+
+func TestAgent_TestDoesNotInitOutputs(t *testing.T) {
+	path, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(path)
+
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&staticInput{}, &models.InputConfig{Name: "static"}))
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("recording", &recordingOutput{},
+		&models.OutputConfig{Name: "recording", BufferStrategy: "disk", BufferDirectory: path}, 0, 0))
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	src := make(chan telegraf.Metric, 10)
+	require.NoError(t, a.test(context.Background(), 0, src))
+	require.Len(t, src, 1)
+
+	// The buffer of the output is not opened.
+	files, err := ioutil.ReadDir(path)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestAgent_Started(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&staticInput{}, &models.InputConfig{Name: "static"}))
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("recording", &recordingOutput{},
+		&models.OutputConfig{Name: "recording"}, 0, 0))
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	select {
+	case <-a.Started():
+		require.Fail(t, "agent started before Run")
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	select {
+	case <-a.Started():
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "agent not started")
+	}
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// unreachableOutput always fails to connect.
+type unreachableOutput struct {
+	recordingOutput
+}
+
+func (o *unreachableOutput) Connect() error {
+	return errors.New("connection refused")
+}
+
+func TestAgent_ConnectOutputStartupErrorBehavior(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+
+	output := models.NewRunningOutput("unreachable", &unreachableOutput{},
+		&models.OutputConfig{Name: "unreachable", StartupErrorBehavior: "retry"}, 0, 0)
+	require.NoError(t, a.connectOutput(context.Background(), output))
+
+	// The default behavior retries once after a delay, which is skipped here.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	output = models.NewRunningOutput("unreachable", &unreachableOutput{},
+		&models.OutputConfig{Name: "unreachable"}, 0, 0)
+	require.Error(t, a.connectOutput(ctx, output))
+}
+
+// timedInput emits metrics with explicit timestamps.
+type timedInput struct {
+	times []time.Time
+}
+
+func (i *timedInput) SampleConfig() string { return "" }
+func (i *timedInput) Description() string  { return "" }
+
+func (i *timedInput) Gather(acc telegraf.Accumulator) error {
+	for _, tm := range i.times {
+		acc.AddFields("cpu", map[string]interface{}{"value": 42}, nil, tm)
+	}
+	return nil
+}
+
+// firstTimeAggregator counts metrics and reports the time of the first one.
+type firstTimeAggregator struct {
+	countAggregator
+	first time.Time
+}
+
+func (a *firstTimeAggregator) Add(in telegraf.Metric) {
+	if a.count == 0 {
+		a.first = in.Time()
+	}
+	a.count++
+}
+
+func (a *firstTimeAggregator) Push(acc telegraf.Accumulator) {
+	if a.count > 0 {
+		acc.AddFields("count", map[string]interface{}{"value": a.count}, nil, a.first)
+	}
+}
+
+func TestAgent_TestPipeline(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&timedInput{
+		times: []time.Time{time.Unix(0, 0), time.Unix(5, 0), time.Unix(12, 0)},
+	}, &models.InputConfig{Name: "timed"}))
+	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(&firstTimeAggregator{}, &models.AggregatorConfig{
+		Name:         "count",
+		Period:       10 * time.Second,
+		DropOriginal: true,
+	}))
+
+	serializer, err := json.NewSerializer(time.Second)
+	require.NoError(t, err)
+	jsonOutput := models.NewRunningOutput("recording", &recordingOutput{},
+		&models.OutputConfig{Name: "recording", Alias: "json"}, 0, 0)
+	jsonOutput.Serializer = serializer
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("recording", &recordingOutput{},
+			&models.OutputConfig{Name: "recording"}, 0, 0),
+		jsonOutput,
+		models.NewRunningOutput("recording", &recordingOutput{},
+			&models.OutputConfig{
+				Name:   "recording",
+				Alias:  "dropped",
+				Filter: models.Filter{NameDrop: []string{"count"}},
+			}, 0, 0),
+	)
+	for _, o := range c.Outputs {
+		require.NoError(t, o.Config.Filter.Compile())
+	}
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, a.testPipeline(context.Background(), 0, &buf))
+
+	// The aggregator periods follow the metric timestamps, so the metrics
+	// are split over two periods without waiting for them.
+	expected := "# outputs.recording\n" +
+		"count value=2i 0\n" +
+		"count value=1i 12000000000\n" +
+		"# outputs.recording::json\n" +
+		`{"fields":{"value":2},"name":"count","tags":{},"timestamp":0}` + "\n" +
+		`{"fields":{"value":1},"name":"count","tags":{},"timestamp":12}` + "\n" +
+		"# outputs.recording::dropped\n"
+	require.Equal(t, expected, buf.String())
+}
+
+type batchOutput struct {
+	recordingOutput
+}
+
+func (o *batchOutput) UsesBatchFormat() bool { return true }
+
+func TestAgent_TestPipelineBatchFormat(t *testing.T) {
+	serializer, err := json.NewSerializer(time.Second)
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": 43}, time.Unix(0, 0)),
+	}
+
+	// Outputs serializing the metrics of a write as a batch are printed
+	// in the batch format of their serializer.
+	var buf bytes.Buffer
+	printer := newPrintOutput(&batchOutput{}, serializer)
+	require.NoError(t, printer.Write(metrics))
+	_, err = printer.WriteTo(&buf)
+	require.NoError(t, err)
+	expected := `{"metrics":[` +
+		`{"fields":{"value":42},"name":"cpu","tags":{},"timestamp":0},` +
+		`{"fields":{"value":43},"name":"mem","tags":{},"timestamp":0}]}`
+	require.JSONEq(t, expected, buf.String())
+
+	// Other outputs are printed metric by metric.
+	buf.Reset()
+	printer = newPrintOutput(&recordingOutput{}, serializer)
+	require.NoError(t, printer.Write(metrics))
+	_, err = printer.WriteTo(&buf)
+	require.NoError(t, err)
+	expected = `{"fields":{"value":42},"name":"cpu","tags":{},"timestamp":0}` + "\n" +
+		`{"fields":{"value":43},"name":"mem","tags":{},"timestamp":0}` + "\n"
+	require.Equal(t, expected, buf.String())
+}
+
+func TestAgent_TestPipelineDoesNotInitOutputs(t *testing.T) {
+	path, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(path)
+	deadLetters := filepath.Join(path, "dead-letters")
+	buffer := filepath.Join(path, "buffer")
+
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&staticInput{}, &models.InputConfig{Name: "static"}))
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("recording", &recordingOutput{},
+		&models.OutputConfig{
+			Name:            "recording",
+			BufferStrategy:  "disk",
+			BufferDirectory: buffer,
+			DeadLetterFile:  deadLetters,
+		}, 0, 0))
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, a.testPipeline(context.Background(), 0, &buf))
+	require.Contains(t, buf.String(), "cpu value=42i")
+
+	// Neither the buffer nor the dead-letter file of the output is opened.
+	files, err := ioutil.ReadDir(path)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/redact"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// printOutput replaces an output in pipeline test mode.  It collects the
+// serialized metrics so that they can be printed once all are written.
+type printOutput struct {
+	sync.Mutex
+	serializer serializers.Serializer
+	batch      bool
+	buf        bytes.Buffer
+}
+
+// newPrintOutput returns a printOutput using the serializer of the output.
+// Outputs without a data_format are printed in line protocol.  The metrics
+// of a write are serialized as a batch if the output does so.
+func newPrintOutput(output telegraf.Output, serializer serializers.Serializer) *printOutput {
+	if serializer == nil {
+		s := influx.NewSerializer()
+		s.SetFieldSortOrder(influx.SortFields)
+		return &printOutput{serializer: s}
+	}
+
+	var batch bool
+	if o, ok := output.(serializers.BatchSerializerOutput); ok {
+		batch = o.UsesBatchFormat()
+	}
+	return &printOutput{serializer: serializer, batch: batch}
+}
+
+func (o *printOutput) SampleConfig() string { return "" }
+func (o *printOutput) Description() string  { return "" }
+func (o *printOutput) Connect() error       { return nil }
+func (o *printOutput) Close() error         { return nil }
+
+func (o *printOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.batch {
+		octets, err := o.serializer.SerializeBatch(metrics)
+		if err != nil {
+			log.Printf("E! [agent] Could not serialize metrics: %v", err)
+			return nil
+		}
+		o.buf.Write(octets)
+		return nil
+	}
+
+	for _, metric := range metrics {
+		octets, err := o.serializer.Serialize(metric)
+		if err != nil {
+			log.Printf("E! [agent] Could not serialize metric: %v", err)
+			continue
+		}
+		o.buf.Write(octets)
+	}
+	return nil
+}
+
+// WriteTo writes the collected metrics to w with secrets redacted.
+func (o *printOutput) WriteTo(w io.Writer) (int64, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	n, err := io.WriteString(w, redact.String(o.buf.String()))
+	return int64(n), err
+}
@@ -0,0 +1,313 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+)
+
+// ErrRestartRequired is returned by Reload when the new configuration cannot
+// be applied to the running agent and the agent must be restarted instead.
+var ErrRestartRequired = errors.New("restart required")
+
+// Reload applies a newly loaded configuration to the running agent.  Only the
+// inputs, outputs and processors whose configuration changed are stopped,
+// started or replaced; all other plugins keep running and unchanged outputs
+// keep their buffered metrics.
+//
+// If the changes cannot be applied without a restart an error wrapping
+// ErrRestartRequired is returned and the running agent is left untouched.
+// Outputs replaced by an output using the same disk buffer or dead-letter file
+// are stopped before the new output is initialized, so a failure after that
+// point also returns ErrRestartRequired.
+func (a *Agent) Reload(newConfig *config.Config) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.iu == nil || a.ou == nil || a.ctx.Err() != nil {
+		return fmt.Errorf("%w: agent is not running", ErrRestartRequired)
+	}
+
+	diff := a.Config.Diff(newConfig)
+	if diff.RestartReason != "" {
+		return fmt.Errorf("%w: %s", ErrRestartRequired, diff.RestartReason)
+	}
+	if diff.Empty() {
+		log.Printf("I! [agent] Configuration unchanged")
+		return nil
+	}
+
+	// Initialize all new plugins before changing the running ones, so that a
+	// failure leaves the agent as it was.
+	for _, input := range diff.AddedInputs {
+		if err := input.Init(); err != nil {
+			return fmt.Errorf("could not initialize input %s: %v",
+				input.LogName(), err)
+		}
+	}
+	for _, processor := range diff.ReplacedProcessors {
+		if err := processor.Init(); err != nil {
+			return fmt.Errorf("could not initialize processor %s: %v",
+				processor.Config.Name, err)
+		}
+	}
+	// Outputs taking over the buffer directory or dead-letter file of a
+	// removed output are initialized once the removed output is closed.
+	replaced, takeover := filesTakenOver(diff.AddedOutputs, diff.RemovedOutputs)
+	for _, output := range diff.AddedOutputs {
+		if takeover[output] {
+			continue
+		}
+		if err := output.Init(); err != nil {
+			closeOutputs(diff.AddedOutputs)
+			return fmt.Errorf("could not initialize output %s: %v",
+				output.Config.Name, err)
+		}
+	}
+
+	// From the removal of the replaced outputs on, a failure leaves the
+	// agent without these outputs and a restart is requested.
+	wrap := func(err error) error {
+		if len(replaced) == 0 {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+	if len(replaced) != 0 {
+		a.removeOutputs(replaced)
+		for _, output := range diff.AddedOutputs {
+			if !takeover[output] {
+				continue
+			}
+			if err := output.Init(); err != nil {
+				closeOutputs(diff.AddedOutputs)
+				return wrap(fmt.Errorf("could not initialize output %s: %v",
+					output.Config.Name, err))
+			}
+		}
+	}
+
+	// Connect the new outputs before removing the old ones so that no
+	// metrics are lost while an output is replaced.
+	for _, output := range diff.AddedOutputs {
+		if err := a.connectOutput(a.ctx, output); err != nil {
+			closeOutputs(diff.AddedOutputs)
+			return wrap(fmt.Errorf("connecting output %s: %w", output.LogName(), err))
+		}
+	}
+	a.addOutputs(diff.AddedOutputs)
+	a.removeOutputs(remaining(diff.RemovedOutputs, replaced))
+
+	// From here on a failure leaves the agent partially reloaded, so a
+	// restart is requested to get back to a consistent state.
+	pipelines := []*pipelineUnit{a.pl}
+	for _, p := range a.ou.pipelines {
+		pipelines = append(pipelines, p.unit)
+	}
+	for _, pl := range pipelines {
+		if err := a.replaceProcessors(pl.pu, diff.ReplacedProcessors); err != nil {
+			return fmt.Errorf("%w: %v", ErrRestartRequired, err)
+		}
+		if err := a.replaceProcessors(pl.apu, diff.ReplacedProcessors); err != nil {
+			return fmt.Errorf("%w: %v", ErrRestartRequired, err)
+		}
+	}
+
+	a.removeInputs(diff.RemovedInputs)
+	if err := a.addInputs(diff.AddedInputs); err != nil {
+		return fmt.Errorf("%w: %v", ErrRestartRequired, err)
+	}
+
+	a.Config.Apply(diff)
+
+	log.Printf("I! [agent] Configuration reloaded: %d inputs added, %d removed; "+
+		"%d outputs added, %d removed; %d processors replaced",
+		len(diff.AddedInputs), len(diff.RemovedInputs),
+		len(diff.AddedOutputs), len(diff.RemovedOutputs),
+		len(diff.ReplacedProcessors))
+	return nil
+}
+
+// filesTakenOver returns the removed outputs whose buffer directory or
+// dead-letter file is used by an added output, and the added outputs using
+// them.  The files must not be opened twice, so the removed output has to be
+// closed before the added one is initialized.
+func filesTakenOver(added, removed []*models.RunningOutput) ([]*models.RunningOutput, map[*models.RunningOutput]bool) {
+	var replaced []*models.RunningOutput
+	takeover := make(map[*models.RunningOutput]bool)
+	for _, old := range removed {
+		shared := false
+		for _, output := range added {
+			if sharesFiles(old.Config, output.Config) {
+				takeover[output] = true
+				shared = true
+			}
+		}
+		if shared {
+			replaced = append(replaced, old)
+		}
+	}
+	return replaced, takeover
+}
+
+func sharesFiles(a, b *models.OutputConfig) bool {
+	if a.BufferStrategy == "disk" && b.BufferStrategy == "disk" &&
+		filepath.Clean(a.BufferDirectory) == filepath.Clean(b.BufferDirectory) {
+		return true
+	}
+	return a.DeadLetterFile != "" && b.DeadLetterFile != "" &&
+		filepath.Clean(a.DeadLetterFile) == filepath.Clean(b.DeadLetterFile)
+}
+
+// closeOutputs closes outputs that were not started, releasing their buffers
+// and dead-letter files.
+func closeOutputs(outputs []*models.RunningOutput) {
+	for _, output := range outputs {
+		output.Close()
+	}
+}
+
+// remaining returns the outputs that are not in exclude.
+func remaining(outputs, exclude []*models.RunningOutput) []*models.RunningOutput {
+	var result []*models.RunningOutput
+outer:
+	for _, output := range outputs {
+		for _, e := range exclude {
+			if output == e {
+				continue outer
+			}
+		}
+		result = append(result, output)
+	}
+	return result
+}
+
+// addOutputs adds connected outputs to the running output unit.
+func (a *Agent) addOutputs(outputs []*models.RunningOutput) {
+	unit := a.ou
+	unit.Lock()
+	defer unit.Unlock()
+
+	for _, output := range outputs {
+		log.Printf("D! [agent] Starting output %s", output.LogName())
+		unit.outputs = append(unit.outputs, output)
+		a.runOutput(unit, output)
+	}
+}
+
+// removeOutputs stops the outputs, writing their buffered metrics one last
+// time, and closes them.
+func (a *Agent) removeOutputs(outputs []*models.RunningOutput) {
+	unit := a.ou
+
+	var runners []*runner
+	unit.Lock()
+	for _, output := range outputs {
+		for i, o := range unit.outputs {
+			if o == output {
+				unit.outputs = append(unit.outputs[:i], unit.outputs[i+1:]...)
+				break
+			}
+		}
+		if r, ok := unit.runners[output]; ok {
+			runners = append(runners, r)
+			delete(unit.runners, output)
+		}
+	}
+	unit.Unlock()
+
+	// The final flush happens outside of the lock so that metrics keep
+	// flowing to the remaining outputs.
+	for _, r := range runners {
+		r.stop()
+	}
+	for _, output := range outputs {
+		log.Printf("D! [agent] Stopped output %s", output.LogName())
+		output.Close()
+	}
+}
+
+// replaceProcessors swaps processors of the running chain with their
+// replacement.
+func (a *Agent) replaceProcessors(
+	units []*processorUnit,
+	replaced map[*models.RunningProcessor]*models.RunningProcessor,
+) error {
+	for _, unit := range units {
+		unit.Lock()
+		processor, ok := replaced[unit.processor]
+		if !ok {
+			unit.Unlock()
+			continue
+		}
+
+		unit.processor.Stop()
+		err := processor.Start(NewAccumulator(processor, unit.dst))
+		if err != nil {
+			// Keep the chain intact by restarting the old processor.
+			if err := unit.processor.Start(NewAccumulator(unit.processor, unit.dst)); err != nil {
+				log.Printf("E! [agent] Restarting processor %s: %v", unit.processor.LogName(), err)
+			}
+			unit.Unlock()
+			return fmt.Errorf("starting processor %s: %w", processor.LogName(), err)
+		}
+		log.Printf("D! [agent] Replaced processor %s", processor.LogName())
+		unit.processor = processor
+		unit.Unlock()
+	}
+	return nil
+}
+
+// removeInputs stops gathering from the inputs.
+func (a *Agent) removeInputs(inputs []*models.RunningInput) {
+	unit := a.iu
+
+	var runners []*runner
+	unit.Lock()
+	for _, input := range inputs {
+		for i, in := range unit.inputs {
+			if in == input {
+				unit.inputs = append(unit.inputs[:i], unit.inputs[i+1:]...)
+				break
+			}
+		}
+		if r, ok := unit.runners[input]; ok {
+			runners = append(runners, r)
+			delete(unit.runners, input)
+		}
+	}
+	unit.removing.Add(1)
+	unit.Unlock()
+	defer unit.removing.Done()
+
+	// Waiting for a running gather happens outside of the lock so that an
+	// input that does not return does not block the other inputs.
+	for _, r := range runners {
+		r.stop()
+	}
+	stopServiceInputs(inputs)
+	for _, input := range inputs {
+		log.Printf("D! [agent] Stopped input %s", input.LogName())
+	}
+}
+
+// addInputs starts the inputs and their periodic gather.
+func (a *Agent) addInputs(inputs []*models.RunningInput) error {
+	unit := a.iu
+	unit.Lock()
+	defer unit.Unlock()
+
+	for _, input := range inputs {
+		if err := a.startServiceInput(unit.dst, input); err != nil {
+			return err
+		}
+		unit.inputs = append(unit.inputs, input)
+		a.runInput(a.ctx, a.startTime, unit, input)
+		log.Printf("D! [agent] Started input %s", input.LogName())
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadDiskBufferConfig = `
+[agent]
+  flush_interval = "1h"
+
+[[outputs.http]]
+  url = %q
+  buffer_strategy = "disk"
+  buffer_directory = %q
+  %s
+`
+
+func TestReload_DiskBuffer(t *testing.T) {
+	path, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(path)
+
+	var writes int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&writes, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(fmt.Sprintf(reloadDiskBufferConfig, ts.URL, path, ""))))
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+	select {
+	case <-a.Started():
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "agent not started")
+	}
+
+	old := a.Config.Outputs[0]
+	old.AddMetric(testutil.TestMetric(42))
+
+	loaded := config.NewConfig()
+	require.NoError(t, loaded.LoadConfigData([]byte(fmt.Sprintf(reloadDiskBufferConfig, ts.URL, path, "metric_batch_size = 10"))))
+	require.NoError(t, a.Reload(loaded))
+
+	// The replaced output writes its metric and is closed before the new
+	// one opens the buffer, so the metric is not loaded again.
+	output := a.Config.Outputs[0]
+	require.NotSame(t, old, output)
+	require.Equal(t, 0, output.BufferLength())
+	require.Equal(t, int64(1), atomic.LoadInt64(&writes))
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// blockingInput blocks in Gather until it is released.
+type blockingInput struct {
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (i *blockingInput) SampleConfig() string { return "" }
+func (i *blockingInput) Description() string  { return "" }
+
+func (i *blockingInput) Gather(acc telegraf.Accumulator) error {
+	i.once.Do(func() { close(i.started) })
+	<-i.release
+	return nil
+}
+
+func TestReload_RemoveBlockedInput(t *testing.T) {
+	input := &blockingInput{started: make(chan struct{}), release: make(chan struct{})}
+	blocked := models.NewRunningInput(input, &models.InputConfig{
+		Name:     "blocking",
+		Interval: 10 * time.Millisecond,
+	})
+
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, blocked)
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+	select {
+	case <-input.started:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "gather not started")
+	}
+
+	removed := make(chan struct{})
+	go func() {
+		a.removeInputs([]*models.RunningInput{blocked})
+		close(removed)
+	}()
+
+	// The input is unlinked and other inputs can be added while it is
+	// still gathering.
+	require.Eventually(t, func() bool {
+		a.iu.Lock()
+		defer a.iu.Unlock()
+		return len(a.iu.inputs) == 0
+	}, 5*time.Second, time.Millisecond)
+	added := models.NewRunningInput(&staticInput{}, &models.InputConfig{Name: "static"})
+	require.NoError(t, a.addInputs([]*models.RunningInput{added}))
+
+	select {
+	case <-removed:
+		require.Fail(t, "input removed before its gather returned")
+	default:
+	}
+	close(input.release)
+	select {
+	case <-removed:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "input not removed")
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
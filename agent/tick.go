@@ -7,6 +7,7 @@ import (
 
 	"github.com/benbjohnson/clock"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/robfig/cron/v3"
 )
 
 type empty struct{}
@@ -273,3 +274,90 @@ func (t *RollingTicker) Stop() {
 	t.cancel()
 	t.wg.Wait()
 }
+
+// ScheduledTicker delivers ticks at the activation times of a cron schedule
+// plus an optional jitter.
+//
+// Each tick is scheduled based on the clock at the previous activation, so
+// changes to the system clock are handled at the latest after the next tick.
+// Activations missed while the clock jumped forward are not caught up on.
+//
+// Ticks are dropped for slow consumers.
+type ScheduledTicker struct {
+	schedule cron.Schedule
+	jitter   time.Duration
+	ch       chan time.Time
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewScheduledTicker(schedule cron.Schedule, jitter time.Duration) *ScheduledTicker {
+	return newScheduledTicker(schedule, jitter, clock.New())
+}
+
+func newScheduledTicker(schedule cron.Schedule, jitter time.Duration, clock clock.Clock) *ScheduledTicker {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &ScheduledTicker{
+		schedule: schedule,
+		jitter:   jitter,
+		ch:       make(chan time.Time, 1),
+		cancel:   cancel,
+	}
+
+	now := clock.Now()
+	next := schedule.Next(now)
+	timer := clock.Timer(t.delay(now, next))
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.run(ctx, timer, next, clock)
+	}()
+
+	return t
+}
+
+func (t *ScheduledTicker) delay(now, next time.Time) time.Duration {
+	return next.Sub(now) + internal.RandomDuration(t.jitter)
+}
+
+func (t *ScheduledTicker) run(ctx context.Context, timer *clock.Timer, next time.Time, clock clock.Clock) {
+	for {
+		// The schedule has no further activations.
+		if next.IsZero() {
+			timer.Stop()
+			<-ctx.Done()
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case tick := <-timer.C:
+			select {
+			case t.ch <- tick:
+			default:
+			}
+
+			// The timer may fire early, never schedule the same activation
+			// twice.
+			now := clock.Now()
+			from := now
+			if from.Before(next) {
+				from = next
+			}
+			next = t.schedule.Next(from)
+			timer.Reset(t.delay(now, next))
+		}
+	}
+}
+
+func (t *ScheduledTicker) Elapsed() <-chan time.Time {
+	return t.ch
+}
+
+func (t *ScheduledTicker) Stop() {
+	t.cancel()
+	t.wg.Wait()
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/robfig/cron/v3"
 	"github.com/stretchr/testify/require"
 )
 
@@ -260,3 +261,88 @@ func simulatedDist(ticker Ticker, clock *clock.Mock) Distribution {
 
 	return dist
 }
+
+func TestScheduledTicker(t *testing.T) {
+	schedule, err := cron.ParseStandard("CRON_TZ=UTC 30 2 * * *")
+	require.NoError(t, err)
+
+	clock := clock.NewMock()
+	since := clock.Now()
+	until := since.Add(72 * time.Hour)
+
+	ticker := newScheduledTicker(schedule, 0, clock)
+	defer ticker.Stop()
+
+	expected := []time.Time{
+		time.Date(1970, 1, 1, 2, 30, 0, 0, time.UTC),
+		time.Date(1970, 1, 2, 2, 30, 0, 0, time.UTC),
+		time.Date(1970, 1, 3, 2, 30, 0, 0, time.UTC),
+	}
+
+	actual := []time.Time{}
+	for !clock.Now().After(until) {
+		select {
+		case tm := <-ticker.Elapsed():
+			actual = append(actual, tm.UTC())
+		default:
+		}
+		clock.Add(10 * time.Minute)
+	}
+
+	require.Equal(t, expected, actual)
+}
+
+func TestScheduledTickerTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// Business days at 9 in New York, starting on Thursday the 1st.
+	schedule, err := cron.ParseStandard("0 9 * * MON-FRI")
+	require.NoError(t, err)
+	schedule.(*cron.SpecSchedule).Location = loc
+
+	clock := clock.NewMock()
+	since := clock.Now()
+	until := since.Add(7 * 24 * time.Hour)
+
+	ticker := newScheduledTicker(schedule, 0, clock)
+	defer ticker.Stop()
+
+	expected := []time.Time{
+		time.Date(1970, 1, 1, 9, 0, 0, 0, loc),
+		time.Date(1970, 1, 2, 9, 0, 0, 0, loc),
+		time.Date(1970, 1, 5, 9, 0, 0, 0, loc),
+		time.Date(1970, 1, 6, 9, 0, 0, 0, loc),
+		time.Date(1970, 1, 7, 9, 0, 0, 0, loc),
+	}
+
+	actual := []time.Time{}
+	for !clock.Now().After(until) {
+		select {
+		case tm := <-ticker.Elapsed():
+			actual = append(actual, tm.In(loc))
+		default:
+		}
+		clock.Add(30 * time.Minute)
+	}
+
+	require.Equal(t, expected, actual)
+}
+
+func TestScheduledTickerMissedTick(t *testing.T) {
+	schedule, err := cron.ParseStandard("@every 10s")
+	require.NoError(t, err)
+
+	clock := clock.NewMock()
+	since := clock.Now()
+
+	ticker := newScheduledTicker(schedule, 0, clock)
+	defer ticker.Stop()
+
+	clock.Add(25 * time.Second)
+	tm := <-ticker.Elapsed()
+	require.Equal(t, since.Add(10*time.Second).UTC(), tm.UTC())
+	clock.Add(5 * time.Second)
+	tm = <-ticker.Elapsed()
+	require.Equal(t, since.Add(30*time.Second).UTC(), tm.UTC())
+}
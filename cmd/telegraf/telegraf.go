@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"os/signal"
 	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -26,6 +28,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 	_ "github.com/influxdata/telegraf/plugins/processors/all"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/all"
 )
 
 // If you update these, update usage.go and usage_windows.go
@@ -35,14 +38,25 @@ var pprofAddr = flag.String("pprof-addr", "",
 	"pprof address to listen on, not activate pprof if empty")
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
-var fTest = flag.Bool("test", false, "enable test mode: gather metrics, print them out, and exit. Note: Test mode only runs inputs, not processors, aggregators, or outputs")
+var fTest = flag.Bool("test", false, "enable test mode: gather metrics, print them out, and exit. Note: Test mode does not run outputs")
+var fTestPipeline = flag.Bool("test-pipeline", false, "enable pipeline test mode: gather metrics, run them through processors and aggregators, print them in the format of each output, and exit")
 var fTestWait = flag.Int("test-wait", 0, "wait up to this many seconds for service inputs to complete in test mode")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
+var fConfigDirectoryFormats = flag.String("config-directory-formats", "",
+	"formats of the other files to load from the config directory, 'toml', 'yaml' or 'json', separator is :")
+var fConfigPollInterval = flag.Duration("config-poll-interval", 0,
+	"poll a remote configuration for changes at this interval and reload it, not activated if 0")
+var fConfigCacheFile = flag.String("config-cache-file", "",
+	"file to store the last good remote configuration in, it is used when the server is unreachable on startup")
+var fConfigPublicKey = flag.String("config-public-key", "",
+	"PEM encoded ed25519 public key verifying the signature of the remote configuration")
 var fVersion = flag.Bool("version", false, "display the version and exit")
 var fSampleConfig = flag.Bool("sample-config", false,
 	"print out full sample configuration")
+var fSampleConfigFormat = flag.String("sample-config-format", "toml",
+	"format of the sample configuration, 'toml', 'yaml' or 'json'")
 var fPidfile = flag.String("pidfile", "", "file to write our pid to")
 var fSectionFilters = flag.String("section-filter", "",
 	"filter the sections to print, separator is ':'. Valid values are 'agent', 'global_tags', 'outputs', 'processors', 'aggregators' and 'inputs'")
@@ -77,6 +91,15 @@ var (
 
 var stop chan struct{}
 
+// remoteConfig is set if the configuration is loaded from a URL.
+var remoteConfig *config.RemoteConfig
+
+// running is the agent currently running, it receives live reloads.
+var running struct {
+	sync.Mutex
+	agent *agent.Agent
+}
+
 func reloadLoop(
 	inputFilters []string,
 	outputFilters []string,
@@ -93,17 +116,52 @@ func reloadLoop(
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
 			syscall.SIGTERM, syscall.SIGINT)
+
+		var poll <-chan time.Time
+		var ticker *time.Ticker
+		if remoteConfig != nil && *fConfigPollInterval > 0 {
+			ticker = time.NewTicker(*fConfigPollInterval)
+			poll = ticker.C
+		}
+
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == syscall.SIGHUP {
+			for {
+				select {
+				case sig := <-signals:
+					if sig != syscall.SIGHUP {
+						cancel()
+						return
+					}
 					log.Printf("I! Reloading Telegraf config")
-					<-reload
-					reload <- true
+				case <-poll:
+					changed, err := remoteConfig.Poll()
+					if err != nil {
+						log.Printf("W! [telegraf] Error polling config from %s: %v", remoteConfig.URL, err)
+						continue
+					}
+					if !changed {
+						continue
+					}
+					log.Printf("I! Config changed on %s, reloading Telegraf config", remoteConfig.URL)
+				case <-stop:
+					cancel()
+					return
 				}
+
+				err := reloadAgent(inputFilters, outputFilters)
+				if err == nil {
+					continue
+				}
+				if !errors.Is(err, agent.ErrRestartRequired) {
+					log.Printf("E! [telegraf] Error reloading config, keeping the running config: %v", err)
+					continue
+				}
+
+				log.Printf("I! Restarting Telegraf: %v", err)
+				<-reload
+				reload <- true
 				cancel()
-			case <-stop:
-				cancel()
+				return
 			}
 		}()
 
@@ -111,47 +169,152 @@ func reloadLoop(
 		if err != nil && err != context.Canceled {
 			log.Fatalf("E! [telegraf] Error running agent: %v", err)
 		}
+		signal.Stop(signals)
+		if ticker != nil {
+			ticker.Stop()
+		}
 	}
 }
 
-func runAgent(ctx context.Context,
-	inputFilters []string,
-	outputFilters []string,
-) error {
-	log.Printf("I! Starting Telegraf %s", version)
+// reloadAgent loads the config and applies the changes to the running agent.
+func reloadAgent(inputFilters []string, outputFilters []string) error {
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		return err
+	}
 
+	running.Lock()
+	defer running.Unlock()
+	if running.agent == nil {
+		return fmt.Errorf("%w: agent is not running", agent.ErrRestartRequired)
+	}
+	if err := running.agent.Reload(c); err != nil {
+		return err
+	}
+	saveRemoteConfig()
+	return nil
+}
+
+// loadConfig loads and validates the config files.
+func loadConfig(inputFilters []string, outputFilters []string) (*config.Config, error) {
 	// If no other options are specified, load the config file and run.
 	c := config.NewConfig()
 	c.OutputFilters = outputFilters
 	c.InputFilters = inputFilters
-	err := c.LoadConfig(*fConfig)
+	var err error
+	if remoteConfig != nil {
+		err = c.LoadRemoteConfig(remoteConfig)
+	} else {
+		err = c.LoadConfig(*fConfig)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if *fConfigDirectory != "" {
+		c.DirectoryFormats = directoryFormats()
 		err = c.LoadDirectory(*fConfigDirectory)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 	if !*fTest && len(c.Outputs) == 0 {
-		return errors.New("Error: no outputs found, did you provide a valid config file?")
+		return nil, errors.New("Error: no outputs found, did you provide a valid config file?")
 	}
 	if *fPlugins == "" && len(c.Inputs) == 0 {
-		return errors.New("Error: no inputs found, did you provide a valid config file?")
+		return nil, errors.New("Error: no inputs found, did you provide a valid config file?")
+	}
+	if err := c.ValidatePipelines(); err != nil {
+		return nil, err
+	}
+	if err := c.ValidateDeadLetters(); err != nil {
+		return nil, err
 	}
 
 	if int64(c.Agent.Interval.Duration) <= 0 {
-		return fmt.Errorf("Agent interval must be positive, found %s",
+		return nil, fmt.Errorf("Agent interval must be positive, found %s",
 			c.Agent.Interval.Duration)
 	}
 
 	if int64(c.Agent.FlushInterval.Duration) <= 0 {
-		return fmt.Errorf("Agent flush_interval must be positive; found %s",
+		return nil, fmt.Errorf("Agent flush_interval must be positive; found %s",
 			c.Agent.Interval.Duration)
 	}
 
+	return c, nil
+}
+
+// saveRemoteConfig caches the remote config, it is called once the agent has
+// applied the config.
+func saveRemoteConfig() {
+	if remoteConfig == nil {
+		return
+	}
+	if err := remoteConfig.Save(); err != nil {
+		log.Printf("W! [telegraf] Error caching config from %s: %v", remoteConfig.URL, err)
+	}
+}
+
+// directoryFormats returns the formats of the --config-directory-formats
+// flag.
+func directoryFormats() []string {
+	if *fConfigDirectoryFormats == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(*fConfigDirectoryFormats), ":")
+}
+
+// checkConfig loads the config files in check mode and prints the problems
+// found as JSON.  It returns the exit status.
+func checkConfig(inputFilters []string, outputFilters []string) int {
+	c := config.NewConfig()
+	c.Check = true
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	if err := c.LoadConfig(*fConfig); err != nil {
+		c.Problems = append(c.Problems, config.Problem{Message: err.Error()})
+	}
+	if *fConfigDirectory != "" {
+		c.DirectoryFormats = directoryFormats()
+		if err := c.LoadDirectory(*fConfigDirectory); err != nil {
+			c.Problems = append(c.Problems, config.Problem{File: *fConfigDirectory, Message: err.Error()})
+		}
+	}
+	if len(c.Outputs) == 0 {
+		c.Problems = append(c.Problems, config.Problem{Message: "no outputs found"})
+	}
+	if *fPlugins == "" && len(c.Inputs) == 0 {
+		c.Problems = append(c.Problems, config.Problem{Message: "no inputs found"})
+	}
+	c.CheckPlugins()
+
+	problems := c.Problems
+	if problems == nil {
+		problems = []config.Problem{}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(problems); err != nil {
+		log.Printf("E! %s", err)
+		return 2
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func runAgent(ctx context.Context,
+	inputFilters []string,
+	outputFilters []string,
+) error {
+	log.Printf("I! Starting Telegraf %s", version)
+
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		return err
+	}
+
 	ag, err := agent.NewAgent(c)
 	if err != nil {
 		return err
@@ -162,6 +325,7 @@ func runAgent(ctx context.Context,
 		Debug:               ag.Config.Agent.Debug || *fDebug,
 		Quiet:               ag.Config.Agent.Quiet || *fQuiet,
 		LogTarget:           ag.Config.Agent.LogTarget,
+		LogFormat:           ag.Config.Agent.LogFormat,
 		Logfile:             ag.Config.Agent.Logfile,
 		RotationInterval:    ag.Config.Agent.LogfileRotationInterval,
 		RotationMaxSize:     ag.Config.Agent.LogfileRotationMaxSize,
@@ -175,6 +339,11 @@ func runAgent(ctx context.Context,
 		return ag.Once(ctx, wait)
 	}
 
+	if *fTestPipeline {
+		wait := time.Duration(*fTestWait) * time.Second
+		return ag.TestPipeline(ctx, wait)
+	}
+
 	if *fTest || *fTestWait != 0 {
 		wait := time.Duration(*fTestWait) * time.Second
 		return ag.Test(ctx, wait)
@@ -204,6 +373,24 @@ func runAgent(ctx context.Context,
 		}
 	}
 
+	running.Lock()
+	running.agent = ag
+	running.Unlock()
+
+	defer func() {
+		running.Lock()
+		running.agent = nil
+		running.Unlock()
+	}()
+
+	go func() {
+		select {
+		case <-ag.Started():
+			saveRemoteConfig()
+		case <-ctx.Done():
+		}
+	}()
+
 	return ag.Run(ctx)
 }
 
@@ -212,6 +399,34 @@ func usageExit(rc int) {
 	os.Exit(rc)
 }
 
+// printSampleConfig prints the sample config in the format of the
+// --sample-config-format flag.
+func printSampleConfig(
+	sectionFilters []string,
+	inputFilters []string,
+	outputFilters []string,
+	aggregatorFilters []string,
+	processorFilters []string,
+) {
+	switch *fSampleConfigFormat {
+	case config.FormatTOML, config.FormatYAML, config.FormatJSON:
+	default:
+		log.Fatalf("E! Invalid sample config format %q, must be 'toml', 'yaml' or 'json'", *fSampleConfigFormat)
+	}
+
+	err := config.PrintSampleConfig(
+		*fSampleConfigFormat,
+		sectionFilters,
+		inputFilters,
+		outputFilters,
+		aggregatorFilters,
+		processorFilters,
+	)
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
+}
+
 func formatFullVersion() string {
 	var parts = []string{"Telegraf"}
 
@@ -292,7 +507,10 @@ func main() {
 			fmt.Println(formatFullVersion())
 			return
 		case "config":
-			config.PrintSampleConfig(
+			if len(args) > 1 && args[1] == "check" {
+				os.Exit(checkConfig(inputFilters, outputFilters))
+			}
+			printSampleConfig(
 				sectionFilters,
 				inputFilters,
 				outputFilters,
@@ -331,7 +549,7 @@ func main() {
 		fmt.Println(formatFullVersion())
 		return
 	case *fSampleConfig:
-		config.PrintSampleConfig(
+		printSampleConfig(
 			sectionFilters,
 			inputFilters,
 			outputFilters,
@@ -348,6 +566,14 @@ func main() {
 		return
 	}
 
+	if config.IsRemoteConfig(*fConfig) {
+		var err error
+		remoteConfig, err = config.NewRemoteConfig(*fConfig, *fConfigCacheFile, *fConfigPublicKey)
+		if err != nil {
+			log.Fatal("E! " + err.Error())
+		}
+	}
+
 	shortVersion := version
 	if shortVersion == "" {
 		shortVersion = "unknown"
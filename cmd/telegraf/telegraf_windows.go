@@ -87,6 +87,18 @@ func runAsWindowsService(inputFilters, outputFilters, aggregatorFilters, process
 		if *fConfigDirectory != "" {
 			svcConfig.Arguments = append(svcConfig.Arguments, "--config-directory", *fConfigDirectory)
 		}
+		if *fConfigDirectoryFormats != "" {
+			svcConfig.Arguments = append(svcConfig.Arguments, "--config-directory-formats", *fConfigDirectoryFormats)
+		}
+		if *fConfigPollInterval > 0 {
+			svcConfig.Arguments = append(svcConfig.Arguments, "--config-poll-interval", fConfigPollInterval.String())
+		}
+		if *fConfigCacheFile != "" {
+			svcConfig.Arguments = append(svcConfig.Arguments, "--config-cache-file", *fConfigCacheFile)
+		}
+		if *fConfigPublicKey != "" {
+			svcConfig.Arguments = append(svcConfig.Arguments, "--config-public-key", *fConfigPublicKey)
+		}
 		//set servicename to service cmd line, to have a custom name after relaunch as a service
 		svcConfig.Arguments = append(svcConfig.Arguments, "--service-name", *fServiceName)
 
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+// Problem is an error in the configuration found in check mode.
+type Problem struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Plugin  string `json:"plugin,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	s := p.File
+	if p.Line > 0 {
+		s += fmt.Sprintf(":%d", p.Line)
+	}
+	if p.Plugin != "" {
+		s += ": " + p.Plugin
+	}
+	return s + ": " + p.Message
+}
+
+// addProblem records err as a problem of the plugin defined by table.  It
+// returns false if the config is not in check mode, in that case the caller
+// must handle the error.
+func (c *Config) addProblem(plugin string, table *ast.Table, err error) bool {
+	if !c.Check {
+		return false
+	}
+
+	p := Problem{File: c.file, Plugin: plugin, Message: err.Error()}
+	var lerr *toml.LineError
+	if errors.As(err, &lerr) {
+		p.Line = lerr.Line
+		p.Message = lerr.Err.Error()
+		if lerr.StructField != "" {
+			p.Message = lerr.StructField + ": " + p.Message
+		}
+	} else if table != nil {
+		p.Line = table.Line
+	}
+	// YAML and JSON are converted to TOML, its lines would be misleading.
+	if configFormat(c.file) != FormatTOML {
+		p.Line = 0
+	}
+
+	// Processors are loaded twice, once for the aggregators.
+	for _, existing := range c.Problems {
+		if existing == p {
+			return true
+		}
+	}
+	c.Problems = append(c.Problems, p)
+	return true
+}
+
+// unmarshalTable applies the table to the plugin.  In check mode all unknown
+// keys are recorded, otherwise the first one is an error.
+func (c *Config) unmarshalTable(plugin string, table *ast.Table, v interface{}) error {
+	if !c.Check {
+		return toml.UnmarshalTable(table, v)
+	}
+
+	var unknown []string
+	cfg := toml.DefaultConfig
+	cfg.MissingField = func(_ reflect.Type, key string) error {
+		unknown = append(unknown, key)
+		return nil
+	}
+	err := cfg.UnmarshalTable(table, v)
+
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		c.addProblem(plugin, table, &toml.LineError{
+			Line: keyLine(table, key),
+			Err:  fmt.Errorf("unknown key %q", key),
+		})
+	}
+	return err
+}
+
+// checkInit initializes the plugin in check mode and records the failure.
+func (c *Config) checkInit(plugin string, table *ast.Table, p interface{}) {
+	if !c.Check {
+		return
+	}
+	if i, ok := p.(telegraf.Initializer); ok {
+		if err := i.Init(); err != nil {
+			c.addProblem(plugin, table, fmt.Errorf("initialization failed: %w", err))
+		}
+	}
+}
+
+// CheckPlugins records the problems of the plugins in relation to each
+// other and sorts all problems by file and line.  It must be called once all
+// configuration is loaded.
+func (c *Config) CheckPlugins() {
+	for _, validate := range []func() error{c.ValidatePipelines, c.ValidateDeadLetters} {
+		if err := validate(); err != nil {
+			c.Problems = append(c.Problems, Problem{Message: err.Error()})
+		}
+	}
+
+	sort.SliceStable(c.Problems, func(i, j int) bool {
+		if c.Problems[i].File != c.Problems[j].File {
+			return c.Problems[i].File < c.Problems[j].File
+		}
+		return c.Problems[i].Line < c.Problems[j].Line
+	})
+}
+
+// keyLine returns the line of key in table or, if it is not found there, in
+// its sub-tables.  It returns the line of the table if the key is not found.
+func keyLine(table *ast.Table, key string) int {
+	if node, ok := table.Fields[key]; ok {
+		switch n := node.(type) {
+		case *ast.KeyValue:
+			return n.Line
+		case *ast.Table:
+			return n.Line
+		case []*ast.Table:
+			return n[0].Line
+		}
+	}
+	for _, node := range table.Fields {
+		var sub []*ast.Table
+		switch n := node.(type) {
+		case *ast.Table:
+			sub = []*ast.Table{n}
+		case []*ast.Table:
+			sub = n
+		}
+		for _, t := range sub {
+			if line := keyLine(t, key); line != t.Line {
+				return line
+			}
+		}
+	}
+	return table.Line
+}
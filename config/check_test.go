@@ -0,0 +1,58 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/stretchr/testify/require"
+)
+
+type initInput struct {
+	Fail bool `toml:"fail"`
+}
+
+func (i *initInput) SampleConfig() string                  { return "" }
+func (i *initInput) Description() string                   { return "" }
+func (i *initInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func (i *initInput) Init() error {
+	if i.Fail {
+		return errors.New("fail is set")
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("check_init", func() telegraf.Input { return &initInput{} })
+}
+
+func TestConfig_Check(t *testing.T) {
+	c := NewConfig()
+	c.Check = true
+	require.NoError(t, c.LoadConfig("./testdata/check.toml"))
+	c.CheckPlugins()
+
+	_, durationErr := time.ParseDuration("5 seconds")
+	file := "./testdata/check.toml"
+	require.Equal(t, []Problem{
+		{File: file, Line: 3, Plugin: "agent", Message: `unknown key "flush_intreval"`},
+		{File: file, Line: 6, Plugin: "inputs.http_listener_v2", Message: "interval: " + durationErr.Error()},
+		{File: file, Line: 10, Plugin: "inputs.http_listener_v2", Message: `unknown key "not_a_field"`},
+		{File: file, Line: 11, Plugin: "inputs.http_listener_v2", Message: `unknown key "other_field"`},
+		{File: file, Line: 14, Plugin: "inputs.http_listener_v2", Message: "http_listener_v2.HTTPListenerV2.ServiceAddress: cannot unmarshal TOML integer into string"},
+		{File: file, Line: 18, Plugin: "inputs.exec", Message: "Invalid data format: unknown"},
+		{File: file, Line: 20, Plugin: "inputs.check_init", Message: "initialization failed: fail is set"},
+		{File: file, Line: 25, Plugin: "outputs.http", Message: "Invalid data format: unknown"},
+	}, c.Problems)
+}
+
+func TestConfig_CheckDisabled(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/check.toml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "line 3: field corresponding to `flush_intreval' is not defined")
+	require.Empty(t, c.Problems)
+}
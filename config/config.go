@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"math"
@@ -20,15 +21,20 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/models"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
+	"github.com/influxdata/telegraf/plugins/parsers/xpath"
 	"github.com/influxdata/telegraf/plugins/processors"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/toml"
 	"github.com/influxdata/toml/ast"
+	"github.com/influxdata/wlog"
+	"github.com/robfig/cron/v3"
 )
 
 var (
@@ -60,6 +66,10 @@ type Config struct {
 	InputFilters  []string
 	OutputFilters []string
 
+	// DirectoryFormats are the formats loaded from configuration
+	// directories in addition to the *.conf files.
+	DirectoryFormats []string
+
 	Agent       *AgentConfig
 	Inputs      []*models.RunningInput
 	Outputs     []*models.RunningOutput
@@ -67,6 +77,20 @@ type Config struct {
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors    models.RunningProcessors
 	AggProcessors models.RunningProcessors
+
+	// SecretStores by id
+	SecretStores map[string]telegraf.SecretStore
+
+	// Check makes loading continue after errors, they are collected in
+	// Problems instead.
+	Check    bool
+	Problems []Problem
+
+	// checksums identifies the configuration of each plugin for reloading.
+	checksums map[interface{}]string
+
+	// file is the config file currently being loaded.
+	file string
 }
 
 func NewConfig() *Config {
@@ -87,6 +111,8 @@ func NewConfig() *Config {
 		AggProcessors: make([]*models.RunningProcessor, 0),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
+		SecretStores:  make(map[string]telegraf.SecretStore),
+		checksums:     make(map[interface{}]string),
 	}
 	return c
 }
@@ -154,6 +180,10 @@ type AgentConfig struct {
 	// is determined by the "logfile" setting.
 	LogTarget string `toml:"logtarget"`
 
+	// Log format is either "text" or "json".  JSON logs have the time, level,
+	// plugin, alias and message as separate fields.
+	LogFormat string `toml:"log_format"`
+
 	// Name of the file to be logged to when using the "file" logtarget.  If set to
 	// the empty string then logs are written to stderr.
 	Logfile string `toml:"logfile"`
@@ -321,6 +351,10 @@ var agentConfig = `
   ## is determined by the "logfile" setting.
   # logtarget = "file"
 
+  ## Log format is either "text" or "json".  JSON logs have the time, level,
+  ## plugin, alias and message as separate fields.
+  # log_format = "text"
+
   ## Name of the file to be logged to when using the "file" logtarget.  If set to
   ## the empty string then logs are written to stderr.
   # logfile = ""
@@ -380,8 +414,35 @@ var serviceInputHeader = `
 
 `
 
-// PrintSampleConfig prints the sample config
+// PrintSampleConfig prints the sample config in the given format.  YAML and
+// JSON only contain the settings that are not commented out.
 func PrintSampleConfig(
+	format string,
+	sectionFilters []string,
+	inputFilters []string,
+	outputFilters []string,
+	aggregatorFilters []string,
+	processorFilters []string,
+) error {
+	if format == FormatTOML {
+		writeSampleConfig(os.Stdout, sectionFilters, inputFilters, outputFilters,
+			aggregatorFilters, processorFilters)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	writeSampleConfig(&buf, sectionFilters, inputFilters, outputFilters,
+		aggregatorFilters, processorFilters)
+	octets, err := fromTOML(buf.Bytes(), format)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(octets)
+	return err
+}
+
+func writeSampleConfig(
+	w io.Writer,
 	sectionFilters []string,
 	inputFilters []string,
 	outputFilters []string,
@@ -389,23 +450,23 @@ func PrintSampleConfig(
 	processorFilters []string,
 ) {
 	// print headers
-	fmt.Printf(header)
+	fmt.Fprintf(w, header)
 
 	if len(sectionFilters) == 0 {
 		sectionFilters = sectionDefaults
 	}
-	printFilteredGlobalSections(sectionFilters)
+	printFilteredGlobalSections(w, sectionFilters)
 
 	// print output plugins
 	if sliceContains("outputs", sectionFilters) {
 		if len(outputFilters) != 0 {
 			if len(outputFilters) >= 3 && outputFilters[1] != "none" {
-				fmt.Printf(outputHeader)
+				fmt.Fprintf(w, outputHeader)
 			}
-			printFilteredOutputs(outputFilters, false)
+			printFilteredOutputs(w, outputFilters, false)
 		} else {
-			fmt.Printf(outputHeader)
-			printFilteredOutputs(outputDefaults, false)
+			fmt.Fprintf(w, outputHeader)
+			printFilteredOutputs(w, outputDefaults, false)
 			// Print non-default outputs, commented
 			var pnames []string
 			for pname := range outputs.Outputs {
@@ -414,7 +475,7 @@ func PrintSampleConfig(
 				}
 			}
 			sort.Strings(pnames)
-			printFilteredOutputs(pnames, true)
+			printFilteredOutputs(w, pnames, true)
 		}
 	}
 
@@ -422,17 +483,17 @@ func PrintSampleConfig(
 	if sliceContains("processors", sectionFilters) {
 		if len(processorFilters) != 0 {
 			if len(processorFilters) >= 3 && processorFilters[1] != "none" {
-				fmt.Printf(processorHeader)
+				fmt.Fprintf(w, processorHeader)
 			}
-			printFilteredProcessors(processorFilters, false)
+			printFilteredProcessors(w, processorFilters, false)
 		} else {
-			fmt.Printf(processorHeader)
+			fmt.Fprintf(w, processorHeader)
 			pnames := []string{}
 			for pname := range processors.Processors {
 				pnames = append(pnames, pname)
 			}
 			sort.Strings(pnames)
-			printFilteredProcessors(pnames, true)
+			printFilteredProcessors(w, pnames, true)
 		}
 	}
 
@@ -440,17 +501,17 @@ func PrintSampleConfig(
 	if sliceContains("aggregators", sectionFilters) {
 		if len(aggregatorFilters) != 0 {
 			if len(aggregatorFilters) >= 3 && aggregatorFilters[1] != "none" {
-				fmt.Printf(aggregatorHeader)
+				fmt.Fprintf(w, aggregatorHeader)
 			}
-			printFilteredAggregators(aggregatorFilters, false)
+			printFilteredAggregators(w, aggregatorFilters, false)
 		} else {
-			fmt.Printf(aggregatorHeader)
+			fmt.Fprintf(w, aggregatorHeader)
 			pnames := []string{}
 			for pname := range aggregators.Aggregators {
 				pnames = append(pnames, pname)
 			}
 			sort.Strings(pnames)
-			printFilteredAggregators(pnames, true)
+			printFilteredAggregators(w, pnames, true)
 		}
 	}
 
@@ -458,12 +519,12 @@ func PrintSampleConfig(
 	if sliceContains("inputs", sectionFilters) {
 		if len(inputFilters) != 0 {
 			if len(inputFilters) >= 3 && inputFilters[1] != "none" {
-				fmt.Printf(inputHeader)
+				fmt.Fprintf(w, inputHeader)
 			}
-			printFilteredInputs(inputFilters, false)
+			printFilteredInputs(w, inputFilters, false)
 		} else {
-			fmt.Printf(inputHeader)
-			printFilteredInputs(inputDefaults, false)
+			fmt.Fprintf(w, inputHeader)
+			printFilteredInputs(w, inputDefaults, false)
 			// Print non-default inputs, commented
 			var pnames []string
 			for pname := range inputs.Inputs {
@@ -472,12 +533,12 @@ func PrintSampleConfig(
 				}
 			}
 			sort.Strings(pnames)
-			printFilteredInputs(pnames, true)
+			printFilteredInputs(w, pnames, true)
 		}
 	}
 }
 
-func printFilteredProcessors(processorFilters []string, commented bool) {
+func printFilteredProcessors(w io.Writer, processorFilters []string, commented bool) {
 	// Filter processors
 	var pnames []string
 	for pname := range processors.Processors {
@@ -491,11 +552,11 @@ func printFilteredProcessors(processorFilters []string, commented bool) {
 	for _, pname := range pnames {
 		creator := processors.Processors[pname]
 		output := creator()
-		printConfig(pname, output, "processors", commented)
+		printConfig(w, pname, output, "processors", commented)
 	}
 }
 
-func printFilteredAggregators(aggregatorFilters []string, commented bool) {
+func printFilteredAggregators(w io.Writer, aggregatorFilters []string, commented bool) {
 	// Filter outputs
 	var anames []string
 	for aname := range aggregators.Aggregators {
@@ -509,11 +570,11 @@ func printFilteredAggregators(aggregatorFilters []string, commented bool) {
 	for _, aname := range anames {
 		creator := aggregators.Aggregators[aname]
 		output := creator()
-		printConfig(aname, output, "aggregators", commented)
+		printConfig(w, aname, output, "aggregators", commented)
 	}
 }
 
-func printFilteredInputs(inputFilters []string, commented bool) {
+func printFilteredInputs(w io.Writer, inputFilters []string, commented bool) {
 	// Filter inputs
 	var pnames []string
 	for pname := range inputs.Inputs {
@@ -543,7 +604,7 @@ func printFilteredInputs(inputFilters []string, commented bool) {
 			continue
 		}
 
-		printConfig(pname, input, "inputs", commented)
+		printConfig(w, pname, input, "inputs", commented)
 	}
 
 	// Print Service Inputs
@@ -552,13 +613,13 @@ func printFilteredInputs(inputFilters []string, commented bool) {
 	}
 	sort.Strings(servInputNames)
 
-	fmt.Printf(serviceInputHeader)
+	fmt.Fprintf(w, serviceInputHeader)
 	for _, name := range servInputNames {
-		printConfig(name, servInputs[name], "inputs", commented)
+		printConfig(w, name, servInputs[name], "inputs", commented)
 	}
 }
 
-func printFilteredOutputs(outputFilters []string, commented bool) {
+func printFilteredOutputs(w io.Writer, outputFilters []string, commented bool) {
 	// Filter outputs
 	var onames []string
 	for oname := range outputs.Outputs {
@@ -572,39 +633,39 @@ func printFilteredOutputs(outputFilters []string, commented bool) {
 	for _, oname := range onames {
 		creator := outputs.Outputs[oname]
 		output := creator()
-		printConfig(oname, output, "outputs", commented)
+		printConfig(w, oname, output, "outputs", commented)
 	}
 }
 
-func printFilteredGlobalSections(sectionFilters []string) {
+func printFilteredGlobalSections(w io.Writer, sectionFilters []string) {
 	if sliceContains("global_tags", sectionFilters) {
-		fmt.Printf(globalTagsConfig)
+		fmt.Fprintf(w, globalTagsConfig)
 	}
 
 	if sliceContains("agent", sectionFilters) {
-		fmt.Printf(agentConfig)
+		fmt.Fprintf(w, agentConfig)
 	}
 }
 
-func printConfig(name string, p telegraf.PluginDescriber, op string, commented bool) {
+func printConfig(w io.Writer, name string, p telegraf.PluginDescriber, op string, commented bool) {
 	comment := ""
 	if commented {
 		comment = "# "
 	}
-	fmt.Printf("\n%s# %s\n%s[[%s.%s]]", comment, p.Description(), comment,
+	fmt.Fprintf(w, "\n%s# %s\n%s[[%s.%s]]", comment, p.Description(), comment,
 		op, name)
 
 	config := p.SampleConfig()
 	if config == "" {
-		fmt.Printf("\n%s  # no configuration\n\n", comment)
+		fmt.Fprintf(w, "\n%s  # no configuration\n\n", comment)
 	} else {
 		lines := strings.Split(config, "\n")
 		for i, line := range lines {
 			if i == 0 || i == len(lines)-1 {
-				fmt.Print("\n")
+				fmt.Fprint(w, "\n")
 				continue
 			}
-			fmt.Print(strings.TrimRight(comment+line, " ") + "\n")
+			fmt.Fprint(w, strings.TrimRight(comment+line, " ")+"\n")
 		}
 	}
 }
@@ -621,7 +682,7 @@ func sliceContains(name string, list []string) bool {
 // PrintInputConfig prints the config usage of a single input.
 func PrintInputConfig(name string) error {
 	if creator, ok := inputs.Inputs[name]; ok {
-		printConfig(name, creator(), "inputs", false)
+		printConfig(os.Stdout, name, creator(), "inputs", false)
 	} else {
 		return errors.New(fmt.Sprintf("Input %s not found", name))
 	}
@@ -631,7 +692,7 @@ func PrintInputConfig(name string) error {
 // PrintOutputConfig prints the config usage of a single output.
 func PrintOutputConfig(name string) error {
 	if creator, ok := outputs.Outputs[name]; ok {
-		printConfig(name, creator(), "outputs", false)
+		printConfig(os.Stdout, name, creator(), "outputs", false)
 	} else {
 		return errors.New(fmt.Sprintf("Output %s not found", name))
 	}
@@ -639,6 +700,9 @@ func PrintOutputConfig(name string) error {
 }
 
 func (c *Config) LoadDirectory(path string) error {
+	if err := checkFormats(c.DirectoryFormats); err != nil {
+		return err
+	}
 	walkfn := func(thispath string, info os.FileInfo, _ error) error {
 		if info == nil {
 			log.Printf("W! Telegraf is not permitted to read %s", thispath)
@@ -653,8 +717,7 @@ func (c *Config) LoadDirectory(path string) error {
 
 			return nil
 		}
-		name := info.Name()
-		if len(name) < 6 || name[len(name)-5:] != ".conf" {
+		if !isConfigFile(info.Name(), c.DirectoryFormats) {
 			return nil
 		}
 		err := c.LoadConfig(thispath)
@@ -702,13 +765,32 @@ func (c *Config) LoadConfig(path string) error {
 			return err
 		}
 	}
+	c.file = path
 	data, err := loadConfig(path)
-	if err != nil {
+	if err == nil {
+		data, err = toTOML(data, configFormat(path))
+	}
+	if err == nil {
+		err = c.LoadConfigData(data)
+	}
+	if err != nil && !c.addProblem("", nil, err) {
 		return fmt.Errorf("Error loading config file %s: %w", path, err)
 	}
+	return nil
+}
 
-	if err = c.LoadConfigData(data); err != nil {
-		return fmt.Errorf("Error loading config file %s: %w", path, err)
+// LoadRemoteConfig fetches the config from r and applies it to c
+func (c *Config) LoadRemoteConfig(r *RemoteConfig) error {
+	c.file = r.URL
+	data, err := r.Fetch()
+	if err == nil {
+		data, err = toTOML(data, configFormat(r.URL))
+	}
+	if err == nil {
+		err = c.LoadConfigData(data)
+	}
+	if err != nil && !c.addProblem("", nil, err) {
+		return fmt.Errorf("Error loading config file %s: %w", r.URL, err)
 	}
 	return nil
 }
@@ -727,7 +809,7 @@ func (c *Config) LoadConfigData(data []byte) error {
 			if !ok {
 				return fmt.Errorf("invalid configuration, bad table name %q", tableName)
 			}
-			if err = toml.UnmarshalTable(subTable, c.Tags); err != nil {
+			if err = c.unmarshalTable(tableName, subTable, c.Tags); err != nil {
 				return fmt.Errorf("error parsing table name %q: %w", tableName, err)
 			}
 		}
@@ -739,9 +821,21 @@ func (c *Config) LoadConfigData(data []byte) error {
 		if !ok {
 			return fmt.Errorf("invalid configuration, error parsing agent table")
 		}
-		if err = toml.UnmarshalTable(subTable, c.Agent); err != nil {
+		if err = c.unmarshalTable("agent", subTable, c.Agent); err != nil {
 			return fmt.Errorf("error parsing agent table: %w", err)
 		}
+		switch c.Agent.LogFormat {
+		case "", logger.LogFormatText, logger.LogFormatJSON:
+		default:
+			err := &toml.LineError{
+				Line:        keyLine(subTable, "log_format"),
+				StructField: "log_format",
+				Err:         fmt.Errorf("invalid log format %q, must be text or json", c.Agent.LogFormat),
+			}
+			if !c.addProblem("agent", subTable, err) {
+				return fmt.Errorf("error parsing agent table: %w", err)
+			}
+		}
 	}
 
 	if !c.Agent.OmitHostname {
@@ -757,6 +851,30 @@ func (c *Config) LoadConfigData(data []byte) error {
 		c.Tags["host"] = c.Agent.Hostname
 	}
 
+	// Parse the secret stores before the plugins referencing them:
+	if val, ok := tbl.Fields["secretstores"]; ok {
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			return fmt.Errorf("invalid configuration, error parsing secretstores table")
+		}
+		for pluginName, pluginVal := range subTable.Fields {
+			switch pluginSubTable := pluginVal.(type) {
+			case []*ast.Table:
+				for _, t := range pluginSubTable {
+					if err = c.addSecretStore(pluginName, t); err != nil {
+						if c.addProblem("secretstores."+pluginName, t, err) {
+							continue
+						}
+						return fmt.Errorf("Error parsing %s, %s", pluginName, err)
+					}
+				}
+			default:
+				return fmt.Errorf("Unsupported config format: %s",
+					pluginName)
+			}
+		}
+	}
+
 	// Parse all the rest of the plugins:
 	for name, val := range tbl.Fields {
 		subTable, ok := val.(*ast.Table)
@@ -765,18 +883,35 @@ func (c *Config) LoadConfigData(data []byte) error {
 		}
 
 		switch name {
-		case "agent", "global_tags", "tags":
+		case "agent", "global_tags", "tags", "secretstores":
+			continue
+		}
+
+		if err = c.resolveSecrets(subTable); err != nil {
+			if c.addProblem(name, subTable, err) {
+				continue
+			}
+			return fmt.Errorf("Error resolving secrets in %s, %s", name, err)
+		}
+
+		switch name {
 		case "outputs":
 			for pluginName, pluginVal := range subTable.Fields {
 				switch pluginSubTable := pluginVal.(type) {
 				// legacy [outputs.influxdb] support
 				case *ast.Table:
 					if err = c.addOutput(pluginName, pluginSubTable); err != nil {
+						if c.addProblem("outputs."+pluginName, pluginSubTable, err) {
+							continue
+						}
 						return fmt.Errorf("Error parsing %s, %s", pluginName, err)
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addOutput(pluginName, t); err != nil {
+							if c.addProblem("outputs."+pluginName, t, err) {
+								continue
+							}
 							return fmt.Errorf("Error parsing %s array, %s", pluginName, err)
 						}
 					}
@@ -791,11 +926,17 @@ func (c *Config) LoadConfigData(data []byte) error {
 				// legacy [inputs.cpu] support
 				case *ast.Table:
 					if err = c.addInput(pluginName, pluginSubTable); err != nil {
+						if c.addProblem("inputs."+pluginName, pluginSubTable, err) {
+							continue
+						}
 						return fmt.Errorf("Error parsing %s, %s", pluginName, err)
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addInput(pluginName, t); err != nil {
+							if c.addProblem("inputs."+pluginName, t, err) {
+								continue
+							}
 							return fmt.Errorf("Error parsing %s, %s", pluginName, err)
 						}
 					}
@@ -810,6 +951,9 @@ func (c *Config) LoadConfigData(data []byte) error {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addProcessor(pluginName, t); err != nil {
+							if c.addProblem("processors."+pluginName, t, err) {
+								continue
+							}
 							return fmt.Errorf("Error parsing %s, %s", pluginName, err)
 						}
 					}
@@ -824,6 +968,9 @@ func (c *Config) LoadConfigData(data []byte) error {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addAggregator(pluginName, t); err != nil {
+							if c.addProblem("aggregators."+pluginName, t, err) {
+								continue
+							}
 							return fmt.Errorf("Error parsing %s, %s", pluginName, err)
 						}
 					}
@@ -836,6 +983,9 @@ func (c *Config) LoadConfigData(data []byte) error {
 		// identifiers are present
 		default:
 			if err = c.addInput(name, subTable); err != nil {
+				if c.addProblem("inputs."+name, subTable, err) {
+					continue
+				}
 				return fmt.Errorf("Error parsing %s, %s", name, err)
 			}
 		}
@@ -848,6 +998,66 @@ func (c *Config) LoadConfigData(data []byte) error {
 	return nil
 }
 
+// ValidatePipelines checks that the pipeline of every output is defined by at
+// least one processor or aggregator.  It must be called once all configuration
+// files are loaded.
+func (c *Config) ValidatePipelines() error {
+	pipelines := make(map[string]bool)
+	for _, processor := range c.Processors {
+		pipelines[processor.Config.Pipeline] = true
+	}
+	for _, aggregator := range c.Aggregators {
+		pipelines[aggregator.Config.Pipeline] = true
+	}
+
+	for _, output := range c.Outputs {
+		if output.Config.Pipeline != "" && !pipelines[output.Config.Pipeline] {
+			return fmt.Errorf("output %s uses undefined pipeline %q, it needs at least one processor or aggregator",
+				output.LogName(), output.Config.Pipeline)
+		}
+	}
+	return nil
+}
+
+// ValidateDeadLetters checks that the dead-letter output of every output is
+// defined.  It must be called once all configuration files are loaded.
+func (c *Config) ValidateDeadLetters() error {
+	for _, output := range c.Outputs {
+		alias := output.Config.DeadLetterOutput
+		if alias == "" {
+			continue
+		}
+
+		target := c.deadLetterOutput(alias)
+		switch {
+		case target == nil:
+			return fmt.Errorf("output %s uses undefined dead_letter_output %q",
+				output.LogName(), alias)
+		case target == output:
+			return fmt.Errorf("output %s cannot be its own dead_letter_output",
+				output.LogName())
+		case target.Config.DeadLetterOutput != "":
+			return fmt.Errorf("dead_letter_output %s cannot have a dead_letter_output itself",
+				target.LogName())
+		case target.Config.Pipeline != "":
+			return fmt.Errorf("dead_letter_output %s cannot use a pipeline",
+				target.LogName())
+		}
+	}
+	return nil
+}
+
+// deadLetterOutput returns the output with the given alias, or nil if there
+// is none.
+func (c *Config) deadLetterOutput(alias string) *models.RunningOutput {
+	for _, output := range c.Outputs {
+		if output.Config.Alias == alias {
+			return output
+		}
+	}
+	return nil
+}
+
 // trimBOM trims the Byte-Order-Marks from the beginning of the file.
 // this is for Windows compatibility only.
 // see https://github.com/influxdata/telegraf/issues/1378
@@ -877,16 +1087,11 @@ func loadConfig(config string) ([]byte, error) {
 }
 
 func fetchConfig(u *url.URL) ([]byte, error) {
-	req, err := http.NewRequest("GET", u.String(), nil)
+	req, err := newConfigRequest(u.String())
 	if err != nil {
 		return nil, err
 	}
 
-	if v, exists := os.LookupEnv("INFLUX_TOKEN"); exists {
-		req.Header.Add("Authorization", "Token "+v)
-	}
-	req.Header.Add("Accept", "application/toml")
-	req.Header.Set("User-Agent", internal.ProductToken())
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -900,6 +1105,21 @@ func fetchConfig(u *url.URL) ([]byte, error) {
 	return ioutil.ReadAll(resp.Body)
 }
 
+// newConfigRequest returns the request to fetch a remote config.
+func newConfigRequest(u string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, exists := os.LookupEnv("INFLUX_TOKEN"); exists {
+		req.Header.Add("Authorization", "Token "+v)
+	}
+	req.Header.Add("Accept", "application/toml")
+	req.Header.Set("User-Agent", internal.ProductToken())
+	return req, nil
+}
+
 // parseConfig loads a TOML configuration from a provided path and
 // returns the AST produced from the TOML parser. When loading the file, it
 // will find environment variables and replace them.
@@ -938,16 +1158,21 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	}
 	aggregator := creator()
 
+	checksum := tableChecksum(name, table)
+
 	conf, err := buildAggregator(name, table)
 	if err != nil {
 		return err
 	}
 
-	if err := toml.UnmarshalTable(table, aggregator); err != nil {
+	if err := c.unmarshalTable("aggregators."+name, table, aggregator); err != nil {
 		return err
 	}
+	c.checkInit("aggregators."+name, table, aggregator)
 
-	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	ra := models.NewRunningAggregator(aggregator, conf)
+	c.checksums[ra] = checksum
+	c.Aggregators = append(c.Aggregators, ra)
 	return nil
 }
 
@@ -957,6 +1182,8 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested processor: %s", name)
 	}
 
+	checksum := tableChecksum(name, table)
+
 	processorConfig, err := buildProcessor(name, table)
 	if err != nil {
 		return err
@@ -966,6 +1193,8 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	c.checkInit("processors."+name, table, rf.Processor)
+	c.checksums[rf] = checksum
 	c.Processors = append(c.Processors, rf)
 
 	// save a copy for the aggregator
@@ -973,6 +1202,7 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	c.checksums[rf] = checksum
 	c.AggProcessors = append(c.AggProcessors, rf)
 
 	return nil
@@ -987,11 +1217,11 @@ func (c *Config) newRunningProcessor(
 	processor := creator()
 
 	if p, ok := processor.(unwrappable); ok {
-		if err := toml.UnmarshalTable(table, p.Unwrap()); err != nil {
+		if err := c.unmarshalTable("processors."+name, table, p.Unwrap()); err != nil {
 			return nil, err
 		}
 	} else {
-		if err := toml.UnmarshalTable(table, processor); err != nil {
+		if err := c.unmarshalTable("processors."+name, table, processor); err != nil {
 			return nil, err
 		}
 	}
@@ -1010,11 +1240,15 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	}
 	output := creator()
 
+	checksum := tableChecksum(name, table)
+
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
+	var serializer serializers.Serializer
 	switch t := output.(type) {
 	case serializers.SerializerOutput:
-		serializer, err := buildSerializer(name, table)
+		var err error
+		serializer, err = buildSerializer(name, table)
 		if err != nil {
 			return err
 		}
@@ -1026,12 +1260,25 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return err
 	}
 
-	if err := toml.UnmarshalTable(table, output); err != nil {
+	if outputConfig.BufferStrategy == "disk" {
+		for _, ro := range c.Outputs {
+			if ro.Config.BufferStrategy == "disk" &&
+				filepath.Clean(ro.Config.BufferDirectory) == filepath.Clean(outputConfig.BufferDirectory) {
+				return fmt.Errorf("buffer_directory %q is used by more than one output",
+					outputConfig.BufferDirectory)
+			}
+		}
+	}
+
+	if err := c.unmarshalTable("outputs."+name, table, output); err != nil {
 		return err
 	}
+	c.checkInit("outputs."+name, table, output)
 
 	ro := models.NewRunningOutput(name, output, outputConfig,
 		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	ro.Serializer = serializer
+	c.checksums[ro] = checksum
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -1051,6 +1298,8 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	}
 	input := creator()
 
+	checksum := tableChecksum(name, table)
+
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
 	if t, ok := input.(parsers.ParserInput); ok {
@@ -1062,10 +1311,14 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	}
 
 	if t, ok := input.(parsers.ParserFuncInput); ok {
+		line := keyLine(table, "data_format")
 		config, err := getParserConfig(name, table)
 		if err != nil {
 			return err
 		}
+		if _, err := parsers.NewParser(config); err != nil {
+			return &toml.LineError{Line: line, Err: err}
+		}
 		t.SetParserFunc(func() (parsers.Parser, error) {
 			return parsers.NewParser(config)
 		})
@@ -1076,12 +1329,14 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return err
 	}
 
-	if err := toml.UnmarshalTable(table, input); err != nil {
+	if err := c.unmarshalTable("inputs."+name, table, input); err != nil {
 		return err
 	}
+	c.checkInit("inputs."+name, table, input)
 
 	rp := models.NewRunningInput(input, pluginConfig)
 	rp.SetDefaultTags(c.Tags)
+	c.checksums[rp] = checksum
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
@@ -1145,6 +1400,10 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	if err := getConfigLogLevel(tbl, &conf.LogLevel); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["alias"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1153,6 +1412,14 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	if node, ok := tbl.Fields["pipeline"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Pipeline = str.Value
+			}
+		}
+	}
+
 	conf.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
 		if subtbl, ok := node.(*ast.Table); ok {
@@ -1163,6 +1430,7 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 	}
 
 	delete(tbl.Fields, "drop_original")
+	delete(tbl.Fields, "pipeline")
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
@@ -1194,6 +1462,10 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 		}
 	}
 
+	if err := getConfigLogLevel(tbl, &conf.LogLevel); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["alias"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1202,8 +1474,17 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 		}
 	}
 
+	if node, ok := tbl.Fields["pipeline"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Pipeline = str.Value
+			}
+		}
+	}
+
 	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "order")
+	delete(tbl.Fields, "pipeline")
 	var err error
 	conf.Filter, err = buildFilter(tbl)
 	if err != nil {
@@ -1349,6 +1630,64 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 	return f, nil
 }
 
+// scheduleParser accepts cron expressions with an optional seconds field and
+// descriptors such as "@daily".
+var scheduleParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour |
+	cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// buildSchedule parses the schedule and schedule_timezone of an input, it
+// returns nil if the input has no schedule.
+func buildSchedule(tbl *ast.Table) (cron.Schedule, error) {
+	var spec, timezone string
+	if node, ok := tbl.Fields["schedule"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				spec = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["schedule_timezone"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				timezone = str.Value
+			}
+		}
+	}
+
+	delete(tbl.Fields, "schedule")
+	delete(tbl.Fields, "schedule_timezone")
+
+	if spec == "" {
+		if timezone != "" {
+			return nil, fmt.Errorf("schedule_timezone requires a schedule")
+		}
+		return nil, nil
+	}
+
+	schedule, err := scheduleParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %v", spec, err)
+	}
+
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule_timezone %q: %v", timezone, err)
+		}
+		s, ok := schedule.(*cron.SpecSchedule)
+		if !ok {
+			return nil, fmt.Errorf("schedule_timezone can not be used with schedule %q", spec)
+		}
+		s.Location = loc
+	}
+
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule %q never runs", spec)
+	}
+	return schedule, nil
+}
+
 // buildInput parses input specific items from the ast.Table,
 // builds the filter and returns a
 // models.InputConfig to be inserted into models.RunningInput
@@ -1367,6 +1706,16 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		return nil, err
 	}
 
+	if err := getConfigDuration(tbl, "gather_timeout", &cp.GatherTimeout); err != nil {
+		return nil, err
+	}
+
+	var err error
+	cp.Schedule, err = buildSchedule(tbl)
+	if err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1391,6 +1740,10 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if err := getConfigLogLevel(tbl, &cp.LogLevel); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["alias"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1413,7 +1766,6 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "tags")
-	var err error
 	cp.Filter, err = buildFilter(tbl)
 	if err != nil {
 		return cp, err
@@ -1425,11 +1777,16 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 // a parsers.Parser object, and creates it, which can then be added onto
 // an Input object.
 func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
+	line := keyLine(tbl, "data_format")
 	config, err := getParserConfig(name, tbl)
 	if err != nil {
 		return nil, err
 	}
-	return parsers.NewParser(config)
+	parser, err := parsers.NewParser(config)
+	if err != nil {
+		return nil, &toml.LineError{Line: line, Err: err}
+	}
+	return parser, nil
 }
 
 func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
@@ -1840,6 +2197,126 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 		}
 	}
 
+	for key, target := range map[string]*string{
+		"protobuf_file":             &c.ProtobufFile,
+		"protobuf_descriptor_set":   &c.ProtobufDescriptorSet,
+		"protobuf_message_type":     &c.ProtobufMessageType,
+		"protobuf_metric_selection": &c.ProtobufMetricSelection,
+		"protobuf_timestamp_path":   &c.ProtobufTimestampPath,
+		"protobuf_timestamp_format": &c.ProtobufTimestampFormat,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					*target = str.Value
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_import_paths"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.ProtobufImportPaths = append(c.ProtobufImportPaths, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	for key, target := range map[string]*string{
+		"avro_schema_registry":   &c.AvroSchemaRegistry,
+		"avro_schema":            &c.AvroSchema,
+		"avro_measurement":       &c.AvroMeasurement,
+		"avro_measurement_field": &c.AvroMeasurementField,
+		"avro_timestamp":         &c.AvroTimestamp,
+		"avro_timestamp_format":  &c.AvroTimestampFormat,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					*target = str.Value
+				}
+			}
+		}
+	}
+
+	for key, target := range map[string]*[]string{
+		"avro_tags":   &c.AvroTags,
+		"avro_fields": &c.AvroFields,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if ary, ok := kv.Value.(*ast.Array); ok {
+					for _, elem := range ary.Value {
+						if str, ok := elem.(*ast.String); ok {
+							*target = append(*target, str.Value)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	c.ProtobufTags = make(map[string]string)
+	c.ProtobufFields = make(map[string]string)
+	for key, target := range map[string]map[string]string{
+		"protobuf_tags":   c.ProtobufTags,
+		"protobuf_fields": c.ProtobufFields,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if subtbl, ok := node.(*ast.Table); ok {
+				for name, val := range subtbl.Fields {
+					if kv, ok := val.(*ast.KeyValue); ok {
+						if str, ok := kv.Value.(*ast.String); ok {
+							target[name] = str.Value
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_v2"]; ok {
+		if subtbl, ok := node.(*ast.Table); ok {
+			if objects, ok := subtbl.Fields["object"].([]*ast.Table); ok {
+				for _, objtbl := range objects {
+					var object json_v2.Object
+					if err := toml.UnmarshalTable(objtbl, &object); err != nil {
+						return nil, err
+					}
+					c.JSONV2Objects = append(c.JSONV2Objects, object)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["xpath"]; ok {
+		if subtbls, ok := node.([]*ast.Table); ok {
+			for _, subtbl := range subtbls {
+				var xpathConfig xpath.Config
+				if err := toml.UnmarshalTable(subtbl, &xpathConfig); err != nil {
+					return nil, err
+				}
+				c.XPathConfig = append(c.XPathConfig, xpathConfig)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["prometheus_metric_version"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				c.PrometheusMetricVersion = int(v)
+			}
+		}
+	}
+
 	c.MetricName = name
 
 	delete(tbl.Fields, "data_format")
@@ -1853,6 +2330,7 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	delete(tbl.Fields, "json_time_key")
 	delete(tbl.Fields, "json_timezone")
 	delete(tbl.Fields, "json_strict")
+	delete(tbl.Fields, "json_v2")
 	delete(tbl.Fields, "data_type")
 	delete(tbl.Fields, "collectd_auth_file")
 	delete(tbl.Fields, "collectd_security_level")
@@ -1884,6 +2362,25 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	delete(tbl.Fields, "csv_timezone")
 	delete(tbl.Fields, "csv_trim_space")
 	delete(tbl.Fields, "form_urlencoded_tag_keys")
+	delete(tbl.Fields, "protobuf_file")
+	delete(tbl.Fields, "protobuf_descriptor_set")
+	delete(tbl.Fields, "protobuf_import_paths")
+	delete(tbl.Fields, "protobuf_message_type")
+	delete(tbl.Fields, "protobuf_metric_selection")
+	delete(tbl.Fields, "protobuf_tags")
+	delete(tbl.Fields, "protobuf_fields")
+	delete(tbl.Fields, "protobuf_timestamp_path")
+	delete(tbl.Fields, "protobuf_timestamp_format")
+	delete(tbl.Fields, "avro_schema_registry")
+	delete(tbl.Fields, "avro_schema")
+	delete(tbl.Fields, "avro_measurement")
+	delete(tbl.Fields, "avro_measurement_field")
+	delete(tbl.Fields, "avro_tags")
+	delete(tbl.Fields, "avro_fields")
+	delete(tbl.Fields, "avro_timestamp")
+	delete(tbl.Fields, "avro_timestamp_format")
+	delete(tbl.Fields, "prometheus_metric_version")
+	delete(tbl.Fields, "xpath")
 
 	return c, nil
 }
@@ -1893,6 +2390,7 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 // an Output object.
 func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error) {
 	c := &serializers.Config{TimestampUnits: time.Duration(1 * time.Second)}
+	line := keyLine(tbl, "data_format")
 
 	if node, ok := tbl.Fields["data_format"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
@@ -2098,7 +2596,48 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	for key, target := range map[string]*string{
+		"csv_delimiter":        &c.CSVDelimiter,
+		"csv_timestamp_format": &c.CSVTimestampFormat,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					*target = str.Value
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_header"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				c.CSVHeader, err = b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_column_order"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.CSVColumnOrder = append(c.CSVColumnOrder, str.Value)
+					}
+				}
+			}
+		}
+	}
+
 	delete(tbl.Fields, "carbon2_format")
+	delete(tbl.Fields, "csv_delimiter")
+	delete(tbl.Fields, "csv_header")
+	delete(tbl.Fields, "csv_timestamp_format")
+	delete(tbl.Fields, "csv_column_order")
 	delete(tbl.Fields, "influx_max_line_bytes")
 	delete(tbl.Fields, "influx_sort_fields")
 	delete(tbl.Fields, "influx_uint_support")
@@ -2116,7 +2655,11 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 	delete(tbl.Fields, "prometheus_export_timestamp")
 	delete(tbl.Fields, "prometheus_sort_metrics")
 	delete(tbl.Fields, "prometheus_string_as_label")
-	return serializers.NewSerializer(c)
+	serializer, err := serializers.NewSerializer(c)
+	if err != nil {
+		return nil, &toml.LineError{Line: line, Err: err}
+	}
+	return serializer, nil
 }
 
 // buildOutput parses output specific items from the ast.Table,
@@ -2150,6 +2693,14 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		return nil, err
 	}
 
+	if err := getConfigDuration(tbl, "backoff_initial_interval", &oc.BackoffInitialInterval); err != nil {
+		return nil, err
+	}
+
+	if err := getConfigDuration(tbl, "backoff_max_interval", &oc.BackoffMaxInterval); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["metric_buffer_limit"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if integer, ok := kv.Value.(*ast.Integer); ok {
@@ -2174,6 +2725,10 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		}
 	}
 
+	if err := getConfigLogLevel(tbl, &oc.LogLevel); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["alias"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -2206,12 +2761,90 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["buffer_strategy"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferStrategy = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_directory"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferDirectory = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["pipeline"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.Pipeline = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["dead_letter_file"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.DeadLetterFile = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["dead_letter_output"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.DeadLetterOutput = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["startup_error_behavior"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.StartupErrorBehavior = str.Value
+			}
+		}
+	}
+
+	switch oc.StartupErrorBehavior {
+	case "", "error", "retry":
+	default:
+		return nil, fmt.Errorf("invalid startup_error_behavior %q, must be \"error\" or \"retry\"", oc.StartupErrorBehavior)
+	}
+
+	if oc.BackoffMaxInterval != 0 && oc.BackoffMaxInterval < oc.BackoffInitialInterval {
+		return nil, errors.New("backoff_max_interval must not be less than backoff_initial_interval")
+	}
+
+	if oc.DeadLetterFile != "" && oc.DeadLetterOutput != "" {
+		return nil, errors.New("only one of dead_letter_file and dead_letter_output can be set")
+	}
+
+	switch oc.BufferStrategy {
+	case "", "memory":
+	case "disk":
+		if oc.BufferDirectory == "" {
+			return nil, fmt.Errorf("buffer_directory is required when buffer_strategy is %q", oc.BufferStrategy)
+		}
+	default:
+		return nil, fmt.Errorf("invalid buffer_strategy %q, must be \"memory\" or \"disk\"", oc.BufferStrategy)
+	}
+
 	delete(tbl.Fields, "metric_buffer_limit")
 	delete(tbl.Fields, "metric_batch_size")
 	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_prefix")
+	delete(tbl.Fields, "buffer_strategy")
+	delete(tbl.Fields, "buffer_directory")
+	delete(tbl.Fields, "pipeline")
+	delete(tbl.Fields, "dead_letter_file")
+	delete(tbl.Fields, "dead_letter_output")
+	delete(tbl.Fields, "startup_error_behavior")
 
 	return oc, nil
 }
@@ -2223,13 +2856,30 @@ type unwrappable interface {
 	Unwrap() telegraf.Processor
 }
 
+// getConfigLogLevel sets the plugin log level from the log_level key.
+func getConfigLogLevel(tbl *ast.Table, target *wlog.Level) error {
+	if node, ok := tbl.Fields["log_level"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				level, err := models.ParseLogLevel(str.Value)
+				if err != nil {
+					return &toml.LineError{Line: kv.Line, StructField: "log_level", Err: err}
+				}
+				delete(tbl.Fields, "log_level")
+				*target = level
+			}
+		}
+	}
+	return nil
+}
+
 func getConfigDuration(tbl *ast.Table, key string, target *time.Duration) error {
 	if node, ok := tbl.Fields[key]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
 				d, err := time.ParseDuration(str.Value)
 				if err != nil {
-					return err
+					return &toml.LineError{Line: kv.Line, StructField: key, Err: err}
 				}
 				delete(tbl.Fields, key)
 				*target = d
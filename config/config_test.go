@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/models"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
@@ -14,6 +15,10 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
 	httpOut "github.com/influxdata/telegraf/plugins/outputs/http"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
+	"github.com/influxdata/telegraf/plugins/parsers/xpath"
+	"github.com/influxdata/toml"
+	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -257,3 +262,378 @@ func TestConfig_BadOrdering(t *testing.T) {
 	require.Error(t, err, "bad ordering")
 	assert.Equal(t, "Error loading config file ./testdata/non_slice_slice.toml: Error parsing http array, line 4: cannot unmarshal TOML array into string (need slice)", err.Error())
 }
+
+func TestConfig_Schedule(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  schedule = "0 2 1 * *"
+  schedule_timezone = "Europe/Berlin"
+  gather_timeout = "30s"
+`))
+	require.NoError(t, err)
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, 30*time.Second, c.Inputs[0].Config.GatherTimeout)
+
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	schedule := c.Inputs[0].Config.Schedule
+	require.NotNil(t, schedule)
+	require.Equal(t,
+		time.Date(2020, 2, 1, 2, 0, 0, 0, loc),
+		schedule.Next(time.Date(2020, 1, 1, 2, 0, 0, 0, loc)).In(loc))
+
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "invalid expression",
+			config: `schedule = "0 2 * *"`,
+			err:    `invalid schedule "0 2 * *"`,
+		},
+		{
+			name:   "invalid timezone",
+			config: "schedule = \"@daily\"\nschedule_timezone = \"Mars/Olympus\"",
+			err:    `invalid schedule_timezone "Mars/Olympus"`,
+		},
+		{
+			name:   "timezone with interval",
+			config: "schedule = \"@every 1h\"\nschedule_timezone = \"UTC\"",
+			err:    `schedule_timezone can not be used with schedule "@every 1h"`,
+		},
+		{
+			name:   "timezone without schedule",
+			config: `schedule_timezone = "UTC"`,
+			err:    "schedule_timezone requires a schedule",
+		},
+		{
+			name:   "never runs",
+			config: `schedule = "0 0 30 2 *"`,
+			err:    `schedule "0 0 30 2 *" never runs`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			err := c.LoadConfigData([]byte("[[inputs.memcached]]\n" + tt.config))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestConfig_Pipelines(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[processors.override]]
+  pipeline = "vendor"
+  name_override = "renamed"
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+
+[[outputs.http]]
+  url = "http://localhost:8080/b"
+  pipeline = "vendor"
+`))
+	require.NoError(t, err)
+	require.NoError(t, c.ValidatePipelines())
+	require.Equal(t, "vendor", c.Processors[0].Config.Pipeline)
+	require.Equal(t, "vendor", c.AggProcessors[0].Config.Pipeline)
+	require.Equal(t, "", c.Outputs[0].Config.Pipeline)
+	require.Equal(t, "vendor", c.Outputs[1].Config.Pipeline)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+  pipeline = "vendor"
+`))
+	require.NoError(t, err)
+	require.EqualError(t, c.ValidatePipelines(),
+		`output outputs.http uses undefined pipeline "vendor", it needs at least one processor or aggregator`)
+}
+
+func TestConfig_OutputBackoff(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+  backoff_initial_interval = "10s"
+  backoff_max_interval = "10m"
+  startup_error_behavior = "retry"
+`))
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Second, c.Outputs[0].Config.BackoffInitialInterval)
+	require.Equal(t, 10*time.Minute, c.Outputs[0].Config.BackoffMaxInterval)
+	require.Equal(t, "retry", c.Outputs[0].Config.StartupErrorBehavior)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+  backoff_initial_interval = "10m"
+  backoff_max_interval = "10s"
+`))
+	require.EqualError(t, err,
+		"Error parsing http array, backoff_max_interval must not be less than backoff_initial_interval")
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+  startup_error_behavior = "ignore"
+`))
+	require.EqualError(t, err,
+		`Error parsing http array, invalid startup_error_behavior "ignore", must be "error" or "retry"`)
+}
+
+func TestConfig_DeadLetters(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+  dead_letter_output = "rejected"
+
+[[outputs.http]]
+  url = "http://localhost:8080/b"
+  dead_letter_file = "/var/lib/telegraf/rejected.influx"
+
+[[outputs.http]]
+  alias = "rejected"
+  url = "http://localhost:8080/c"
+`))
+	require.NoError(t, err)
+	require.NoError(t, c.ValidateDeadLetters())
+	require.Equal(t, "rejected", c.Outputs[0].Config.DeadLetterOutput)
+	require.Equal(t, "/var/lib/telegraf/rejected.influx", c.Outputs[1].Config.DeadLetterFile)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+  dead_letter_output = "rejected"
+  dead_letter_file = "/var/lib/telegraf/rejected.influx"
+`))
+	require.EqualError(t, err,
+		"Error parsing http array, only one of dead_letter_file and dead_letter_output can be set")
+
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "undefined",
+			config: `
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+  dead_letter_output = "rejected"
+`,
+			err: `output outputs.http uses undefined dead_letter_output "rejected"`,
+		},
+		{
+			name: "self",
+			config: `
+[[outputs.http]]
+  alias = "rejected"
+  url = "http://localhost:8080/a"
+  dead_letter_output = "rejected"
+`,
+			err: "output outputs.http::rejected cannot be its own dead_letter_output",
+		},
+		{
+			name: "chained",
+			config: `
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+  dead_letter_output = "rejected"
+
+[[outputs.http]]
+  alias = "rejected"
+  url = "http://localhost:8080/b"
+  dead_letter_output = "other"
+
+[[outputs.http]]
+  alias = "other"
+  url = "http://localhost:8080/c"
+`,
+			err: "dead_letter_output outputs.http::rejected cannot have a dead_letter_output itself",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			require.NoError(t, c.LoadConfigData([]byte(tt.config)))
+			require.EqualError(t, c.ValidateDeadLetters(), tt.err)
+		})
+	}
+}
+
+func TestConfig_LogLevel(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[agent]
+  log_format = "json"
+
+[[inputs.memcached]]
+  log_level = "DEBUG"
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+  log_level = "error"
+`))
+	require.NoError(t, err)
+	require.Equal(t, "json", c.Agent.LogFormat)
+	require.Equal(t, wlog.DEBUG, c.Inputs[0].Config.LogLevel)
+	require.Equal(t, wlog.DEBUG, c.Inputs[0].Log().(*models.Logger).Level)
+	require.Equal(t, wlog.ERROR, c.Outputs[0].Config.LogLevel)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  log_level = "trace"
+`))
+	require.EqualError(t, err,
+		`Error parsing memcached, line 3: (log_level) invalid log level "trace", must be one of debug, info, warn or error`)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[agent]
+  log_format = "logfmt"
+`))
+	require.EqualError(t, err,
+		`error parsing agent table: line 3: (log_format) invalid log format "logfmt", must be text or json`)
+}
+
+func TestConfig_ParserJSONV2(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+data_format = "json_v2"
+
+[[json_v2.object]]
+  path = "devices"
+  tags = ["id"]
+  explode = ["sensors"]
+  [json_v2.object.renames]
+    "sensors.name" = "sensor"
+
+[[json_v2.object]]
+  measurement_name = "summary"
+  timestamp_path = "updated"
+  timestamp_format = "unix"
+`))
+	require.NoError(t, err)
+
+	c, err := getParserConfig("http", tbl)
+	require.NoError(t, err)
+	require.Equal(t, []json_v2.Object{
+		{
+			Path:    "devices",
+			Tags:    []string{"id"},
+			Explode: []string{"sensors"},
+			Renames: map[string]string{"sensors.name": "sensor"},
+		},
+		{
+			MeasurementName: "summary",
+			TimestampPath:   "updated",
+			TimestampFormat: "unix",
+		},
+	}, c.JSONV2Objects)
+	require.Empty(t, tbl.Fields)
+
+	_, err = parsers.NewParser(c)
+	require.NoError(t, err)
+}
+
+func TestConfig_ParserXPath(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+data_format = "xml"
+
+[[xpath]]
+  metric_selection = "/Bus/Sensor"
+  metric_name = "string('sensor')"
+  [xpath.tags]
+    name = "@name"
+  [xpath.fields_int]
+    consumers = "Variable/@consumers"
+
+[[xpath]]
+  timestamp = "/Bus/Timestamp"
+  timestamp_format = "unix"
+  [xpath.fields]
+    sensors = "count(/Bus/Sensor)"
+`))
+	require.NoError(t, err)
+
+	c, err := getParserConfig("file", tbl)
+	require.NoError(t, err)
+	require.Equal(t, []xpath.Config{
+		{
+			Selection:  "/Bus/Sensor",
+			MetricName: "string('sensor')",
+			Tags:       map[string]string{"name": "@name"},
+			FieldsInt:  map[string]string{"consumers": "Variable/@consumers"},
+		},
+		{
+			Timestamp:       "/Bus/Timestamp",
+			TimestampFormat: "unix",
+			Fields:          map[string]string{"sensors": "count(/Bus/Sensor)"},
+		},
+	}, c.XPathConfig)
+	require.Empty(t, tbl.Fields)
+
+	_, err = parsers.NewParser(c)
+	require.NoError(t, err)
+}
+
+func TestConfig_ParserAvro(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+data_format = "avro"
+avro_schema_registry = "http://localhost:8081"
+avro_measurement = "sensor"
+avro_tags = ["name", "location_site"]
+avro_fields = ["value"]
+avro_timestamp = "time"
+avro_timestamp_format = "unix_ms"
+`))
+	require.NoError(t, err)
+
+	c, err := getParserConfig("kafka_consumer", tbl)
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:8081", c.AvroSchemaRegistry)
+	require.Equal(t, "sensor", c.AvroMeasurement)
+	require.Equal(t, []string{"name", "location_site"}, c.AvroTags)
+	require.Equal(t, []string{"value"}, c.AvroFields)
+	require.Equal(t, "time", c.AvroTimestamp)
+	require.Equal(t, "unix_ms", c.AvroTimestampFormat)
+	require.Empty(t, tbl.Fields)
+
+	_, err = parsers.NewParser(c)
+	require.NoError(t, err)
+}
+
+func TestConfig_SerializerCSV(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+data_format = "csv"
+csv_delimiter = ";"
+csv_header = true
+csv_timestamp_format = "rfc3339"
+csv_column_order = ["name", "timestamp", "fields"]
+`))
+	require.NoError(t, err)
+
+	s, err := buildSerializer("file", tbl)
+	require.NoError(t, err)
+	require.Empty(t, tbl.Fields)
+
+	m, err := metric.New("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 1.0},
+		time.Date(2020, 11, 2, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "name;timestamp;value\ncpu;2020-11-02T12:00:00Z;1\n", string(buf))
+}
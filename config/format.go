@@ -0,0 +1,337 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// The supported configuration formats.
+const (
+	FormatTOML = "toml"
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// bareKeyRe matches the keys that need no quoting in TOML.
+var bareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// configFormat returns the format of the config file by its extension,
+// files without a known extension are TOML.
+func configFormat(path string) string {
+	if u, err := url.Parse(path); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		path = u.Path
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	default:
+		return FormatTOML
+	}
+}
+
+// isConfigFile returns if the directory entry is loaded by LoadDirectory.
+// Files ending with .conf are always loaded, the other extensions only if
+// their format is in formats.
+func isConfigFile(name string, formats []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == ".conf" {
+		return true
+	}
+	switch ext {
+	case ".toml", ".yaml", ".yml", ".json":
+		return sliceContains(configFormat(name), formats)
+	}
+	return false
+}
+
+// checkFormats returns an error if one of the formats is unknown.
+func checkFormats(formats []string) error {
+	for _, format := range formats {
+		switch format {
+		case FormatTOML, FormatYAML, FormatJSON:
+		default:
+			return fmt.Errorf("unknown config format %q, must be 'toml', 'yaml' or 'json'", format)
+		}
+	}
+	return nil
+}
+
+// toTOML converts a YAML or JSON configuration to TOML with the same tables,
+// so that all formats are loaded the same way.  Comments are not kept.
+func toTOML(data []byte, format string) ([]byte, error) {
+	var table map[string]interface{}
+	switch format {
+	case FormatTOML:
+		return data, nil
+	case FormatYAML:
+		var v interface{}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return nil, nil
+		}
+		var ok bool
+		if table, ok = fromYAML(v).(map[string]interface{}); !ok {
+			return nil, errors.New("the configuration must be a mapping")
+		}
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&table); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format %q", format)
+	}
+
+	var buf bytes.Buffer
+	if err := writeTOMLTable(&buf, nil, table); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fromYAML converts the maps decoded by yaml to maps with string keys.
+func fromYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = fromYAML(value)
+		}
+		return m
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = fromYAML(elem)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// writeTOMLTable writes the contents of a table, its key/values first and
+// then its sub-tables with their full path.
+func writeTOMLTable(buf *bytes.Buffer, path []string, table map[string]interface{}) error {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var tables, arrays []string
+	for _, key := range keys {
+		switch v := table[key].(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			tables = append(tables, key)
+			continue
+		case []interface{}:
+			if isTableArray(v) {
+				arrays = append(arrays, key)
+				continue
+			}
+		}
+
+		buf.WriteString(tomlKey(key) + " = ")
+		if err := writeTOMLValue(buf, table[key]); err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(append(path, key), "."), err)
+		}
+		buf.WriteString("\n")
+	}
+
+	for _, key := range tables {
+		sub := append(path[:len(path):len(path)], key)
+		fmt.Fprintf(buf, "\n[%s]\n", tomlPath(sub))
+		if err := writeTOMLTable(buf, sub, table[key].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	for _, key := range arrays {
+		sub := append(path[:len(path):len(path)], key)
+		for _, elem := range table[key].([]interface{}) {
+			fmt.Fprintf(buf, "\n[[%s]]\n", tomlPath(sub))
+			if err := writeTOMLTable(buf, sub, elem.(map[string]interface{})); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeTOMLValue(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		buf.WriteString(tomlString(v))
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(v, 10))
+	case float64:
+		return writeTOMLFloat(buf, v)
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			buf.WriteString(v.String())
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		return writeTOMLFloat(buf, f)
+	case time.Time:
+		buf.WriteString(v.Format(time.RFC3339Nano))
+	case []interface{}:
+		buf.WriteString("[")
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := writeTOMLValue(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf.WriteString("{")
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(tomlKey(key) + " = ")
+			if err := writeTOMLValue(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}")
+	case nil:
+		return errors.New("null values are not supported in arrays")
+	default:
+		return fmt.Errorf("unsupported value type %T", v)
+	}
+	return nil
+}
+
+func writeTOMLFloat(buf *bytes.Buffer, f float64) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("unsupported float value %v", f)
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	buf.WriteString(s)
+	return nil
+}
+
+// isTableArray returns if the array is written as an array of tables.
+func isTableArray(v []interface{}) bool {
+	if len(v) == 0 {
+		return false
+	}
+	for _, elem := range v {
+		if _, ok := elem.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func tomlPath(path []string) string {
+	keys := make([]string, 0, len(path))
+	for _, key := range path {
+		keys = append(keys, tomlKey(key))
+	}
+	return strings.Join(keys, ".")
+}
+
+func tomlKey(key string) string {
+	if bareKeyRe.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString returns s as a TOML basic string.
+func tomlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// fromTOML converts a TOML configuration to YAML or JSON.  Comments are not
+// kept.
+func fromTOML(data []byte, format string) ([]byte, error) {
+	if format == FormatTOML {
+		return data, nil
+	}
+
+	table := make(map[string]interface{})
+	if err := toml.Unmarshal(data, &table); err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatYAML:
+		return yaml.Marshal(table)
+	case FormatJSON:
+		octets, err := json.MarshalIndent(table, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(octets, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown config format %q", format)
+	}
+}
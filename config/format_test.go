@@ -0,0 +1,123 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	httpOut "github.com/influxdata/telegraf/plugins/outputs/http"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadFormats(t *testing.T) {
+	expected := NewConfig()
+	require.NoError(t, expected.LoadConfig("./testdata/formats.toml"))
+	require.Equal(t, "quoted \"value\"\n",
+		expected.Outputs[0].Output.(*httpOut.HTTP).Headers["X-Special.Header"])
+
+	for _, file := range []string{"./testdata/formats.yaml", "./testdata/formats.json"} {
+		t.Run(file, func(t *testing.T) {
+			c := NewConfig()
+			require.NoError(t, c.LoadConfig(file))
+
+			require.Equal(t, expected.Agent, c.Agent)
+			require.Len(t, c.Inputs, 1)
+			require.Equal(t, expected.Inputs[0].Input, c.Inputs[0].Input)
+			require.Equal(t, expected.Inputs[0].Config, c.Inputs[0].Config)
+			require.Len(t, c.Outputs, 1)
+			require.Equal(t, expected.Outputs[0].Output, c.Outputs[0].Output)
+			require.Equal(t, expected.Outputs[0].Config, c.Outputs[0].Config)
+		})
+	}
+}
+
+func TestConfig_LoadDirectoryFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	exec, err := ioutil.ReadFile("./testdata/subconfig/exec.conf")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "exec.conf"), exec, 0644))
+	yaml, err := ioutil.ReadFile("./testdata/formats.yaml")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "formats.yml"), yaml, 0644))
+	// Not a Telegraf configuration, must not be loaded.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "state.json"), []byte(`[1, 2]`), 0644))
+
+	c := NewConfig()
+	require.NoError(t, c.LoadDirectory(dir))
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, "exec", c.Inputs[0].Config.Name)
+	require.Len(t, c.Outputs, 0)
+
+	c = NewConfig()
+	c.DirectoryFormats = []string{FormatYAML}
+	require.NoError(t, c.LoadDirectory(dir))
+	require.Len(t, c.Inputs, 2)
+	require.Len(t, c.Outputs, 1)
+
+	c = NewConfig()
+	c.DirectoryFormats = []string{"ini"}
+	require.Error(t, c.LoadDirectory(dir))
+}
+
+func TestToTOML(t *testing.T) {
+	data, err := toTOML([]byte(`
+float: 1.0
+int: 1
+list: [a, "b c"]
+empty: []
+nothing:
+"dotted.key": "tab\tand\u0001"
+tables:
+  - {}
+  - nested:
+      a: true
+`), FormatYAML)
+	require.NoError(t, err)
+	require.Equal(t, `"dotted.key" = "tab\tand\u0001"
+empty = []
+float = 1.0
+int = 1
+list = ["a", "b c"]
+
+[[tables]]
+
+[[tables]]
+
+[tables.nested]
+a = true
+`, string(data))
+
+	data, err = toTOML([]byte(`{"int": 10, "float": 2.5, "exp": 1e3}`), FormatJSON)
+	require.NoError(t, err)
+	require.Equal(t, "exp = 1000.0\nfloat = 2.5\nint = 10\n", string(data))
+
+	_, err = toTOML([]byte(`list: [a, null]`), FormatYAML)
+	require.EqualError(t, err, "list: null values are not supported in arrays")
+
+	_, err = toTOML([]byte(`[1, 2]`), FormatYAML)
+	require.EqualError(t, err, "the configuration must be a mapping")
+}
+
+func TestSampleConfigFormats(t *testing.T) {
+	var buf bytes.Buffer
+	writeSampleConfig(&buf, []string{"agent", "inputs"}, []string{"memcached"}, nil, nil, nil)
+
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			sample, err := fromTOML(buf.Bytes(), format)
+			require.NoError(t, err)
+			data, err := toTOML(sample, format)
+			require.NoError(t, err)
+
+			c := NewConfig()
+			require.NoError(t, c.LoadConfigData(data))
+			require.Len(t, c.Inputs, 1)
+			require.Equal(t, "memcached", c.Inputs[0].Config.Name)
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/toml/ast"
+)
+
+// Diff describes the changes between a running configuration and a newly
+// loaded one, plugin by plugin.
+type Diff struct {
+	// RestartReason is set when the changes cannot be applied to a running
+	// agent, for example when the agent settings or aggregators changed.
+	RestartReason string
+
+	AddedInputs    []*models.RunningInput
+	RemovedInputs  []*models.RunningInput
+	AddedOutputs   []*models.RunningOutput
+	RemovedOutputs []*models.RunningOutput
+
+	// ReplacedProcessors maps a running processor to the processor taking
+	// its place in the processor chain.
+	ReplacedProcessors map[*models.RunningProcessor]*models.RunningProcessor
+
+	running *Config
+	loaded  *Config
+
+	// kept maps plugins of the loaded configuration to the running plugins
+	// with an identical configuration.
+	kept map[interface{}]interface{}
+}
+
+// Empty returns true if there are no changes.
+func (d *Diff) Empty() bool {
+	return d.RestartReason == "" &&
+		len(d.AddedInputs) == 0 && len(d.RemovedInputs) == 0 &&
+		len(d.AddedOutputs) == 0 && len(d.RemovedOutputs) == 0 &&
+		len(d.ReplacedProcessors) == 0
+}
+
+// Diff compares c, the running configuration, with other and returns the
+// changes needed to turn c into other.  Plugins are compared by the content of
+// their configuration table.
+func (c *Config) Diff(other *Config) *Diff {
+	d := &Diff{
+		ReplacedProcessors: make(map[*models.RunningProcessor]*models.RunningProcessor),
+		running:            c,
+		loaded:             other,
+		kept:               make(map[interface{}]interface{}),
+	}
+
+	switch {
+	case !reflect.DeepEqual(c.Agent, other.Agent):
+		d.RestartReason = "agent settings changed"
+		return d
+	case !reflect.DeepEqual(c.Tags, other.Tags):
+		d.RestartReason = "global tags changed"
+		return d
+	case len(other.Outputs) == 0:
+		d.RestartReason = "no outputs configured"
+		return d
+	}
+
+	var oldAggs, newAggs []interface{}
+	for _, agg := range c.Aggregators {
+		oldAggs = append(oldAggs, agg)
+	}
+	for _, agg := range other.Aggregators {
+		newAggs = append(newAggs, agg)
+	}
+	if added, removed := d.match(oldAggs, newAggs); len(added) != 0 || len(removed) != 0 {
+		d.RestartReason = "aggregators changed"
+		return d
+	}
+
+	var oldInputs, newInputs []interface{}
+	for _, input := range c.Inputs {
+		oldInputs = append(oldInputs, input)
+	}
+	for _, input := range other.Inputs {
+		newInputs = append(newInputs, input)
+	}
+	added, removed := d.match(oldInputs, newInputs)
+	for _, p := range added {
+		d.AddedInputs = append(d.AddedInputs, p.(*models.RunningInput))
+	}
+	for _, p := range removed {
+		d.RemovedInputs = append(d.RemovedInputs, p.(*models.RunningInput))
+	}
+
+	var oldOutputs, newOutputs []interface{}
+	for _, output := range c.Outputs {
+		oldOutputs = append(oldOutputs, output)
+	}
+	for _, output := range other.Outputs {
+		newOutputs = append(newOutputs, output)
+	}
+	added, removed = d.match(oldOutputs, newOutputs)
+	for _, p := range added {
+		d.AddedOutputs = append(d.AddedOutputs, p.(*models.RunningOutput))
+	}
+	for _, p := range removed {
+		d.RemovedOutputs = append(d.RemovedOutputs, p.(*models.RunningOutput))
+	}
+
+	// The pipelines are started with the outputs, so only outputs of a
+	// pipeline that is already in use can be added.
+	running := make(map[string]bool)
+	for _, output := range c.Outputs {
+		running[output.Config.Pipeline] = true
+	}
+	for _, output := range d.AddedOutputs {
+		if p := output.Config.Pipeline; p != "" && !running[p] {
+			d.RestartReason = fmt.Sprintf("pipeline %q added", p)
+			return d
+		}
+	}
+
+	// Outputs are linked to their dead-letter output when they are started.
+	targets := make(map[string]bool)
+	for _, outputs := range [][]*models.RunningOutput{c.Outputs, other.Outputs} {
+		for _, output := range outputs {
+			if output.Config.DeadLetterOutput != "" {
+				targets[output.Config.DeadLetterOutput] = true
+			}
+		}
+	}
+	for _, outputs := range [][]*models.RunningOutput{d.AddedOutputs, d.RemovedOutputs} {
+		for _, output := range outputs {
+			if output.Config.DeadLetterOutput != "" || targets[output.Config.Alias] {
+				d.RestartReason = "dead-letter outputs changed"
+				return d
+			}
+		}
+	}
+
+	if err := d.matchProcessors(c.Processors, other.Processors); err != nil {
+		d.RestartReason = err.Error()
+		return d
+	}
+	if err := d.matchProcessors(c.AggProcessors, other.AggProcessors); err != nil {
+		d.RestartReason = err.Error()
+		return d
+	}
+
+	return d
+}
+
+// match pairs up the plugins with identical configuration and returns the
+// remaining plugins.
+func (d *Diff) match(running, loaded []interface{}) (added, removed []interface{}) {
+	unused := make(map[string][]interface{})
+	for _, p := range running {
+		sum := d.running.checksums[p]
+		unused[sum] = append(unused[sum], p)
+	}
+
+	for _, p := range loaded {
+		sum := d.loaded.checksums[p]
+		if candidates := unused[sum]; len(candidates) > 0 {
+			d.kept[p] = candidates[0]
+			unused[sum] = candidates[1:]
+			continue
+		}
+		added = append(added, p)
+	}
+
+	for _, p := range running {
+		sum := d.running.checksums[p]
+		for _, u := range unused[sum] {
+			if u == p {
+				removed = append(removed, p)
+				break
+			}
+		}
+	}
+	return added, removed
+}
+
+// matchProcessors compares the processor chains.  Processors can only be
+// replaced in place, so the chains must be of the same length.
+func (d *Diff) matchProcessors(running, loaded models.RunningProcessors) error {
+	if len(running) != len(loaded) {
+		return fmt.Errorf("number of processors changed")
+	}
+
+	var oldProcs, newProcs []interface{}
+	for _, p := range running {
+		oldProcs = append(oldProcs, p)
+	}
+	for _, p := range loaded {
+		newProcs = append(newProcs, p)
+	}
+	if added, _ := d.match(oldProcs, newProcs); len(added) == 0 {
+		return nil
+	}
+
+	// Order both chains the same way the agent does when starting them and
+	// replace the processors by position.
+	for _, p := range loaded {
+		delete(d.kept, p)
+	}
+	running = sortedProcessors(running)
+	loaded = sortedProcessors(loaded)
+	for i := range running {
+		if d.running.checksums[running[i]] == d.loaded.checksums[loaded[i]] {
+			d.kept[loaded[i]] = running[i]
+			continue
+		}
+		if running[i].Config.Pipeline != loaded[i].Config.Pipeline {
+			return fmt.Errorf("processor pipelines changed")
+		}
+		d.ReplacedProcessors[running[i]] = loaded[i]
+	}
+	return nil
+}
+
+// Apply updates c with the plugins of the loaded configuration, keeping the
+// running instance of every unchanged plugin.  It is called once the changes
+// have been applied to the running agent.
+func (c *Config) Apply(d *Diff) {
+	loaded := d.loaded
+
+	checksums := make(map[interface{}]string, len(loaded.checksums))
+	for p, sum := range loaded.checksums {
+		if old, ok := d.kept[p]; ok {
+			checksums[old] = sum
+			continue
+		}
+		checksums[p] = sum
+	}
+	c.checksums = checksums
+
+	c.Inputs = make([]*models.RunningInput, 0, len(loaded.Inputs))
+	for _, p := range loaded.Inputs {
+		if old, ok := d.kept[p]; ok {
+			p = old.(*models.RunningInput)
+		}
+		c.Inputs = append(c.Inputs, p)
+	}
+
+	c.Outputs = make([]*models.RunningOutput, 0, len(loaded.Outputs))
+	for _, p := range loaded.Outputs {
+		if old, ok := d.kept[p]; ok {
+			p = old.(*models.RunningOutput)
+		}
+		c.Outputs = append(c.Outputs, p)
+	}
+
+	c.Processors = make(models.RunningProcessors, 0, len(loaded.Processors))
+	for _, p := range loaded.Processors {
+		if old, ok := d.kept[p]; ok {
+			p = old.(*models.RunningProcessor)
+		}
+		c.Processors = append(c.Processors, p)
+	}
+
+	c.AggProcessors = make(models.RunningProcessors, 0, len(loaded.AggProcessors))
+	for _, p := range loaded.AggProcessors {
+		if old, ok := d.kept[p]; ok {
+			p = old.(*models.RunningProcessor)
+		}
+		c.AggProcessors = append(c.AggProcessors, p)
+	}
+}
+
+// sortedProcessors returns a copy of the processors sorted from last to
+// first, the order the processor chain is started in.
+func sortedProcessors(processors models.RunningProcessors) models.RunningProcessors {
+	sorted := make(models.RunningProcessors, len(processors))
+	copy(sorted, processors)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Config.Order > sorted[j].Config.Order
+	})
+	return sorted
+}
+
+// tableChecksum identifies the configuration table a plugin is created from.
+func tableChecksum(name string, tbl *ast.Table) string {
+	var sb strings.Builder
+	sb.WriteString(name)
+	writeTable(&sb, tbl)
+
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeTable writes a canonical representation of the table, independent of
+// key order and formatting, to sb.
+func writeTable(sb *strings.Builder, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sb.WriteString("{")
+	for _, key := range keys {
+		sb.WriteString(key)
+		sb.WriteString("=")
+		switch v := tbl.Fields[key].(type) {
+		case *ast.KeyValue:
+			writeValue(sb, v.Value)
+		case *ast.Table:
+			writeTable(sb, v)
+		case []*ast.Table:
+			sb.WriteString("[")
+			for _, t := range v {
+				writeTable(sb, t)
+			}
+			sb.WriteString("]")
+		}
+		sb.WriteString(";")
+	}
+	sb.WriteString("}")
+}
+
+func writeValue(sb *strings.Builder, value ast.Value) {
+	switch v := value.(type) {
+	case *ast.String:
+		sb.WriteString(fmt.Sprintf("%q", v.Value))
+	case *ast.Array:
+		sb.WriteString("[")
+		for _, item := range v.Value {
+			writeValue(sb, item)
+			sb.WriteString(",")
+		}
+		sb.WriteString("]")
+	default:
+		sb.WriteString(value.Source())
+	}
+}
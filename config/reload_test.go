@@ -0,0 +1,336 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/models"
+	_ "github.com/influxdata/telegraf/plugins/processors/override"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadBaseConfig = `
+[agent]
+  hostname = "localhost"
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+
+[[inputs.exec]]
+  commands = ["echo"]
+  data_format = "influx"
+
+[[processors.override]]
+  order = 1
+  name_override = "first"
+
+[[processors.override]]
+  order = 2
+  name_override = "second"
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+
+[[outputs.http]]
+  url = "http://localhost:8080/b"
+`
+
+func loadReloadConfig(t *testing.T, data string) *Config {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(data)))
+	return c
+}
+
+func TestDiff_Unchanged(t *testing.T) {
+	running := loadReloadConfig(t, reloadBaseConfig)
+	loaded := loadReloadConfig(t, reloadBaseConfig)
+
+	diff := running.Diff(loaded)
+	require.Empty(t, diff.RestartReason)
+	require.True(t, diff.Empty())
+}
+
+func TestDiff_FormattingIgnored(t *testing.T) {
+	running := loadReloadConfig(t, reloadBaseConfig)
+	loaded := loadReloadConfig(t, `
+[agent]
+  hostname = "localhost"
+
+# comments and key order do not matter
+[[inputs.exec]]
+  data_format = 'influx'
+  commands = [ "echo" ]
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+
+[[processors.override]]
+  name_override = "first"
+  order = 1
+
+[[processors.override]]
+  order = 2
+  name_override = "second"
+
+[[outputs.http]]
+  url = "http://localhost:8080/b"
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+`)
+
+	diff := running.Diff(loaded)
+	require.True(t, diff.Empty())
+}
+
+func TestDiff_InputsAndOutputs(t *testing.T) {
+	running := loadReloadConfig(t, reloadBaseConfig)
+	loaded := loadReloadConfig(t, `
+[agent]
+  hostname = "localhost"
+
+[[inputs.memcached]]
+  servers = ["localhost:11211"]
+
+[[inputs.exec]]
+  commands = ["echo"]
+  data_format = "influx"
+
+[[processors.override]]
+  order = 1
+  name_override = "first"
+
+[[processors.override]]
+  order = 2
+  name_override = "second"
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+
+[[outputs.http]]
+  url = "http://localhost:8080/c"
+`)
+
+	diff := running.Diff(loaded)
+	require.Empty(t, diff.RestartReason)
+
+	require.Len(t, diff.RemovedInputs, 1)
+	require.Equal(t, "memcached", diff.RemovedInputs[0].Config.Name)
+	require.Len(t, diff.AddedInputs, 1)
+	require.Equal(t, "memcached", diff.AddedInputs[0].Config.Name)
+
+	require.Len(t, diff.RemovedOutputs, 1)
+	require.Same(t, running.Outputs[1], diff.RemovedOutputs[0])
+	require.Len(t, diff.AddedOutputs, 1)
+	require.Same(t, loaded.Outputs[1], diff.AddedOutputs[0])
+
+	require.Empty(t, diff.ReplacedProcessors)
+
+	// The unchanged plugins keep their running instance.
+	keptOutput := running.Outputs[0]
+	// Plugins of different types are loaded in random order.
+	var keptExec *models.RunningInput
+	for _, input := range running.Inputs {
+		if input.Config.Name == "exec" {
+			keptExec = input
+		}
+	}
+	require.NotNil(t, keptExec)
+	running.Apply(diff)
+	require.Len(t, running.Outputs, 2)
+	require.Same(t, keptOutput, running.Outputs[0])
+	require.Same(t, loaded.Outputs[1], running.Outputs[1])
+	require.Contains(t, running.Inputs, keptExec)
+
+	// A second reload of the same config is a no-op.
+	require.True(t, running.Diff(loadReloadConfig(t, `
+[agent]
+  hostname = "localhost"
+
+[[inputs.memcached]]
+  servers = ["localhost:11211"]
+
+[[inputs.exec]]
+  commands = ["echo"]
+  data_format = "influx"
+
+[[processors.override]]
+  order = 1
+  name_override = "first"
+
+[[processors.override]]
+  order = 2
+  name_override = "second"
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+
+[[outputs.http]]
+  url = "http://localhost:8080/c"
+`)).Empty())
+}
+
+func TestDiff_ProcessorReplaced(t *testing.T) {
+	running := loadReloadConfig(t, reloadBaseConfig)
+	loaded := loadReloadConfig(t, `
+[agent]
+  hostname = "localhost"
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+
+[[inputs.exec]]
+  commands = ["echo"]
+  data_format = "influx"
+
+[[processors.override]]
+  order = 1
+  name_override = "first"
+
+[[processors.override]]
+  order = 2
+  name_override = "changed"
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+
+[[outputs.http]]
+  url = "http://localhost:8080/b"
+`)
+
+	diff := running.Diff(loaded)
+	require.Empty(t, diff.RestartReason)
+	require.Empty(t, diff.AddedInputs)
+	require.Empty(t, diff.AddedOutputs)
+
+	// One for the processor chain and one for the aggregator chain.
+	require.Len(t, diff.ReplacedProcessors, 2)
+	for old, replacement := range diff.ReplacedProcessors {
+		require.Equal(t, int64(2), old.Config.Order)
+		require.Equal(t, int64(2), replacement.Config.Order)
+	}
+}
+
+func TestDiff_RestartRequired(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{
+			name: "agent settings",
+			config: `
+[agent]
+  hostname = "localhost"
+  interval = "1m"
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+`,
+		},
+		{
+			name: "global tags",
+			config: `
+[global_tags]
+  dc = "us-east-1"
+
+[agent]
+  hostname = "localhost"
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+`,
+		},
+		{
+			name: "processor removed",
+			config: `
+[agent]
+  hostname = "localhost"
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+
+[[processors.override]]
+  order = 1
+  name_override = "first"
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+`,
+		},
+		{
+			name: "processor moved to pipeline",
+			config: `
+[agent]
+  hostname = "localhost"
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+
+[[processors.override]]
+  order = 1
+  name_override = "first"
+
+[[processors.override]]
+  order = 2
+  name_override = "second"
+  pipeline = "b"
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+
+[[outputs.http]]
+  url = "http://localhost:8080/b"
+  pipeline = "b"
+`,
+		},
+		{
+			name: "dead-letter output added",
+			config: `
+[agent]
+  hostname = "localhost"
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+
+[[inputs.exec]]
+  commands = ["echo"]
+  data_format = "influx"
+
+[[processors.override]]
+  order = 1
+  name_override = "first"
+
+[[processors.override]]
+  order = 2
+  name_override = "second"
+
+[[outputs.http]]
+  url = "http://localhost:8080/a"
+  dead_letter_output = "rejected"
+
+[[outputs.http]]
+  url = "http://localhost:8080/b"
+
+[[outputs.http]]
+  alias = "rejected"
+  url = "http://localhost:8080/c"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running := loadReloadConfig(t, reloadBaseConfig)
+			loaded := loadReloadConfig(t, tt.config)
+
+			diff := running.Diff(loaded)
+			require.NotEmpty(t, diff.RestartReason)
+			require.False(t, diff.Empty())
+		})
+	}
+}
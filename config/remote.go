@@ -0,0 +1,236 @@
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SignatureHeader is the response header holding the base64 encoded ed25519
+// signature of a remote configuration.
+const SignatureHeader = "Telegraf-Config-Signature"
+
+// RemoteConfig fetches a configuration from an HTTP URL.  It remembers the
+// last configuration so that polling uses conditional requests, and keeps a
+// copy on disk to start from when the server is unreachable.
+type RemoteConfig struct {
+	sync.Mutex
+
+	URL string
+	// CacheFile is where the last good configuration is stored, if set.
+	CacheFile string
+	// PublicKey verifies the signature of the configuration, if set.
+	PublicKey ed25519.PublicKey
+
+	client       *http.Client
+	etag         string
+	lastModified string
+	data         []byte
+}
+
+// NewRemoteConfig returns a RemoteConfig for the URL.  If publicKeyFile is
+// set, it must contain a PEM encoded ed25519 public key and all
+// configurations must be signed by the matching private key.
+func NewRemoteConfig(url, cacheFile, publicKeyFile string) (*RemoteConfig, error) {
+	r := &RemoteConfig{
+		URL:       url,
+		CacheFile: cacheFile,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if publicKeyFile != "" {
+		key, err := readPublicKey(publicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading config public key: %w", err)
+		}
+		r.PublicKey = key
+	}
+	return r, nil
+}
+
+// IsRemoteConfig returns if the config path is an HTTP URL.
+func IsRemoteConfig(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	octets, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(octets)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T, must be ed25519", key)
+	}
+	return edKey, nil
+}
+
+// Fetch returns the configuration.  If it is unchanged on the server the
+// last one is returned.  If the server cannot be reached before the first
+// configuration is fetched, the cached copy is returned.
+func (r *RemoteConfig) Fetch() ([]byte, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	err := r.fetch()
+	if err == nil {
+		return r.data, nil
+	}
+	if r.data != nil {
+		log.Printf("W! Error fetching config from %s, using the last one: %v", r.URL, err)
+		return r.data, nil
+	}
+	if r.CacheFile == "" {
+		return nil, err
+	}
+
+	data, cacheErr := ioutil.ReadFile(r.CacheFile)
+	if cacheErr != nil {
+		return nil, fmt.Errorf("%v, and could not read cached config: %v", err, cacheErr)
+	}
+	log.Printf("W! Error fetching config from %s, using cached config %s: %v", r.URL, r.CacheFile, err)
+	r.data = data
+	return r.data, nil
+}
+
+// Poll fetches the configuration and returns if it changed.
+func (r *RemoteConfig) Poll() (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	old := r.data
+	if err := r.fetch(); err != nil {
+		return false, err
+	}
+	return !bytes.Equal(old, r.data), nil
+}
+
+// Save stores the last fetched configuration in the cache file.  It should
+// be called once the configuration is known to be good.
+func (r *RemoteConfig) Save() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.CacheFile == "" || r.data == nil {
+		return nil
+	}
+
+	// Write to a temporary file first so that the cache is never truncated.
+	tmp, err := ioutil.TempFile(filepath.Dir(r.CacheFile), filepath.Base(r.CacheFile)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(r.data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.CacheFile)
+}
+
+func (r *RemoteConfig) fetch() error {
+	req, err := newConfigRequest(r.URL)
+	if err != nil {
+		return err
+	}
+	if r.data != nil {
+		if r.etag != "" {
+			req.Header.Set("If-None-Match", r.etag)
+		}
+		if r.lastModified != "" {
+			req.Header.Set("If-Modified-Since", r.lastModified)
+		}
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if r.data == nil {
+			return errors.New("server returned 304 Not Modified without a previous config")
+		}
+		return nil
+	case http.StatusOK:
+	default:
+		return fmt.Errorf("failed to retrieve remote config: %s", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := verifyDigest(resp.Header.Get("Digest"), data); err != nil {
+		return err
+	}
+	if r.PublicKey != nil {
+		if err := verifySignature(r.PublicKey, resp.Header.Get(SignatureHeader), data); err != nil {
+			return err
+		}
+	}
+
+	r.data = data
+	r.etag = resp.Header.Get("ETag")
+	r.lastModified = resp.Header.Get("Last-Modified")
+	return nil
+}
+
+// verifyDigest checks the SHA-256 checksum of a Digest header as defined in
+// RFC 3230.  Other algorithms are ignored.
+func verifyDigest(header string, data []byte) error {
+	for _, digest := range strings.Split(header, ",") {
+		parts := strings.SplitN(strings.TrimSpace(digest), "=", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "SHA-256") {
+			continue
+		}
+		expected, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid config digest: %w", err)
+		}
+		sum := sha256.Sum256(data)
+		if !bytes.Equal(expected, sum[:]) {
+			return errors.New("config does not match its SHA-256 digest")
+		}
+	}
+	return nil
+}
+
+func verifySignature(key ed25519.PublicKey, header string, data []byte) error {
+	if header == "" {
+		return fmt.Errorf("config is not signed, missing %s header", SignatureHeader)
+	}
+	signature, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return fmt.Errorf("invalid config signature: %w", err)
+	}
+	if !ed25519.Verify(key, data, signature) {
+		return errors.New("config signature verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// configServer serves a config with an ETag and optional headers.
+type configServer struct {
+	sync.Mutex
+	config      string
+	etag        string
+	headers     map[string]string
+	conditional int
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	if r.Header.Get("If-None-Match") != "" {
+		s.conditional++
+		if r.Header.Get("If-None-Match") == s.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	for k, v := range s.headers {
+		w.Header().Set(k, v)
+	}
+	w.Header().Set("ETag", s.etag)
+	w.Write([]byte(s.config))
+}
+
+func (s *configServer) set(config, etag string) {
+	s.Lock()
+	defer s.Unlock()
+	s.config, s.etag = config, etag
+}
+
+func TestRemoteConfig_Poll(t *testing.T) {
+	s := &configServer{}
+	s.set("[agent]\n", `"v1"`)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	r, err := NewRemoteConfig(ts.URL, "", "")
+	require.NoError(t, err)
+	data, err := r.Fetch()
+	require.NoError(t, err)
+	require.Equal(t, "[agent]\n", string(data))
+
+	changed, err := r.Poll()
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Equal(t, 1, s.conditional)
+
+	s.set("[agent]\n  debug = true\n", `"v2"`)
+	changed, err = r.Poll()
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	data, err = r.Fetch()
+	require.NoError(t, err)
+	require.Equal(t, "[agent]\n  debug = true\n", string(data))
+	require.Equal(t, 3, s.conditional)
+}
+
+func TestRemoteConfig_Digest(t *testing.T) {
+	sum := sha256.Sum256([]byte("[agent]\n"))
+	s := &configServer{headers: map[string]string{
+		"Digest": "md5=ignored, SHA-256=" + base64.StdEncoding.EncodeToString(sum[:]),
+	}}
+	s.set("[agent]\n", `"v1"`)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	r, err := NewRemoteConfig(ts.URL, "", "")
+	require.NoError(t, err)
+	_, err = r.Fetch()
+	require.NoError(t, err)
+
+	s.set("[agent]\n  debug = true\n", `"v2"`)
+	_, err = r.Poll()
+	require.EqualError(t, err, "config does not match its SHA-256 digest")
+}
+
+func TestRemoteConfig_Signature(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "remote")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "key.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	require.NoError(t, ioutil.WriteFile(keyFile, pemData, 0600))
+
+	s := &configServer{headers: map[string]string{
+		SignatureHeader: base64.StdEncoding.EncodeToString(ed25519.Sign(private, []byte("[agent]\n"))),
+	}}
+	s.set("[agent]\n", `"v1"`)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	r, err := NewRemoteConfig(ts.URL, "", keyFile)
+	require.NoError(t, err)
+	_, err = r.Fetch()
+	require.NoError(t, err)
+
+	// The signature does not match the new config.
+	s.set("[agent]\n  debug = true\n", `"v2"`)
+	_, err = r.Poll()
+	require.EqualError(t, err, "config signature verification failed")
+
+	s.Lock()
+	s.headers = nil
+	s.Unlock()
+	_, err = r.Poll()
+	require.EqualError(t, err, "config is not signed, missing Telegraf-Config-Signature header")
+}
+
+func TestRemoteConfig_CacheFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "remote")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cacheFile := filepath.Join(dir, "telegraf.conf")
+
+	s := &configServer{}
+	s.set("[agent]\n", `"v1"`)
+	ts := httptest.NewServer(s)
+
+	r, err := NewRemoteConfig(ts.URL, cacheFile, "")
+	require.NoError(t, err)
+	_, err = r.Fetch()
+	require.NoError(t, err)
+	require.NoError(t, r.Save())
+	ts.Close()
+
+	// A restarted agent cannot reach the server and uses the cache.
+	r, err = NewRemoteConfig(ts.URL, cacheFile, "")
+	require.NoError(t, err)
+	data, err := r.Fetch()
+	require.NoError(t, err)
+	require.Equal(t, "[agent]\n", string(data))
+
+	_, err = r.Poll()
+	require.Error(t, err)
+
+	r, err = NewRemoteConfig(ts.URL, filepath.Join(dir, "missing.conf"), "")
+	require.NoError(t, err)
+	_, err = r.Fetch()
+	require.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/redact"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+// secretRe is a regex to find secret references, "@{<store id>:<key>}", in
+// the plugin configuration.
+var secretRe = regexp.MustCompile(`@\{(\w+):([^{}]+)\}`)
+
+// secretStoreIDRe matches the valid secret store ids.
+var secretStoreIDRe = regexp.MustCompile(`^\w+$`)
+
+func (c *Config) addSecretStore(name string, table *ast.Table) error {
+	creator, ok := secretstores.SecretStores[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested secretstore: %s", name)
+	}
+	store := creator()
+
+	var id string
+	if node, ok := table.Fields["id"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				id = str.Value
+			}
+		}
+		delete(table.Fields, "id")
+	}
+	if !secretStoreIDRe.MatchString(id) {
+		return fmt.Errorf("invalid or missing id %q, only letters, digits and underscores are allowed", id)
+	}
+	if _, ok := c.SecretStores[id]; ok {
+		return fmt.Errorf("duplicate secretstore id %q", id)
+	}
+
+	if err := toml.UnmarshalTable(table, store); err != nil {
+		return err
+	}
+	if si, ok := store.(telegraf.Initializer); ok {
+		if err := si.Init(); err != nil {
+			return fmt.Errorf("could not initialize secretstore %s: %v", id, err)
+		}
+	}
+
+	c.SecretStores[id] = store
+	return nil
+}
+
+// resolveSecrets replaces the secret references in all string values of the
+// table with the secret.  The secrets are registered for redaction so that
+// they do not appear in the logs.
+func (c *Config) resolveSecrets(tbl *ast.Table) error {
+	for _, field := range tbl.Fields {
+		switch node := field.(type) {
+		case *ast.KeyValue:
+			if err := c.resolveValue(node.Value); err != nil {
+				return &toml.LineError{Line: node.Line, Err: err}
+			}
+		case *ast.Table:
+			if err := c.resolveSecrets(node); err != nil {
+				return err
+			}
+		case []*ast.Table:
+			for _, t := range node {
+				if err := c.resolveSecrets(t); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) resolveValue(value ast.Value) error {
+	switch v := value.(type) {
+	case *ast.String:
+		var err error
+		resolved := secretRe.ReplaceAllStringFunc(v.Value, func(ref string) string {
+			if err != nil {
+				return ref
+			}
+			match := secretRe.FindStringSubmatch(ref)
+			var secret []byte
+			secret, err = c.getSecret(match[1], match[2])
+			return string(secret)
+		})
+		if err != nil {
+			return err
+		}
+		v.Value = resolved
+	case *ast.Array:
+		for _, item := range v.Value {
+			if err := c.resolveValue(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) getSecret(id, key string) ([]byte, error) {
+	store, ok := c.SecretStores[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown secretstore %q", id)
+	}
+	secret, err := store.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("secretstore %q: %w", id, err)
+	}
+	redact.Add(string(secret))
+	return secret, nil
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/internal/redact"
+	httpOut "github.com/influxdata/telegraf/plugins/outputs/http"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/file"
+	"github.com/stretchr/testify/require"
+)
+
+func secretsDir(t *testing.T, secrets map[string]string) string {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	for key, secret := range secrets {
+		err := ioutil.WriteFile(filepath.Join(dir, key), []byte(secret+"\n"), 0600)
+		require.NoError(t, err)
+	}
+	return dir
+}
+
+func TestConfig_SecretReferences(t *testing.T) {
+	dir := secretsDir(t, map[string]string{
+		"user":     "telegraf",
+		"password": `p@ss"word`,
+	})
+	defer os.RemoveAll(dir)
+
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(fmt.Sprintf(`
+[[secretstores.file]]
+  id = "local"
+  directory = %q
+
+[[outputs.http]]
+  url = "http://localhost:8080/@{local:user}"
+  username = "@{local:user}"
+  password = "@{local:password}"
+  [outputs.http.headers]
+    Authorization = "Basic @{local:user}:@{local:password}"
+`, dir)))
+	require.NoError(t, err)
+
+	require.Len(t, c.SecretStores, 1)
+	require.Len(t, c.Outputs, 1)
+	output := c.Outputs[0].Output.(*httpOut.HTTP)
+	require.Equal(t, "http://localhost:8080/telegraf", output.URL)
+	require.Equal(t, "telegraf", output.Username)
+	require.Equal(t, `p@ss"word`, output.Password)
+	require.Equal(t, `Basic telegraf:p@ss"word`, output.Headers["Authorization"])
+
+	require.Equal(t, "password is ****", redact.String(`password is p@ss"word`))
+}
+
+func TestConfig_SecretStoreErrors(t *testing.T) {
+	dir := secretsDir(t, nil)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{
+			name: "unknown store",
+			config: `
+[[outputs.http]]
+  password = "@{missing:password}"
+`,
+		},
+		{
+			name: "unknown key",
+			config: `
+[[secretstores.file]]
+  id = "local"
+  directory = "` + dir + `"
+
+[[outputs.http]]
+  password = "@{local:password}"
+`,
+		},
+		{
+			name: "missing id",
+			config: `
+[[secretstores.file]]
+  directory = "` + dir + `"
+`,
+		},
+		{
+			name: "duplicate id",
+			config: `
+[[secretstores.file]]
+  id = "local"
+  directory = "` + dir + `"
+
+[[secretstores.file]]
+  id = "local"
+  directory = "` + dir + `"
+`,
+		},
+		{
+			name: "unknown plugin",
+			config: `
+[[secretstores.nonexistent]]
+  id = "local"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			require.Error(t, c.LoadConfigData([]byte(tt.config)))
+		})
+	}
+}
+
+func TestConfig_SecretChangeReplacesPlugin(t *testing.T) {
+	dir := secretsDir(t, map[string]string{"password": "old"})
+	defer os.RemoveAll(dir)
+
+	data := []byte(fmt.Sprintf(`
+[[secretstores.file]]
+  id = "local"
+  directory = %q
+
+[[outputs.http]]
+  password = "@{local:password}"
+
+[[outputs.http]]
+  url = "http://localhost:8080/unchanged"
+`, dir))
+
+	running := loadReloadConfig(t, string(data))
+	require.True(t, running.Diff(loadReloadConfig(t, string(data))).Empty())
+
+	err := ioutil.WriteFile(filepath.Join(dir, "password"), []byte("new"), 0600)
+	require.NoError(t, err)
+
+	diff := running.Diff(loadReloadConfig(t, string(data)))
+	require.Len(t, diff.RemovedOutputs, 1)
+	require.Same(t, running.Outputs[0], diff.RemovedOutputs[0])
+	require.Len(t, diff.AddedOutputs, 1)
+	require.Equal(t, "new", diff.AddedOutputs[0].Output.(*httpOut.HTTP).Password)
+}
@@ -1,5 +1,7 @@
 package telegraf
 
+import "context"
+
 type Input interface {
 	PluginDescriber
 
@@ -8,6 +10,17 @@ type Input interface {
 	Gather(Accumulator) error
 }
 
+// ContextGatherer is an Input that can be canceled while gathering.  When
+// implemented GatherContext is called instead of Gather, with a context that
+// is canceled once the gather_timeout of the input expires.
+type ContextGatherer interface {
+	Input
+
+	// GatherContext is like Gather but should return as soon as possible
+	// once the context is done.
+	GatherContext(context.Context, Accumulator) error
+}
+
 type ServiceInput interface {
 	Input
 
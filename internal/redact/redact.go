@@ -0,0 +1,64 @@
+// Package redact keeps track of secret values so that they can be removed
+// from logs and other output.
+package redact
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every secret value.
+const Mask = "****"
+
+var (
+	mu       sync.RWMutex
+	secrets  = make(map[string]bool)
+	replacer = strings.NewReplacer()
+)
+
+// Add registers secret values to be redacted.  Empty values are ignored.
+func Add(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, value := range values {
+		if value != "" {
+			secrets[value] = true
+		}
+	}
+
+	// Longer secrets go first so that they are masked completely when they
+	// contain a shorter one.
+	sorted := make([]string, 0, len(secrets))
+	for secret := range secrets {
+		sorted = append(sorted, secret)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+
+	oldnew := make([]string, 0, 2*len(sorted))
+	for _, secret := range sorted {
+		oldnew = append(oldnew, secret, Mask)
+	}
+	replacer = strings.NewReplacer(oldnew...)
+}
+
+// String returns s with all registered secret values replaced by Mask.
+func String(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return replacer.Replace(s)
+}
+
+// Bytes returns b with all registered secret values replaced by Mask.
+func Bytes(b []byte) []byte {
+	mu.RLock()
+	empty := len(secrets) == 0
+	mu.RUnlock()
+	if empty {
+		return b
+	}
+	return []byte(String(string(b)))
+}
@@ -0,0 +1,16 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	require.Equal(t, "password=hunter2", String("password=hunter2"))
+
+	Add("hunter2", "")
+	require.Equal(t, "password=****", String("password=hunter2"))
+	require.Equal(t, []byte("token=****, again ****"), Bytes([]byte("token=hunter2, again hunter2")))
+	require.Equal(t, "nothing to hide", String("nothing to hide"))
+}
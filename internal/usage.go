@@ -11,11 +11,21 @@ Usage:
 The commands & flags are:
 
   config              print out full sample configuration to stdout
+  config check        check the configuration files and print the problems
+                      found as JSON, exits with status 1 if there are any
   version             print the version to stdout
 
   --aggregator-filter <filter>   filter the aggregators to enable, separator is :
   --config <file>                configuration file to load
   --config-directory <directory> directory containing additional *.conf files
+  --config-directory-formats     formats of the other files to load from the config
+                                 directory, 'toml', 'yaml' or 'json', separator is :
+  --config-cache-file <file>     file to store the last good remote configuration in,
+                                 it is used when the server is unreachable on startup
+  --config-poll-interval         poll a remote configuration for changes at
+                                 this interval and reload it
+  --config-public-key <file>     PEM encoded ed25519 public key verifying the
+                                 signature of the remote configuration
   --plugin-directory             directory containing *.so files, this directory will be
                                  searched recursively. Any Plugin found will be loaded
                                  and namespaced.
@@ -32,8 +42,13 @@ The commands & flags are:
                                  Valid values are 'agent', 'global_tags', 'outputs',
                                  'processors', 'aggregators' and 'inputs'
   --sample-config                print out full sample configuration
+  --sample-config-format         format of the sample configuration, 'toml',
+                                 'yaml' or 'json'
   --once                         enable once mode: gather metrics once, write them, and exit
   --test                         enable test mode: gather metrics once and print them
+  --test-pipeline                enable pipeline test mode: gather metrics once, run
+                                 processors and aggregators, and print the metrics
+                                 of each output in its data format
   --test-wait                    wait up to this many seconds for service
                                  inputs to complete in test or once mode
   --usage <plugin>               print usage for a plugin, ie, 'telegraf --usage mysql'
@@ -44,12 +59,25 @@ Examples:
   # generate a telegraf config file:
   telegraf config > telegraf.conf
 
+  # generate a telegraf config file in YAML
+  telegraf --sample-config-format yaml config > telegraf.yaml
+
   # generate config with only cpu input & influxdb output plugins defined
   telegraf --input-filter cpu --output-filter influxdb config
 
+  # check the configuration for unknown keys, invalid values and plugins
+  # failing to initialize
+  telegraf --config telegraf.conf --config-directory telegraf.d config check
+
   # run a single telegraf collection, outputting metrics to stdout
   telegraf --config telegraf.conf --test
 
+  # preview what each output would write after processors and aggregators
+  telegraf --config telegraf.conf --test-pipeline
+
+  # poll a remote configuration every minute and reload it when it changes
+  telegraf --config https://example.com/telegraf.conf --config-poll-interval 1m --config-cache-file telegraf.conf
+
   # run telegraf with all plugins defined in config file
   telegraf --config telegraf.conf
 
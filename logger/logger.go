@@ -1,14 +1,19 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"log"
 	"os"
 	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/redact"
 	"github.com/influxdata/telegraf/internal/rotate"
 	"github.com/influxdata/wlog"
 )
@@ -20,6 +25,19 @@ const (
 	LogTargetStderr = "stderr"
 )
 
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// levelNames are the names of the levels in the JSON format.
+var levelNames = map[byte]string{
+	'D': "debug",
+	'I': "info",
+	'W': "warn",
+	'E': "error",
+}
+
 // LogConfig contains the log configuration settings
 type LogConfig struct {
 	// will set the log level to DEBUG
@@ -28,6 +46,8 @@ type LogConfig struct {
 	Quiet bool
 	//stderr, stdout, file or eventlog (Windows only)
 	LogTarget string
+	// text or json, the eventlog target always uses text
+	LogFormat string
 	// will direct the logging output to a file. Empty string is
 	// interpreted as stderr. If there is an error opening the file the
 	// logger will fallback to stderr
@@ -54,18 +74,72 @@ func registerLogger(name string, loggerCreator LoggerCreator) {
 }
 
 type telegrafLog struct {
-	writer         io.Writer
 	internalWriter io.Writer
+	format         string
 }
 
 func (t *telegrafLog) Write(b []byte) (n int, err error) {
+	return t.write(b, false)
+}
+
+// write writes the log line in the configured format.  Unless force is set,
+// lines below the global log level are dropped.
+func (t *telegrafLog) write(b []byte, force bool) (n int, err error) {
+	n = len(b)
+	level := byte('I')
+	if prefixRegex.Match(b) {
+		level = b[0]
+		b = bytes.TrimLeft(b[2:], " ")
+	}
+	if !force && wlog.Levels[level] < wlog.LogLevel() {
+		return n, nil
+	}
+
+	ts := time.Now().UTC()
 	var line []byte
-	if !prefixRegex.Match(b) {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" I! "), b...)
+	if t.format == LogFormatJSON {
+		line, err = jsonLine(ts, level, b)
+		if err != nil {
+			return 0, err
+		}
 	} else {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" "), b...)
+		line = append([]byte(ts.Format(time.RFC3339)+" "+string(level)+"! "), b...)
 	}
-	return t.writer.Write(line)
+	return t.internalWriter.Write(line)
+}
+
+// jsonEntry is a log line in the JSON format.
+type jsonEntry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Plugin  string `json:"plugin,omitempty"`
+	Alias   string `json:"alias,omitempty"`
+	Message string `json:"message"`
+}
+
+// jsonLine formats a message, with the "[plugin::alias]" prefix of the text
+// format, as JSON.
+func jsonLine(ts time.Time, level byte, msg []byte) ([]byte, error) {
+	entry := jsonEntry{
+		Time:    ts.Format(time.RFC3339Nano),
+		Level:   levelNames[level],
+		Message: strings.TrimRight(string(msg), "\r\n"),
+	}
+	if strings.HasPrefix(entry.Message, "[") {
+		if end := strings.Index(entry.Message, "] "); end > 0 {
+			entry.Plugin = entry.Message[1:end]
+			entry.Message = entry.Message[end+2:]
+			if i := strings.Index(entry.Plugin, "::"); i >= 0 {
+				entry.Plugin, entry.Alias = entry.Plugin[:i], entry.Plugin[i+2:]
+			}
+		}
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
 }
 
 func (t *telegrafLog) Close() error {
@@ -82,11 +156,23 @@ func (t *telegrafLog) Close() error {
 	return nil
 }
 
+// redactWriter masks the secrets of the configuration in the log messages.
+type redactWriter struct {
+	writer io.Writer
+}
+
+func (r *redactWriter) Write(b []byte) (n int, err error) {
+	if _, err := r.writer.Write(redact.Bytes(b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
 // newTelegrafWriter returns a logging-wrapped writer.
-func newTelegrafWriter(w io.Writer) io.Writer {
+func newTelegrafWriter(w io.Writer, format string) io.Writer {
 	return &telegrafLog{
-		writer:         wlog.NewWriter(w),
 		internalWriter: w,
+		format:         format,
 	}
 }
 
@@ -120,13 +206,37 @@ func (t *telegrafLogCreator) CreateLogger(config LogConfig) (io.Writer, error) {
 		writer = defaultWriter
 	}
 
-	return newTelegrafWriter(writer), nil
+	return newTelegrafWriter(writer, config.LogFormat), nil
 }
 
 // Keep track what is actually set as a log output, because log package doesn't provide a getter.
 // It allows closing previous writer if re-set and have possibility to test what is actually set
 var actualLogger io.Writer
 
+// actualLoggerMu protects actualLogger, which is used by Output.
+var actualLoggerMu sync.Mutex
+
+// Output writes a log message, beginning with its level prefix like "D!",
+// without checking it against the global log level.  It is used by loggers
+// that have their own level.
+func Output(msg string) {
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	b := redact.Bytes([]byte(msg))
+
+	actualLoggerMu.Lock()
+	defer actualLoggerMu.Unlock()
+	switch w := actualLogger.(type) {
+	case *telegrafLog:
+		w.write(b, true)
+	case nil:
+		os.Stderr.Write(b)
+	default:
+		w.Write(b)
+	}
+}
+
 func newLogWriter(config LogConfig) io.Writer {
 	log.SetFlags(0)
 	if config.Debug {
@@ -146,11 +256,13 @@ func newLogWriter(config LogConfig) io.Writer {
 		logWriter, _ = (&telegrafLogCreator{}).CreateLogger(config)
 	}
 
+	actualLoggerMu.Lock()
 	if closer, isCloser := actualLogger.(io.Closer); isCloser {
 		closer.Close()
 	}
-	log.SetOutput(logWriter)
+	log.SetOutput(&redactWriter{writer: logWriter})
 	actualLogger = logWriter
+	actualLoggerMu.Unlock()
 
 	return logWriter
 }
@@ -2,14 +2,17 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/redact"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -29,6 +32,71 @@ func TestWriteLogToFile(t *testing.T) {
 	assert.Equal(t, f[19:], []byte("Z I! TEST\n"))
 }
 
+func TestWriteLogRedactsSecrets(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	redact.Add("s3cr3t-passw0rd")
+
+	config := createBasicLogConfig(tmpfile.Name())
+	SetupLogging(config)
+	log.Printf("E! connecting with password s3cr3t-passw0rd failed")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, f[19:], []byte("Z E! connecting with password **** failed\n"))
+}
+
+func TestWriteLogJSON(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	config := createBasicLogConfig(tmpfile.Name())
+	config.LogFormat = LogFormatJSON
+	SetupLogging(config)
+	log.Printf("W! [inputs.cpu::mycpu] Collection took longer than expected")
+	log.Printf("E! [outputs.file] Error writing \"metrics\"")
+	log.Printf("D! ignored")
+	log.Printf("no prefix")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(f), []byte("\n"))
+	require.Len(t, lines, 3)
+
+	expected := []map[string]interface{}{
+		{"level": "warn", "plugin": "inputs.cpu", "alias": "mycpu", "message": "Collection took longer than expected"},
+		{"level": "error", "plugin": "outputs.file", "message": "Error writing \"metrics\""},
+		{"level": "info", "message": "no prefix"},
+	}
+	for i, line := range lines {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &entry))
+		_, err := time.Parse(time.RFC3339Nano, entry["time"].(string))
+		require.NoError(t, err)
+		delete(entry, "time")
+		require.Equal(t, expected[i], entry)
+	}
+}
+
+func TestOutputIgnoresGlobalLevel(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	config := createBasicLogConfig(tmpfile.Name())
+	config.Quiet = true
+	SetupLogging(config)
+	log.Printf("D! [inputs.cpu] filtered")
+	Output("D! [inputs.cpu] TEST")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, f[19:], []byte("Z D! [inputs.cpu] TEST\n"))
+}
+
 func TestDebugWriteLogToFile(t *testing.T) {
 	tmpfile, err := ioutil.TempFile("", "")
 	assert.NoError(t, err)
@@ -137,7 +205,7 @@ func TestLogTargetSettings(t *testing.T) {
 func BenchmarkTelegrafLogWrite(b *testing.B) {
 	var msg = []byte("test")
 	var buf bytes.Buffer
-	w := newTelegrafWriter(&buf)
+	w := newTelegrafWriter(&buf, LogFormatText)
 	for i := 0; i < b.N; i++ {
 		buf.Reset()
 		w.Write(msg)
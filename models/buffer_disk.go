@@ -0,0 +1,371 @@
+package models
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/tidwall/wal"
+)
+
+// DiskBuffer stores metrics in a write-ahead log on disk so that unsent
+// metrics survive a restart of Telegraf.
+//
+// Metrics are accepted once they have been synced to disk, tracking metrics
+// are therefore considered delivered once they are persisted.  The log is
+// synced before each batch is taken and when the buffer is closed, so that
+// adding a metric does not wait for the disk.
+type DiskBuffer struct {
+	sync.Mutex
+	BufferStats
+
+	BufferDiskSize selfstat.Stat
+
+	path string
+	file *wal.Log
+	cap  int // the capacity of the buffer
+
+	first uint64 // index of the first/oldest metric, zero if empty
+	last  uint64 // index of the last/newest metric, zero if empty
+
+	batchSize int // number of metrics currently in the batch
+
+	// batchIndex are the indexes of the metrics returned by Batch, metrics
+	// dropped from the log while the batch is out are not counted as
+	// written.
+	batchIndex []uint64
+
+	// pending are the metrics written to the log since the last sync, ordered
+	// by their index.
+	pending []pendingMetric
+}
+
+type pendingMetric struct {
+	index  uint64
+	metric telegraf.Metric
+}
+
+// diskMetric is the on-disk representation of a metric.
+type diskMetric struct {
+	Name   string
+	Tags   map[string]string
+	Fields map[string]interface{}
+	Time   int64
+	Type   telegraf.ValueType
+}
+
+// NewDiskBuffer opens or creates a DiskBuffer in the given directory.  Any
+// metrics left over from a previous run are loaded and will be written before
+// newly added metrics.
+func NewDiskBuffer(name string, alias string, capacity int, path string) (*DiskBuffer, error) {
+	tags := map[string]string{"output": name}
+	if alias != "" {
+		tags["alias"] = alias
+	}
+
+	b := &DiskBuffer{
+		BufferStats: NewBufferStats(name, alias, capacity),
+		BufferDiskSize: selfstat.Register(
+			"write",
+			"buffer_disk_size",
+			tags,
+		),
+		path: path,
+		cap:  capacity,
+	}
+
+	if err := b.open(); err != nil {
+		return nil, err
+	}
+
+	b.BufferSize.Set(int64(b.length()))
+	b.updateDiskSize()
+	return b, nil
+}
+
+func (b *DiskBuffer) open() error {
+	if err := os.MkdirAll(b.path, 0750); err != nil {
+		return fmt.Errorf("creating buffer directory: %w", err)
+	}
+
+	opts := *wal.DefaultOptions
+	opts.NoSync = true
+	file, err := wal.Open(b.path, &opts)
+	if err != nil {
+		return fmt.Errorf("opening buffer %q: %w", b.path, err)
+	}
+	b.file = file
+
+	b.first, err = file.FirstIndex()
+	if err != nil {
+		return err
+	}
+	b.last, err = file.LastIndex()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// reset removes all entries from the log.  The log is not able to truncate
+// its last entry so the files are removed and the log is recreated.
+func (b *DiskBuffer) reset() error {
+	if err := b.file.Close(); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(b.path); err != nil {
+		return err
+	}
+	return b.open()
+}
+
+// Len returns the number of metrics currently in the buffer.
+func (b *DiskBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.length()
+}
+
+func (b *DiskBuffer) length() int {
+	if b.last == 0 {
+		return 0
+	}
+	return int(b.last - b.first + 1)
+}
+
+func (b *DiskBuffer) add(m telegraf.Metric) (int, error) {
+	data, err := encodeMetric(m)
+	if err != nil {
+		return 0, err
+	}
+
+	index := b.last + 1
+	if err := b.file.Write(index, data); err != nil {
+		return 0, err
+	}
+	if b.first == 0 {
+		b.first = index
+	}
+	b.last = index
+	b.BufferDiskSize.Incr(int64(len(data)))
+
+	b.metricAdded()
+	b.pending = append(b.pending, pendingMetric{index: index, metric: m})
+
+	// Drop the oldest metric if the buffer is over capacity, this happens
+	// after the write since the log can not truncate its only entry.
+	dropped := 0
+	if b.length() > b.cap {
+		if err := b.file.TruncateFront(b.first + 1); err != nil {
+			return 0, err
+		}
+		b.first++
+		AgentMetricsDropped.Incr(1)
+		b.MetricsDropped.Incr(1)
+		dropped++
+
+		// The dropped metric was not synced yet if it is still pending.
+		if len(b.pending) > 0 && b.pending[0].index < b.first {
+			b.pending[0].metric.Reject()
+			b.pending = b.pending[1:]
+		}
+
+		if b.batchSize > 0 {
+			b.batchSize--
+		}
+	}
+	return dropped, nil
+}
+
+// sync flushes the log to disk and accepts the pending metrics.  If the log
+// cannot be synced the metrics are rejected, they are still sent if they
+// have been written.
+func (b *DiskBuffer) sync() {
+	if len(b.pending) == 0 {
+		return
+	}
+
+	err := b.file.Sync()
+	for _, p := range b.pending {
+		if err != nil {
+			p.metric.Reject()
+			continue
+		}
+		p.metric.Accept()
+	}
+	b.pending = b.pending[:0]
+}
+
+// Add adds metrics to the buffer and returns number of dropped metrics.
+// Metrics that cannot be written to disk are dropped.
+func (b *DiskBuffer) Add(metrics ...telegraf.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	dropped := 0
+	for _, m := range metrics {
+		n, err := b.add(m)
+		if err != nil {
+			b.metricDropped(m)
+			dropped++
+			continue
+		}
+		dropped += n
+	}
+
+	b.BufferSize.Set(int64(b.length()))
+	return dropped
+}
+
+// Batch returns a slice containing up to batchSize of the oldest metrics.
+// Metrics are ordered from oldest to newest in the batch.  Entries that
+// cannot be decoded are dropped.
+func (b *DiskBuffer) Batch(batchSize int) []telegraf.Metric {
+	b.Lock()
+	defer b.Unlock()
+
+	b.sync()
+
+	outLen := min(b.length(), batchSize)
+	out := make([]telegraf.Metric, 0, outLen)
+	b.batchIndex = b.batchIndex[:0]
+	if outLen == 0 {
+		return out
+	}
+
+	for index := b.first; index < b.first+uint64(outLen); index++ {
+		data, err := b.file.Read(index)
+		if err != nil {
+			AgentMetricsDropped.Incr(1)
+			b.MetricsDropped.Incr(1)
+			continue
+		}
+		m, err := decodeMetric(data)
+		if err != nil {
+			AgentMetricsDropped.Incr(1)
+			b.MetricsDropped.Incr(1)
+			continue
+		}
+		out = append(out, m)
+		b.batchIndex = append(b.batchIndex, index)
+	}
+
+	// Skip past a batch that has no readable entries, otherwise it would
+	// block the buffer forever.
+	if len(out) == 0 {
+		b.truncate(b.first + uint64(outLen))
+		b.BufferSize.Set(int64(b.length()))
+		return out
+	}
+
+	b.batchSize = outLen
+	return out
+}
+
+// Accept marks the batch, acquired from Batch(), as successfully written and
+// removes it from the log.
+func (b *DiskBuffer) Accept(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for i, m := range batch {
+		if i < len(b.batchIndex) && b.batchIndex[i] < b.first {
+			// Dropped by an overflow and already counted as dropped.
+			continue
+		}
+		b.metricWritten(m)
+	}
+
+	if b.batchSize > 0 {
+		b.truncate(b.first + uint64(b.batchSize))
+	}
+
+	b.resetBatch()
+	b.BufferSize.Set(int64(b.length()))
+	b.updateDiskSize()
+}
+
+// truncate removes all entries before index from the log.  If the log cannot
+// be truncated the entries stay in the buffer and will be sent again.
+func (b *DiskBuffer) truncate(index uint64) {
+	if index > b.last {
+		if err := b.reset(); err == nil {
+			b.first, b.last = 0, 0
+		}
+		return
+	}
+
+	if err := b.file.TruncateFront(index); err == nil {
+		b.first = index
+	}
+}
+
+// Reject marks the batch, acquired from Batch(), as unsent.  The metrics are
+// still in the log and will be returned by the next call to Batch().
+func (b *DiskBuffer) Reject(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.resetBatch()
+}
+
+// Close flushes and closes the log.
+func (b *DiskBuffer) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	b.sync()
+	if err := b.file.Sync(); err != nil {
+		return err
+	}
+	return b.file.Close()
+}
+
+func (b *DiskBuffer) resetBatch() {
+	b.batchSize = 0
+	b.batchIndex = b.batchIndex[:0]
+}
+
+// updateDiskSize sets the stat with the size of the log files on disk.
+func (b *DiskBuffer) updateDiskSize() {
+	var size int64
+	filepath.Walk(b.path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	b.BufferDiskSize.Set(size)
+}
+
+func encodeMetric(m telegraf.Metric) ([]byte, error) {
+	dm := diskMetric{
+		Name:   m.Name(),
+		Tags:   m.Tags(),
+		Fields: m.Fields(),
+		Time:   m.Time().UnixNano(),
+		Type:   m.Type(),
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&dm); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeMetric(data []byte) (telegraf.Metric, error) {
+	var dm diskMetric
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&dm); err != nil {
+		return nil, err
+	}
+	return metric.New(dm.Name, dm.Tags, dm.Fields, time.Unix(0, dm.Time), dm.Type)
+}
@@ -0,0 +1,256 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiskBuffer(t *testing.T, capacity int) (*DiskBuffer, string) {
+	path, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+
+	b, err := NewDiskBuffer("test", "", capacity, path)
+	require.NoError(t, err)
+	b.MetricsAdded.Set(0)
+	b.MetricsWritten.Set(0)
+	b.MetricsDropped.Set(0)
+	return b, path
+}
+
+func TestDiskBuffer_LenEmpty(t *testing.T) {
+	b, path := newTestDiskBuffer(t, 5)
+	defer os.RemoveAll(path)
+	defer b.Close()
+
+	require.Equal(t, 0, b.Len())
+}
+
+func TestDiskBuffer_LenOverfill(t *testing.T) {
+	m := Metric()
+	b, path := newTestDiskBuffer(t, 5)
+	defer os.RemoveAll(path)
+	defer b.Close()
+
+	dropped := b.Add(m, m, m, m, m, m)
+
+	require.Equal(t, 1, dropped)
+	require.Equal(t, 5, b.Len())
+	require.Equal(t, int64(1), b.MetricsDropped.Get())
+}
+
+func TestDiskBuffer_BatchLatest(t *testing.T) {
+	b, path := newTestDiskBuffer(t, 4)
+	defer os.RemoveAll(path)
+	defer b.Close()
+
+	b.Add(MetricTime(1))
+	b.Add(MetricTime(2))
+	b.Add(MetricTime(3))
+	batch := b.Batch(2)
+
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(1),
+			MetricTime(2),
+		}, batch)
+}
+
+func TestDiskBuffer_AcceptRemovesBatch(t *testing.T) {
+	b, path := newTestDiskBuffer(t, 5)
+	defer os.RemoveAll(path)
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	batch := b.Batch(2)
+	b.Accept(batch)
+
+	require.Equal(t, 1, b.Len())
+	require.Equal(t, int64(2), b.MetricsWritten.Get())
+
+	batch = b.Batch(2)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(3),
+		}, batch)
+	b.Accept(batch)
+
+	require.Equal(t, 0, b.Len())
+
+	b.Add(MetricTime(4))
+	batch = b.Batch(2)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(4),
+		}, batch)
+}
+
+func TestDiskBuffer_RejectKeepsBatch(t *testing.T) {
+	b, path := newTestDiskBuffer(t, 5)
+	defer os.RemoveAll(path)
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	batch := b.Batch(2)
+	b.Reject(batch)
+
+	require.Equal(t, 3, b.Len())
+
+	batch = b.Batch(5)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(1),
+			MetricTime(2),
+			MetricTime(3),
+		}, batch)
+}
+
+func TestDiskBuffer_AddDuringBatchOverfill(t *testing.T) {
+	b, path := newTestDiskBuffer(t, 3)
+	defer os.RemoveAll(path)
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	batch := b.Batch(2)
+	b.Add(MetricTime(4))
+	b.Accept(batch)
+
+	batch = b.Batch(5)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(3),
+			MetricTime(4),
+		}, batch)
+}
+
+func TestDiskBuffer_AddDuringBatchOverfillStats(t *testing.T) {
+	b, path := newTestDiskBuffer(t, 3)
+	defer os.RemoveAll(path)
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	batch := b.Batch(2)
+	b.Add(MetricTime(4))
+	b.Accept(batch)
+
+	// The first metric of the batch was dropped by the overflow, it is not
+	// also counted as written.
+	require.Equal(t, int64(1), b.MetricsDropped.Get())
+	require.Equal(t, int64(1), b.MetricsWritten.Get())
+	require.Equal(t, 2, b.Len())
+}
+
+func TestDiskBuffer_PreservesTypes(t *testing.T) {
+	b, path := newTestDiskBuffer(t, 5)
+	defer os.RemoveAll(path)
+	defer b.Close()
+
+	m, err := metric.New(
+		"cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{
+			"int":    int64(-42),
+			"uint":   uint64(42),
+			"float":  42.5,
+			"bool":   true,
+			"string": "value",
+		},
+		time.Unix(0, 1257894000000000123),
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+
+	b.Add(m.Copy())
+	batch := b.Batch(1)
+
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, batch)
+	require.Equal(t, telegraf.Counter, batch[0].Type())
+}
+
+func TestDiskBuffer_Persistent(t *testing.T) {
+	b, path := newTestDiskBuffer(t, 5)
+	defer os.RemoveAll(path)
+
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	b.Accept(b.Batch(1))
+	require.NoError(t, b.Close())
+
+	b, err := NewDiskBuffer("test", "", 5, path)
+	require.NoError(t, err)
+	defer b.Close()
+
+	require.Equal(t, 2, b.Len())
+	batch := b.Batch(5)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(2),
+			MetricTime(3),
+		}, batch)
+	require.NotZero(t, b.BufferDiskSize.Get())
+}
+
+func TestDiskBuffer_AcceptTrackingMetricOnSync(t *testing.T) {
+	b, path := newTestDiskBuffer(t, 5)
+	defer os.RemoveAll(path)
+	defer b.Close()
+
+	var accept int
+	mm := &MockMetric{
+		Metric: Metric(),
+		AcceptF: func() {
+			accept++
+		},
+	}
+
+	// The metric is only delivered once the log is synced to disk.
+	b.Add(mm)
+	require.Equal(t, 0, accept)
+	b.Batch(1)
+	require.Equal(t, 1, accept)
+}
+
+func TestDiskBuffer_AcceptTrackingMetricOnClose(t *testing.T) {
+	b, path := newTestDiskBuffer(t, 5)
+	defer os.RemoveAll(path)
+
+	var accept int
+	mm := &MockMetric{
+		Metric: Metric(),
+		AcceptF: func() {
+			accept++
+		},
+	}
+
+	b.Add(mm)
+	require.NoError(t, b.Close())
+	require.Equal(t, 1, accept)
+}
+
+func TestDiskBuffer_RejectUnsyncedDroppedMetric(t *testing.T) {
+	b, path := newTestDiskBuffer(t, 1)
+	defer os.RemoveAll(path)
+	defer b.Close()
+
+	var accept, reject int
+	mm := &MockMetric{
+		Metric: Metric(),
+		AcceptF: func() {
+			accept++
+		},
+		RejectF: func() {
+			reject++
+		},
+	}
+
+	dropped := b.Add(mm, Metric())
+	require.Equal(t, 1, dropped)
+	b.Batch(1)
+	require.Equal(t, 0, accept)
+	require.Equal(t, 1, reject)
+}
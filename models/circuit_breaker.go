@@ -0,0 +1,102 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// States of the circuit breaker as reported in the circuit_state stat.
+const (
+	CircuitClosed   = 0
+	CircuitOpen     = 1
+	CircuitHalfOpen = 2
+)
+
+// circuitBreaker stops writes to a failing output for an exponentially
+// growing interval.  Once the interval has elapsed a single write is let
+// through, if it succeeds the breaker closes again.
+type circuitBreaker struct {
+	sync.Mutex
+
+	initial time.Duration
+	max     time.Duration
+
+	failures int
+	retryAt  time.Time
+
+	State               selfstat.Stat
+	ConsecutiveFailures selfstat.Stat
+}
+
+func newCircuitBreaker(initial, max time.Duration, tags map[string]string) *circuitBreaker {
+	if max < initial {
+		max = initial
+	}
+	return &circuitBreaker{
+		initial: initial,
+		max:     max,
+		State: selfstat.Register(
+			"write",
+			"circuit_state",
+			tags,
+		),
+		ConsecutiveFailures: selfstat.Register(
+			"write",
+			"consecutive_failures",
+			tags,
+		),
+	}
+}
+
+// allow returns if a write may be attempted, and otherwise the time when it
+// may be attempted again.
+func (b *circuitBreaker) allow(now time.Time) (bool, time.Time) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.failures == 0 || b.initial == 0 {
+		return true, time.Time{}
+	}
+	if now.Before(b.retryAt) {
+		return false, b.retryAt
+	}
+	b.State.Set(CircuitHalfOpen)
+	return true, time.Time{}
+}
+
+// success closes the breaker.
+func (b *circuitBreaker) success() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures = 0
+	b.retryAt = time.Time{}
+	b.State.Set(CircuitClosed)
+	b.ConsecutiveFailures.Set(0)
+}
+
+// failure opens the breaker and returns the interval until the next write.
+func (b *circuitBreaker) failure(now time.Time) time.Duration {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures++
+	b.ConsecutiveFailures.Set(int64(b.failures))
+	if b.initial == 0 {
+		return 0
+	}
+
+	backoff := b.initial
+	for i := 1; i < b.failures && backoff < b.max; i++ {
+		backoff *= 2
+	}
+	if backoff > b.max {
+		backoff = b.max
+	}
+
+	b.retryAt = now.Add(backoff)
+	b.State.Set(CircuitOpen)
+	return backoff
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakerBackoff(t *testing.T) {
+	b := newCircuitBreaker(time.Second, 5*time.Second, map[string]string{"output": "breaker"})
+	now := time.Unix(0, 0)
+
+	ok, _ := b.allow(now)
+	require.True(t, ok)
+
+	var backoffs []time.Duration
+	for i := 0; i < 5; i++ {
+		backoffs = append(backoffs, b.failure(now))
+	}
+	require.Equal(t, []time.Duration{
+		1 * time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		5 * time.Second,
+		5 * time.Second,
+	}, backoffs)
+	require.Equal(t, int64(CircuitOpen), b.State.Get())
+	require.Equal(t, int64(5), b.ConsecutiveFailures.Get())
+
+	ok, retryAt := b.allow(now.Add(4 * time.Second))
+	require.False(t, ok)
+	require.Equal(t, now.Add(5*time.Second), retryAt)
+
+	ok, _ = b.allow(now.Add(5 * time.Second))
+	require.True(t, ok)
+	require.Equal(t, int64(CircuitHalfOpen), b.State.Get())
+
+	b.success()
+	require.Equal(t, int64(CircuitClosed), b.State.Get())
+	require.Equal(t, int64(0), b.ConsecutiveFailures.Get())
+	require.Equal(t, time.Second, b.failure(now))
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(0, 0, map[string]string{"output": "breaker_disabled"})
+	now := time.Unix(0, 0)
+
+	require.Equal(t, time.Duration(0), b.failure(now))
+	ok, _ := b.allow(now)
+	require.True(t, ok)
+	require.Equal(t, int64(CircuitClosed), b.State.Get())
+	require.Equal(t, int64(1), b.ConsecutiveFailures.Get())
+	b.success()
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"os"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// DeadLetterSink receives the metrics an output rejected permanently.
+type DeadLetterSink interface {
+	// AddMetric adds a rejected metric to the sink.
+	//
+	// Takes ownership of metric
+	AddMetric(metric telegraf.Metric)
+}
+
+// DeadLetterFile appends rejected metrics to a file in InfluxDB line protocol.
+type DeadLetterFile struct {
+	sync.Mutex
+
+	file       *os.File
+	serializer *influx.Serializer
+	log        telegraf.Logger
+}
+
+// NewDeadLetterFile opens or creates the file at path for appending.
+func NewDeadLetterFile(path string, log telegraf.Logger) (*DeadLetterFile, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	serializer := influx.NewSerializer()
+	serializer.SetFieldSortOrder(influx.SortFields)
+	serializer.SetFieldTypeSupport(influx.UintSupport)
+	return &DeadLetterFile{
+		file:       file,
+		serializer: serializer,
+		log:        log,
+	}, nil
+}
+
+// AddMetric writes the metric to the file.  Metrics that cannot be serialized
+// or written are logged and dropped.
+func (f *DeadLetterFile) AddMetric(metric telegraf.Metric) {
+	f.Lock()
+	defer f.Unlock()
+	defer metric.Drop()
+
+	octets, err := f.serializer.Serialize(metric)
+	if err != nil {
+		f.log.Errorf("Could not serialize dead-letter metric: %v", err)
+		return
+	}
+	if _, err := f.file.Write(octets); err != nil {
+		f.log.Errorf("Could not write dead-letter metric to %q: %v", f.file.Name(), err)
+	}
+}
+
+// Close closes the file.
+func (f *DeadLetterFile) Close() error {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.file.Close()
+}
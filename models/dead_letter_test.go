@@ -0,0 +1,36 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dead_letter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rejected.influx")
+	require.NoError(t, ioutil.WriteFile(path, []byte("old value=1i 0\n"), 0640))
+
+	sink, err := NewDeadLetterFile(path, testutil.Logger{})
+	require.NoError(t, err)
+
+	sink.AddMetric(testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 42.5, "count": uint64(3)},
+		time.Unix(0, 42),
+	))
+	require.NoError(t, sink.Close())
+
+	octets, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "old value=1i 0\ncpu,host=a count=3u,usage=42.5 42\n", string(octets))
+}
@@ -1,16 +1,22 @@
 package models
 
 import (
+	"fmt"
 	"log"
 	"reflect"
+	"strings"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/wlog"
 )
 
 // Logger defines a logging structure for plugins.
 type Logger struct {
 	OnErrs []func()
 	Name   string // Name is the plugin name, will be printed in the `[]`.
+	// Level overrides the global log level of the agent if set.
+	Level wlog.Level
 }
 
 // NewLogger creates a new logger instance
@@ -20,6 +26,20 @@ func NewLogger(pluginType, name, alias string) *Logger {
 	}
 }
 
+// ParseLogLevel returns the level for the name of a level, one of "debug",
+// "info", "warn" or "error".  An empty name returns the zero level, meaning
+// the global log level is used.
+func ParseLogLevel(name string) (wlog.Level, error) {
+	if name == "" {
+		return 0, nil
+	}
+	level, ok := wlog.StringToLevel[strings.ToUpper(name)]
+	if !ok || level == wlog.OFF {
+		return 0, fmt.Errorf("invalid log level %q, must be one of debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
 // OnErr defines a callback that triggers only when errors are about to be written to the log
 func (l *Logger) OnErr(f func()) {
 	l.OnErrs = append(l.OnErrs, f)
@@ -30,7 +50,7 @@ func (l *Logger) Errorf(format string, args ...interface{}) {
 	for _, f := range l.OnErrs {
 		f()
 	}
-	log.Printf("E! ["+l.Name+"] "+format, args...)
+	l.print(wlog.ERROR, fmt.Sprintf(format, args...))
 }
 
 // Error logs an error message, patterned after log.Print.
@@ -38,37 +58,50 @@ func (l *Logger) Error(args ...interface{}) {
 	for _, f := range l.OnErrs {
 		f()
 	}
-	log.Print(append([]interface{}{"E! [" + l.Name + "] "}, args...)...)
+	l.print(wlog.ERROR, fmt.Sprint(args...))
 }
 
 // Debugf logs a debug message, patterned after log.Printf.
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	log.Printf("D! ["+l.Name+"] "+format, args...)
+	l.print(wlog.DEBUG, fmt.Sprintf(format, args...))
 }
 
 // Debug logs a debug message, patterned after log.Print.
 func (l *Logger) Debug(args ...interface{}) {
-	log.Print(append([]interface{}{"D! [" + l.Name + "] "}, args...)...)
+	l.print(wlog.DEBUG, fmt.Sprint(args...))
 }
 
 // Warnf logs a warning message, patterned after log.Printf.
 func (l *Logger) Warnf(format string, args ...interface{}) {
-	log.Printf("W! ["+l.Name+"] "+format, args...)
+	l.print(wlog.WARN, fmt.Sprintf(format, args...))
 }
 
 // Warn logs a warning message, patterned after log.Print.
 func (l *Logger) Warn(args ...interface{}) {
-	log.Print(append([]interface{}{"W! [" + l.Name + "] "}, args...)...)
+	l.print(wlog.WARN, fmt.Sprint(args...))
 }
 
 // Infof logs an information message, patterned after log.Printf.
 func (l *Logger) Infof(format string, args ...interface{}) {
-	log.Printf("I! ["+l.Name+"] "+format, args...)
+	l.print(wlog.INFO, fmt.Sprintf(format, args...))
 }
 
 // Info logs an information message, patterned after log.Print.
 func (l *Logger) Info(args ...interface{}) {
-	log.Print(append([]interface{}{"I! [" + l.Name + "] "}, args...)...)
+	l.print(wlog.INFO, fmt.Sprint(args...))
+}
+
+// print writes the message with the level and name prefix.  Without a level
+// of its own the message is filtered by the global log level.
+func (l *Logger) print(level wlog.Level, msg string) {
+	line := string(wlog.ReverseLevels[level]) + "! [" + l.Name + "] " + msg
+	if l.Level == 0 {
+		log.Print(line)
+		return
+	}
+	if level >= l.Level {
+		logger.Output(line)
+	}
 }
 
 // logName returns the log-friendly name/type.
@@ -1,9 +1,14 @@
 package models
 
 import (
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/require"
 )
 
@@ -22,3 +27,50 @@ func TestErrorCounting(t *testing.T) {
 
 	require.Equal(t, int64(2), reg.Get())
 }
+
+func TestLoggerLevel(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	logger.SetupLogging(logger.LogConfig{
+		LogTarget:           logger.LogTargetFile,
+		Logfile:             tmpfile.Name(),
+		RotationMaxArchives: -1,
+	})
+	defer logger.SetupLogging(logger.LogConfig{})
+
+	debugLog := Logger{Name: "inputs.debug", Level: wlog.DEBUG}
+	debugLog.Debugf("shown %d", 1)
+	errorLog := Logger{Name: "inputs.error", Level: wlog.ERROR}
+	errorLog.Info("hidden")
+	errorLog.Error("shown")
+	globalLog := Logger{Name: "inputs.global"}
+	globalLog.Debug("hidden")
+	globalLog.Warn("shown")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(t, err)
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(string(f)), "\n") {
+		messages = append(messages, line[21:])
+	}
+	require.Equal(t, []string{
+		"D! [inputs.debug] shown 1",
+		"E! [inputs.error] shown",
+		"W! [inputs.global] shown",
+	}, messages)
+}
+
+func TestParseLogLevel(t *testing.T) {
+	level, err := ParseLogLevel("Warn")
+	require.NoError(t, err)
+	require.Equal(t, wlog.WARN, level)
+
+	level, err = ParseLogLevel("")
+	require.NoError(t, err)
+	require.Equal(t, wlog.Level(0), level)
+
+	_, err = ParseLogLevel("off")
+	require.Error(t, err)
+}
@@ -1,10 +1,13 @@
 package models
 
 import (
+	"context"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/wlog"
+	"github.com/robfig/cron/v3"
 )
 
 var (
@@ -19,8 +22,11 @@ type RunningInput struct {
 	log         telegraf.Logger
 	defaultTags map[string]string
 
-	MetricsGathered selfstat.Stat
-	GatherTime      selfstat.Stat
+	MetricsGathered  selfstat.Stat
+	GatherTime       selfstat.Stat
+	GatherTimeouts   selfstat.Stat
+	GathersAbandoned selfstat.Stat
+	GathersSkipped   selfstat.Stat
 }
 
 func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
@@ -31,6 +37,7 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 
 	inputErrorsRegister := selfstat.Register("gather", "errors", tags)
 	logger := NewLogger("inputs", config.Name, config.Alias)
+	logger.Level = config.LogLevel
 	logger.OnErr(func() {
 		inputErrorsRegister.Incr(1)
 		GlobalGatherErrors.Incr(1)
@@ -50,6 +57,21 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 			"gather_time_ns",
 			tags,
 		),
+		GatherTimeouts: selfstat.Register(
+			"gather",
+			"gather_timeouts",
+			tags,
+		),
+		GathersAbandoned: selfstat.Register(
+			"gather",
+			"gathers_abandoned",
+			tags,
+		),
+		GathersSkipped: selfstat.Register(
+			"gather",
+			"gathers_skipped",
+			tags,
+		),
 		log: logger,
 	}
 }
@@ -58,9 +80,12 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 type InputConfig struct {
 	Name             string
 	Alias            string
+	LogLevel         wlog.Level
 	Interval         time.Duration
 	CollectionJitter time.Duration
 	Precision        time.Duration
+	GatherTimeout    time.Duration
+	Schedule         cron.Schedule
 
 	NameOverride      string
 	MeasurementPrefix string
@@ -112,9 +137,16 @@ func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 	return m
 }
 
-func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
+// Gather runs the Gather function of the input, or GatherContext if the
+// input is a telegraf.ContextGatherer.
+func (r *RunningInput) Gather(ctx context.Context, acc telegraf.Accumulator) error {
 	start := time.Now()
-	err := r.Input.Gather(acc)
+	var err error
+	if cg, ok := r.Input.(telegraf.ContextGatherer); ok {
+		err = cg.GatherContext(ctx, acc)
+	} else {
+		err = r.Input.Gather(acc)
+	}
 	elapsed := time.Since(start)
 	r.GatherTime.Incr(elapsed.Nanoseconds())
 	return err
@@ -1,12 +1,16 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/wlog"
 )
 
 const (
@@ -15,13 +19,17 @@ const (
 
 	// Default number of metrics kept. It should be a multiple of batch size.
 	DEFAULT_METRIC_BUFFER_LIMIT = 10000
+
+	// Default maximum time between writes to a failing output.
+	DEFAULT_BACKOFF_MAX_INTERVAL = 5 * time.Minute
 )
 
 // OutputConfig containing name and filter
 type OutputConfig struct {
-	Name   string
-	Alias  string
-	Filter Filter
+	Name     string
+	Alias    string
+	LogLevel wlog.Level
+	Filter   Filter
 
 	FlushInterval     time.Duration
 	FlushJitter       time.Duration
@@ -31,6 +39,30 @@ type OutputConfig struct {
 	NameOverride string
 	NamePrefix   string
 	NameSuffix   string
+
+	BufferStrategy  string
+	BufferDirectory string
+
+	// Pipeline is the name of the processors and aggregators applied to the
+	// metrics of this output only.
+	Pipeline string
+
+	// DeadLetterFile is the file the metrics rejected permanently by the
+	// output are appended to.
+	DeadLetterFile string
+	// DeadLetterOutput is the alias of the output receiving the metrics
+	// rejected permanently by this output.
+	DeadLetterOutput string
+
+	// BackoffInitialInterval is the time writes are postponed after the
+	// first failure, it doubles with every further failure up to
+	// BackoffMaxInterval.  Zero disables the backoff.
+	BackoffInitialInterval time.Duration
+	BackoffMaxInterval     time.Duration
+
+	// StartupErrorBehavior is "retry" to start without a connection and
+	// connect on the next writes, or "error" to fail the startup.
+	StartupErrorBehavior string
 }
 
 // RunningOutput contains the output configuration
@@ -45,12 +77,23 @@ type RunningOutput struct {
 	MetricBatchSize   int
 
 	MetricsFiltered selfstat.Stat
+	MetricsRejected selfstat.Stat
 	WriteTime       selfstat.Stat
 
+	// DeadLetter receives the metrics rejected permanently by the output,
+	// they are dropped if it is nil.  It must be set before the first write.
+	DeadLetter DeadLetterSink
+
+	// Serializer is the serializer of outputs with a data_format, it is used
+	// to print the metrics in pipeline test mode.
+	Serializer serializers.Serializer
+
 	BatchReady chan time.Time
 
-	buffer *Buffer
-	log    telegraf.Logger
+	buffer    MetricBuffer
+	breaker   *circuitBreaker
+	connected bool
+	log       telegraf.Logger
 
 	aggMutex sync.Mutex
 }
@@ -69,6 +112,7 @@ func NewRunningOutput(
 
 	writeErrorsRegister := selfstat.Register("write", "errors", tags)
 	logger := NewLogger("outputs", config.Name, config.Alias)
+	logger.Level = config.LogLevel
 	logger.OnErr(func() {
 		writeErrorsRegister.Incr(1)
 	})
@@ -86,9 +130,14 @@ func NewRunningOutput(
 	if batchSize == 0 {
 		batchSize = DEFAULT_METRIC_BATCH_SIZE
 	}
+	backoffMax := config.BackoffMaxInterval
+	if backoffMax == 0 {
+		backoffMax = DEFAULT_BACKOFF_MAX_INTERVAL
+	}
 
 	ro := &RunningOutput{
 		buffer:            NewBuffer(config.Name, config.Alias, bufferLimit),
+		breaker:           newCircuitBreaker(config.BackoffInitialInterval, backoffMax, tags),
 		BatchReady:        make(chan time.Time, 1),
 		Output:            output,
 		Config:            config,
@@ -99,6 +148,11 @@ func NewRunningOutput(
 			"metrics_filtered",
 			tags,
 		),
+		MetricsRejected: selfstat.Register(
+			"write",
+			"metrics_rejected",
+			tags,
+		),
 		WriteTime: selfstat.RegisterTiming(
 			"write",
 			"write_time_ns",
@@ -120,6 +174,30 @@ func (ro *RunningOutput) metricFiltered(metric telegraf.Metric) {
 }
 
 func (r *RunningOutput) Init() error {
+	switch r.Config.BufferStrategy {
+	case "", "memory":
+	case "disk":
+		buffer, err := NewDiskBuffer(r.Config.Name, r.Config.Alias,
+			r.MetricBufferLimit, r.Config.BufferDirectory)
+		if err != nil {
+			return err
+		}
+		if n := buffer.Len(); n > 0 {
+			r.log.Infof("Loaded %d unsent metrics from %q", n, r.Config.BufferDirectory)
+		}
+		r.buffer = buffer
+	default:
+		return fmt.Errorf("invalid buffer_strategy %q", r.Config.BufferStrategy)
+	}
+
+	if r.Config.DeadLetterFile != "" {
+		sink, err := NewDeadLetterFile(r.Config.DeadLetterFile, r.log)
+		if err != nil {
+			return err
+		}
+		r.DeadLetter = sink
+	}
+
 	if p, ok := r.Output.(telegraf.Initializer); ok {
 		err := p.Init()
 		if err != nil {
@@ -130,6 +208,19 @@ func (r *RunningOutput) Init() error {
 	return nil
 }
 
+// Connect connects the output.  A failure counts towards the backoff of the
+// next writes, which reconnect the output.
+func (r *RunningOutput) Connect() error {
+	err := r.Output.Connect()
+	if err != nil {
+		r.breaker.failure(time.Now())
+		return err
+	}
+	r.connected = true
+	r.breaker.success()
+	return nil
+}
+
 // AddMetric adds a metric to the output.
 //
 // Takes ownership of metric
@@ -180,6 +271,17 @@ func (ro *RunningOutput) AddMetric(metric telegraf.Metric) {
 // Write writes all metrics to the output, stopping when all have been sent on
 // or error.
 func (ro *RunningOutput) Write() error {
+	return ro.writeAll(false)
+}
+
+// WriteFinal writes all metrics to the output like Write, also while the
+// circuit breaker is open.  It is used for the last write before the output
+// is closed, after which metrics in memory are lost.
+func (ro *RunningOutput) WriteFinal() error {
+	return ro.writeAll(true)
+}
+
+func (ro *RunningOutput) writeAll(final bool) error {
 	if output, ok := ro.Output.(telegraf.AggregatingOutput); ok {
 		ro.aggMutex.Lock()
 		metrics := output.Push()
@@ -190,6 +292,10 @@ func (ro *RunningOutput) Write() error {
 
 	atomic.StoreInt64(&ro.newMetricsCount, 0)
 
+	if ok, err := ro.ready(final); !ok {
+		return err
+	}
+
 	// Only process the metrics in the buffer now.  Metrics added while we are
 	// writing will be sent on the next call.
 	nBuffer := ro.buffer.Len()
@@ -200,39 +306,120 @@ func (ro *RunningOutput) Write() error {
 			break
 		}
 
-		err := ro.write(batch)
+		err := ro.writeBatch(batch)
 		if err != nil {
-			ro.buffer.Reject(batch)
 			return err
 		}
-		ro.buffer.Accept(batch)
 	}
 	return nil
 }
 
 // WriteBatch writes a single batch of metrics to the output.
 func (ro *RunningOutput) WriteBatch() error {
+	if ok, err := ro.ready(false); !ok {
+		return err
+	}
+
 	batch := ro.buffer.Batch(ro.MetricBatchSize)
 	if len(batch) == 0 {
 		return nil
 	}
 
+	return ro.writeBatch(batch)
+}
+
+// Close closes the output and its buffer
+func (r *RunningOutput) Close() {
+	if r.connected {
+		err := r.Output.Close()
+		if err != nil {
+			r.log.Errorf("Error closing output: %v", err)
+		}
+	}
+
+	err := r.buffer.Close()
+	if err != nil {
+		r.log.Errorf("Error closing buffer: %v", err)
+	}
+
+	if sink, ok := r.DeadLetter.(*DeadLetterFile); ok {
+		err = sink.Close()
+		if err != nil {
+			r.log.Errorf("Error closing dead-letter file: %v", err)
+		}
+	}
+}
+
+// writeBatch writes a batch taken from the buffer and returns it to the
+// buffer if the write fails.  Metrics rejected permanently by the output are
+// removed from the buffer and passed on to the dead-letter sink.
+func (ro *RunningOutput) writeBatch(batch []telegraf.Metric) error {
 	err := ro.write(batch)
+
+	var partial *telegraf.PartialWriteError
+	if errors.As(err, &partial) {
+		batch = ro.deadLetter(batch, partial)
+		err = nil
+	}
+
 	if err != nil {
 		ro.buffer.Reject(batch)
+		if backoff := ro.breaker.failure(time.Now()); backoff > 0 {
+			ro.log.Warnf("Postponing writes for %s", backoff)
+		}
 		return err
 	}
 	ro.buffer.Accept(batch)
-
+	ro.breaker.success()
 	return nil
 }
 
-// Close closes the output
-func (r *RunningOutput) Close() {
-	err := r.Output.Close()
-	if err != nil {
-		r.log.Errorf("Error closing output: %v", err)
+// ready returns if the output can be written to, reconnecting it if needed.
+// Writes are skipped while the circuit breaker is open, unless it is the
+// final write.
+func (ro *RunningOutput) ready(final bool) (bool, error) {
+	if ok, retryAt := ro.breaker.allow(time.Now()); !ok && !final {
+		ro.log.Debugf("Skipping write, output is failing until %s", retryAt.Format(time.RFC3339))
+		return false, nil
+	}
+
+	if !ro.connected {
+		if err := ro.Connect(); err != nil {
+			return false, fmt.Errorf("reconnecting: %w", err)
+		}
+		ro.log.Infof("Connection established")
+	}
+	return true, nil
+}
+
+// deadLetter hands the rejected metrics of the batch to the dead-letter sink
+// and returns the remaining, written, metrics.
+func (ro *RunningOutput) deadLetter(batch []telegraf.Metric, err *telegraf.PartialWriteError) []telegraf.Metric {
+	rejected := make(map[int]bool, len(err.MetricsReject))
+	for _, i := range err.MetricsReject {
+		if i >= 0 && i < len(batch) {
+			rejected[i] = true
+		}
+	}
+	if len(rejected) == 0 {
+		return batch
+	}
+	ro.log.Errorf("Permanently rejected %d metrics: %v", len(rejected), err.Err)
+
+	written := make([]telegraf.Metric, 0, len(batch)-len(rejected))
+	for i, metric := range batch {
+		if !rejected[i] {
+			written = append(written, metric)
+			continue
+		}
+
+		ro.MetricsRejected.Incr(1)
+		if ro.DeadLetter != nil {
+			ro.DeadLetter.AddMetric(metric.Copy())
+		}
+		metric.Reject()
 	}
+	return written
 }
 
 func (r *RunningOutput) write(metrics []telegraf.Metric) error {
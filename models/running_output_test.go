@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -326,6 +327,101 @@ func TestRunningOutputWriteFail(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+func TestRunningOutputWriteBackoff(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:                 Filter{},
+		BackoffInitialInterval: 50 * time.Millisecond,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 100, 1000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+
+	// Writes are skipped until the backoff has elapsed.
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 0)
+	assert.Equal(t, 5, ro.BufferLength())
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 5)
+	assert.Equal(t, 0, ro.BufferLength())
+}
+
+func TestRunningOutputWriteFinalIgnoresBackoff(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:                 Filter{},
+		BackoffInitialInterval: time.Hour,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 100, 1000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 0)
+
+	require.NoError(t, ro.WriteFinal())
+	assert.Len(t, m.Metrics(), 5)
+	assert.Equal(t, 0, ro.BufferLength())
+}
+
+func TestRunningOutputConnectResetsBackoff(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:                 Filter{},
+		BackoffInitialInterval: time.Hour,
+	}
+
+	m := &connectingOutput{failConnect: true}
+	ro := NewRunningOutput("test", m, conf, 100, 1000)
+	require.Error(t, ro.Connect())
+
+	m.failConnect = false
+	require.NoError(t, ro.Connect())
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 5)
+}
+
+func TestRunningOutputReconnect(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:               Filter{},
+		StartupErrorBehavior: "retry",
+	}
+
+	m := &connectingOutput{failConnect: true}
+	ro := NewRunningOutput("test", m, conf, 100, 1000)
+	require.Error(t, ro.Connect())
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	err := ro.Write()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "reconnecting")
+	assert.Len(t, m.Metrics(), 0)
+
+	m.failConnect = false
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 5)
+	assert.Equal(t, 3, m.connects)
+}
+
 // Verify that the order of points is preserved during a write failure.
 func TestRunningOutputWriteFailOrder(t *testing.T) {
 	conf := &OutputConfig{
@@ -488,14 +584,17 @@ func TestInternalMetrics(t *testing.T) {
 				"alias":  "test_alias",
 			},
 			map[string]interface{}{
-				"buffer_limit":     10,
-				"buffer_size":      0,
-				"errors":           0,
-				"metrics_added":    0,
-				"metrics_dropped":  0,
-				"metrics_filtered": 0,
-				"metrics_written":  0,
-				"write_time_ns":    0,
+				"buffer_limit":         10,
+				"buffer_size":          0,
+				"circuit_state":        0,
+				"consecutive_failures": 0,
+				"errors":               0,
+				"metrics_added":        0,
+				"metrics_dropped":      0,
+				"metrics_filtered":     0,
+				"metrics_rejected":     0,
+				"metrics_written":      0,
+				"write_time_ns":        0,
 			},
 			time.Unix(0, 0),
 		),
@@ -512,6 +611,92 @@ func TestInternalMetrics(t *testing.T) {
 	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime())
 }
 
+func TestRunningOutputDeadLetter(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	sink := &mockOutput{}
+	dlo := NewRunningOutput("dead_letter", sink, &OutputConfig{}, 10, 100)
+
+	m := &rejectingOutput{reject: "bad"}
+	ro := NewRunningOutput("test", m, conf, 10, 100)
+	ro.MetricsRejected.Set(0)
+	ro.DeadLetter = dlo
+
+	ro.AddMetric(testutil.TestMetric(1, "good"))
+	ro.AddMetric(testutil.TestMetric(2, "bad"))
+	ro.AddMetric(testutil.TestMetric(3, "good"))
+	require.NoError(t, ro.Write())
+	require.NoError(t, dlo.Write())
+
+	require.Len(t, m.Metrics(), 2)
+	require.Equal(t, 0, ro.BufferLength())
+	require.Equal(t, int64(1), ro.MetricsRejected.Get())
+
+	require.Len(t, sink.Metrics(), 1)
+	require.Equal(t, "bad", sink.Metrics()[0].Name())
+	require.Equal(t, int64(2), sink.Metrics()[0].Fields()["value"])
+}
+
+func TestRunningOutputRejectWithoutDeadLetter(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &rejectingOutput{reject: "bad"}
+	ro := NewRunningOutput("test", m, conf, 10, 100)
+
+	ro.AddMetric(testutil.TestMetric(1, "bad"))
+	ro.AddMetric(testutil.TestMetric(2, "bad"))
+	require.NoError(t, ro.Write())
+
+	require.Len(t, m.Metrics(), 0)
+	require.Equal(t, 0, ro.BufferLength())
+}
+
+// rejectingOutput permanently rejects the metrics with the given name.
+type rejectingOutput struct {
+	mockOutput
+	reject string
+}
+
+func (m *rejectingOutput) Write(metrics []telegraf.Metric) error {
+	m.Lock()
+	defer m.Unlock()
+
+	var rejected []int
+	for i, metric := range metrics {
+		if metric.Name() == m.reject {
+			rejected = append(rejected, i)
+			continue
+		}
+		m.metrics = append(m.metrics, metric)
+	}
+	if len(rejected) == 0 {
+		return nil
+	}
+	return &telegraf.PartialWriteError{
+		Err:           errors.New("type conflict"),
+		MetricsReject: rejected,
+	}
+}
+
+// connectingOutput fails to connect until failConnect is unset.
+type connectingOutput struct {
+	mockOutput
+	failConnect bool
+	connects    int
+}
+
+func (m *connectingOutput) Connect() error {
+	m.connects++
+	if m.failConnect {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
 type mockOutput struct {
 	sync.Mutex
 
@@ -1,5 +1,7 @@
 package telegraf
 
+import "fmt"
+
 type Output interface {
 	PluginDescriber
 
@@ -24,3 +26,22 @@ type AggregatingOutput interface {
 	// Reset signals the the aggregator period is completed.
 	Reset()
 }
+
+// PartialWriteError is returned by Output.Write when some metrics of the batch
+// were rejected permanently, for example because of a type conflict on the
+// server, and must not be retried.  All other metrics of the batch are
+// considered written.
+type PartialWriteError struct {
+	// Err is the reason the metrics were rejected.
+	Err error
+	// MetricsReject are the indices of the rejected metrics in the batch.
+	MetricsReject []int
+}
+
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("%d metrics rejected: %v", len(e.MetricsReject), e.Err)
+}
+
+func (e *PartialWriteError) Unwrap() error {
+	return e.Err
+}
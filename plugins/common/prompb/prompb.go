@@ -0,0 +1,234 @@
+// Package prompb implements the messages of the Prometheus remote write
+// protocol used by Telegraf.
+//
+// Only the subset of the WriteRequest defined in prompb/remote.proto and
+// prompb/types.proto of Prometheus needed to send and receive samples is
+// supported, other fields such as the metadata are skipped when decoding.
+package prompb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// WriteRequest is the body of a remote write request, before compression.
+type WriteRequest struct {
+	Timeseries []TimeSeries
+}
+
+// TimeSeries is a series identified by its labels with its samples.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// Label is a name and value pair of a series.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value at a timestamp in milliseconds.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// Field numbers of the messages.
+const (
+	writeRequestTimeseries = 1
+
+	timeSeriesLabels  = 1
+	timeSeriesSamples = 2
+
+	labelName  = 1
+	labelValue = 2
+
+	sampleValue     = 1
+	sampleTimestamp = 2
+)
+
+// Marshal returns the protobuf encoding of the request.
+func (m *WriteRequest) Marshal() ([]byte, error) {
+	var b []byte
+	for i := range m.Timeseries {
+		b = protowire.AppendTag(b, writeRequestTimeseries, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Timeseries[i].marshal(nil))
+	}
+	return b, nil
+}
+
+func (m *TimeSeries) marshal(b []byte) []byte {
+	for i := range m.Labels {
+		b = protowire.AppendTag(b, timeSeriesLabels, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Labels[i].marshal(nil))
+	}
+	for i := range m.Samples {
+		b = protowire.AppendTag(b, timeSeriesSamples, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Samples[i].marshal(nil))
+	}
+	return b
+}
+
+func (m *Label) marshal(b []byte) []byte {
+	// Default values are left out as in the generated code.
+	if m.Name != "" {
+		b = protowire.AppendTag(b, labelName, protowire.BytesType)
+		b = protowire.AppendString(b, m.Name)
+	}
+	if m.Value != "" {
+		b = protowire.AppendTag(b, labelValue, protowire.BytesType)
+		b = protowire.AppendString(b, m.Value)
+	}
+	return b
+}
+
+func (m *Sample) marshal(b []byte) []byte {
+	if m.Value != 0 || math.Signbit(m.Value) {
+		b = protowire.AppendTag(b, sampleValue, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(m.Value))
+	}
+	if m.Timestamp != 0 {
+		b = protowire.AppendTag(b, sampleTimestamp, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.Timestamp))
+	}
+	return b
+}
+
+// Unmarshal decodes the protobuf encoding of a request, appending the series
+// to the request.
+func (m *WriteRequest) Unmarshal(b []byte) error {
+	return walk(b, func(num protowire.Number, typ protowire.Type, v []byte) (int, error) {
+		if num != writeRequestTimeseries {
+			return skip(num, typ, v)
+		}
+		data, n, err := consumeBytes(typ, v)
+		if err != nil {
+			return 0, err
+		}
+		var ts TimeSeries
+		if err := ts.unmarshal(data); err != nil {
+			return 0, err
+		}
+		m.Timeseries = append(m.Timeseries, ts)
+		return n, nil
+	})
+}
+
+func (m *TimeSeries) unmarshal(b []byte) error {
+	return walk(b, func(num protowire.Number, typ protowire.Type, v []byte) (int, error) {
+		switch num {
+		case timeSeriesLabels:
+			data, n, err := consumeBytes(typ, v)
+			if err != nil {
+				return 0, err
+			}
+			var l Label
+			if err := l.unmarshal(data); err != nil {
+				return 0, err
+			}
+			m.Labels = append(m.Labels, l)
+			return n, nil
+		case timeSeriesSamples:
+			data, n, err := consumeBytes(typ, v)
+			if err != nil {
+				return 0, err
+			}
+			var s Sample
+			if err := s.unmarshal(data); err != nil {
+				return 0, err
+			}
+			m.Samples = append(m.Samples, s)
+			return n, nil
+		}
+		return skip(num, typ, v)
+	})
+}
+
+func (m *Label) unmarshal(b []byte) error {
+	return walk(b, func(num protowire.Number, typ protowire.Type, v []byte) (int, error) {
+		switch num {
+		case labelName, labelValue:
+			data, n, err := consumeBytes(typ, v)
+			if err != nil {
+				return 0, err
+			}
+			if num == labelName {
+				m.Name = string(data)
+			} else {
+				m.Value = string(data)
+			}
+			return n, nil
+		}
+		return skip(num, typ, v)
+	})
+}
+
+func (m *Sample) unmarshal(b []byte) error {
+	return walk(b, func(num protowire.Number, typ protowire.Type, v []byte) (int, error) {
+		switch num {
+		case sampleValue:
+			if typ != protowire.Fixed64Type {
+				return 0, fmt.Errorf("invalid wire type %d for field %d", typ, num)
+			}
+			value, n := protowire.ConsumeFixed64(v)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			m.Value = math.Float64frombits(value)
+			return n, nil
+		case sampleTimestamp:
+			if typ != protowire.VarintType {
+				return 0, fmt.Errorf("invalid wire type %d for field %d", typ, num)
+			}
+			value, n := protowire.ConsumeVarint(v)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			m.Timestamp = int64(value)
+			return n, nil
+		}
+		return skip(num, typ, v)
+	})
+}
+
+// walk calls field for every field of the message in b.  The function gets
+// the data following the tag and returns the length of the field value.
+func walk(b []byte, field func(protowire.Number, protowire.Type, []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, err := field(num, typ, b)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func consumeBytes(typ protowire.Type, b []byte) ([]byte, int, error) {
+	if typ != protowire.BytesType {
+		return nil, 0, errors.New("invalid wire type for embedded message")
+	}
+	data, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return nil, 0, protowire.ParseError(n)
+	}
+	return data, n, nil
+}
+
+func skip(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	n := protowire.ConsumeFieldValue(num, typ, b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	return n, nil
+}
@@ -0,0 +1,56 @@
+package prompb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var testRequest = WriteRequest{
+	Timeseries: []TimeSeries{
+		{
+			Labels:  []Label{{Name: "__name__", Value: "up"}},
+			Samples: []Sample{{Value: 1, Timestamp: 1000}},
+		},
+	},
+}
+
+// testEncoding is testRequest as encoded by the generated Prometheus code.
+var testEncoding = []byte{
+	0x0a, 0x1e, // timeseries
+	0x0a, 0x0e, // labels
+	0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_',
+	0x12, 0x02, 'u', 'p',
+	0x12, 0x0c, // samples
+	0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f,
+	0x10, 0xe8, 0x07,
+}
+
+func TestMarshal(t *testing.T) {
+	data, err := testRequest.Marshal()
+	require.NoError(t, err)
+	require.Equal(t, testEncoding, data)
+}
+
+func TestUnmarshal(t *testing.T) {
+	var req WriteRequest
+	require.NoError(t, req.Unmarshal(testEncoding))
+	require.Equal(t, testRequest, req)
+}
+
+func TestUnmarshalSkipsUnknownFields(t *testing.T) {
+	// The metadata of the request is field 3.
+	data := protowire.AppendTag(nil, 3, protowire.BytesType)
+	data = protowire.AppendBytes(data, []byte{0x08, 0x01})
+	data = append(data, testEncoding...)
+
+	var req WriteRequest
+	require.NoError(t, req.Unmarshal(data))
+	require.Equal(t, testRequest, req)
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	var req WriteRequest
+	require.Error(t, req.Unmarshal(testEncoding[:len(testEncoding)-1]))
+}
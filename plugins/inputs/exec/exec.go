@@ -151,17 +151,26 @@ func (e *Exec) ProcessCommand(command string, acc telegraf.Accumulator, wg *sync
 		return
 	}
 
+	if !isNagios {
+		err := parsers.ParseStream(e.parser, bytes.NewReader(out), func(m telegraf.Metric) error {
+			acc.AddMetric(m)
+			return nil
+		})
+		if err != nil {
+			acc.AddError(err)
+		}
+		return
+	}
+
 	metrics, err := e.parser.Parse(out)
 	if err != nil {
 		acc.AddError(err)
 		return
 	}
 
-	if isNagios {
-		metrics, err = nagios.TryAddState(runErr, metrics)
-		if err != nil {
-			e.Log.Errorf("Failed to add nagios state: %s", err)
-		}
+	metrics, err = nagios.TryAddState(runErr, metrics)
+	if err != nil {
+		e.Log.Errorf("Failed to add nagios state: %s", err)
 	}
 
 	for _, m := range metrics {
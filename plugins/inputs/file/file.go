@@ -2,7 +2,6 @@ package file
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 
@@ -70,17 +69,9 @@ func (f *File) Gather(acc telegraf.Accumulator) error {
 		return err
 	}
 	for _, k := range f.filenames {
-		metrics, err := f.readMetric(k)
-		if err != nil {
+		if err := f.readMetric(acc, k); err != nil {
 			return err
 		}
-
-		for _, m := range metrics {
-			if f.FileTag != "" {
-				m.AddTag(f.FileTag, filepath.Base(k))
-			}
-			acc.AddMetric(m)
-		}
 	}
 	return nil
 }
@@ -107,19 +98,23 @@ func (f *File) refreshFilePaths() error {
 	return nil
 }
 
-func (f *File) readMetric(filename string) ([]telegraf.Metric, error) {
+// readMetric parses the file while it is read and adds the metrics to the
+// accumulator.
+func (f *File) readMetric(acc telegraf.Accumulator, filename string) error {
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer file.Close()
 
 	r, _ := utfbom.Skip(f.decoder.Reader(file))
-	fileContents, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, fmt.Errorf("E! Error file: %v could not be read, %s", filename, err)
-	}
-	return f.parser.Parse(fileContents)
+	return parsers.ParseStream(f.parser, r, func(m telegraf.Metric) error {
+		if f.FileTag != "" {
+			m.AddTag(f.FileTag, filepath.Base(filename))
+		}
+		acc.AddMetric(m)
+		return nil
+	})
 }
 
 func init() {
@@ -4,7 +4,7 @@ import (
 	"compress/gzip"
 	"crypto/subtle"
 	"crypto/tls"
-	"io/ioutil"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -209,27 +209,20 @@ func (h *HTTPListenerV2) serveWrite(res http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	var bytes []byte
+	var metrics []telegraf.Metric
 	var ok bool
 
 	switch strings.ToLower(h.DataSource) {
 	case query:
-		bytes, ok = h.collectQuery(res, req)
+		metrics, ok = h.parseQuery(res, req)
 	default:
-		bytes, ok = h.collectBody(res, req)
+		metrics, ok = h.parseBody(res, req)
 	}
 
 	if !ok {
 		return
 	}
 
-	metrics, err := h.Parse(bytes)
-	if err != nil {
-		h.Log.Debugf("Parse error: %s", err.Error())
-		badRequest(res)
-		return
-	}
-
 	for _, m := range metrics {
 		for headerName, measurementName := range h.HTTPHeaderTags {
 			headerValues := req.Header.Get(headerName)
@@ -244,10 +237,15 @@ func (h *HTTPListenerV2) serveWrite(res http.ResponseWriter, req *http.Request)
 	res.WriteHeader(http.StatusNoContent)
 }
 
-func (h *HTTPListenerV2) collectBody(res http.ResponseWriter, req *http.Request) ([]byte, bool) {
+// parseBody parses the request body while it is read.  The metrics are only
+// returned once the whole body is parsed, so that a request is either
+// accepted or rejected as a whole.
+func (h *HTTPListenerV2) parseBody(res http.ResponseWriter, req *http.Request) ([]telegraf.Metric, bool) {
 	body := req.Body
 
-	// Handle gzip request bodies
+	// Handle gzip request bodies.  Snappy encoded bodies, as sent by
+	// Prometheus remote write, are left to the prometheusremotewrite parser
+	// since the compression is part of that data format.
 	if req.Header.Get("Content-Encoding") == "gzip" {
 		var err error
 		body, err = gzip.NewReader(req.Body)
@@ -259,14 +257,39 @@ func (h *HTTPListenerV2) collectBody(res http.ResponseWriter, req *http.Request)
 		defer body.Close()
 	}
 
-	body = http.MaxBytesReader(res, body, h.MaxBodySize.Size)
-	bytes, err := ioutil.ReadAll(body)
-	if err != nil {
+	r := &bodyReader{r: http.MaxBytesReader(res, body, h.MaxBodySize.Size)}
+	metrics := make([]telegraf.Metric, 0)
+	err := parsers.ParseStream(h.Parser, r, func(m telegraf.Metric) error {
+		metrics = append(metrics, m)
+		return nil
+	})
+	if r.err != nil {
 		tooLarge(res)
 		return nil, false
 	}
+	if err != nil {
+		h.Log.Debugf("Parse error: %s", err.Error())
+		badRequest(res)
+		return nil, false
+	}
+
+	return metrics, true
+}
+
+func (h *HTTPListenerV2) parseQuery(res http.ResponseWriter, req *http.Request) ([]telegraf.Metric, bool) {
+	bytes, ok := h.collectQuery(res, req)
+	if !ok {
+		return nil, false
+	}
+
+	metrics, err := h.Parse(bytes)
+	if err != nil {
+		h.Log.Debugf("Parse error: %s", err.Error())
+		badRequest(res)
+		return nil, false
+	}
 
-	return bytes, true
+	return metrics, true
 }
 
 func (h *HTTPListenerV2) collectQuery(res http.ResponseWriter, req *http.Request) ([]byte, bool) {
@@ -282,6 +305,21 @@ func (h *HTTPListenerV2) collectQuery(res http.ResponseWriter, req *http.Request
 	return []byte(query), true
 }
 
+// bodyReader keeps the error of reading the request body, to tell it apart
+// from parse errors.
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
 func tooLarge(res http.ResponseWriter) {
 	res.Header().Set("Content-Type", "application/json")
 	res.WriteHeader(http.StatusRequestEntityTooLarge)
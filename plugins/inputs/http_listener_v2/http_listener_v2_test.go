@@ -15,6 +15,7 @@ import (
 
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -306,6 +307,44 @@ func TestWriteHTTPGzippedData(t *testing.T) {
 	}
 }
 
+func TestWritePrometheusRemoteWrite(t *testing.T) {
+	listener := newTestHTTPListenerV2()
+	parser, err := parsers.NewParser(&parsers.Config{DataFormat: "prometheusremotewrite"})
+	require.NoError(t, err)
+	listener.Parser = parser
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	serializer, err := serializers.NewSerializer(&serializers.Config{DataFormat: "prometheusremotewrite"})
+	require.NoError(t, err)
+	data, err := serializer.Serialize(testutil.MustMetric(
+		"prometheus",
+		map[string]string{"code": "200"},
+		map[string]interface{}{"http_requests_total": 42.0},
+		time.Unix(0, 1422568543702000000),
+	))
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("POST", createURL(listener, "http", "/write", ""), bytes.NewBuffer(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 204, resp.StatusCode)
+
+	acc.Wait(1)
+	acc.AssertContainsTaggedFields(t, "prometheus",
+		map[string]interface{}{"http_requests_total": 42.0},
+		map[string]string{"code": "200"},
+	)
+}
+
 // writes 25,000 metrics to the listener with 10 different writers
 func TestWriteHTTPHighTraffic(t *testing.T) {
 	if runtime.GOOS == "darwin" {
@@ -1,6 +1,7 @@
 package http_response
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -250,7 +251,7 @@ func setError(err error, fields map[string]interface{}, tags map[string]string)
 }
 
 // HTTPGather gathers all fields and returns any errors it encounters
-func (h *HTTPResponse) httpGather(u string) (map[string]interface{}, map[string]string, error) {
+func (h *HTTPResponse) httpGather(ctx context.Context, u string) (map[string]interface{}, map[string]string, error) {
 	// Prepare fields and tags
 	fields := make(map[string]interface{})
 	tags := map[string]string{"server": u, "method": h.Method}
@@ -259,7 +260,7 @@ func (h *HTTPResponse) httpGather(u string) (map[string]interface{}, map[string]
 	if h.Body != "" {
 		body = strings.NewReader(h.Body)
 	}
-	request, err := http.NewRequest(h.Method, u, body)
+	request, err := http.NewRequestWithContext(ctx, h.Method, u, body)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -292,6 +293,11 @@ func (h *HTTPResponse) httpGather(u string) (map[string]interface{}, map[string]
 	// If an error in returned, it means we are dealing with a network error, as
 	// HTTP error codes do not generate errors in the net/http library
 	if err != nil {
+		// A canceled gather is not a result of the server
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
 		// Log error
 		h.Log.Debugf("Network error while polling %s: %s", u, err.Error())
 
@@ -380,6 +386,11 @@ func (h *HTTPResponse) setBodyReadError(error_msg string, bodyBytes []byte, fiel
 
 // Gather gets all metric fields and tags and returns any errors it encounters
 func (h *HTTPResponse) Gather(acc telegraf.Accumulator) error {
+	return h.GatherContext(context.Background(), acc)
+}
+
+// GatherContext is like Gather, stopping once the context is done.
+func (h *HTTPResponse) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
 	// Compile the body regex if it exist
 	if h.compiledStringMatch == nil {
 		var err error
@@ -416,6 +427,10 @@ func (h *HTTPResponse) Gather(acc telegraf.Accumulator) error {
 	}
 
 	for _, u := range h.URLs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
 		addr, err := url.Parse(u)
 		if err != nil {
 			acc.AddError(err)
@@ -432,8 +447,11 @@ func (h *HTTPResponse) Gather(acc telegraf.Accumulator) error {
 		var tags map[string]string
 
 		// Gather data
-		fields, tags, err = h.httpGather(u)
+		fields, tags, err = h.httpGather(ctx, u)
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			acc.AddError(err)
 			continue
 		}
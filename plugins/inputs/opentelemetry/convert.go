@@ -0,0 +1,326 @@
+package opentelemetry
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+const (
+	// Metric schemas, named after the metric_version of the prometheus input
+	// whose layout they follow.
+	schemaPrometheusV1 = "prometheus-v1"
+	schemaPrometheusV2 = "prometheus-v2"
+
+	// Tags added for the instrumentation scope of the metrics.
+	scopeNameTag    = "otel.scope.name"
+	scopeVersionTag = "otel.scope.version"
+
+	// startTimeField holds the start of the aggregation period of sums,
+	// histograms and summaries.
+	startTimeField = "start_time_unix_nano"
+)
+
+// converter turns OTLP export requests into telegraf metrics.
+type converter struct {
+	schema string
+	now    func() time.Time
+}
+
+// convert returns the metrics of the request, and the number of data points
+// that could not be converted.
+func (c *converter) convert(req *collectorpb.ExportMetricsServiceRequest) ([]telegraf.Metric, int64) {
+	var metrics []telegraf.Metric
+	var rejected int64
+	now := c.now()
+
+	for _, rm := range req.GetResourceMetrics() {
+		resourceTags := make(map[string]string)
+		addAttributes(resourceTags, rm.GetResource().GetAttributes())
+
+		for _, sm := range rm.GetScopeMetrics() {
+			tags := make(map[string]string, len(resourceTags)+2)
+			for k, v := range resourceTags {
+				tags[k] = v
+			}
+			if scope := sm.GetScope(); scope != nil {
+				addAttributes(tags, scope.GetAttributes())
+				if scope.GetName() != "" {
+					tags[scopeNameTag] = scope.GetName()
+				}
+				if scope.GetVersion() != "" {
+					tags[scopeVersionTag] = scope.GetVersion()
+				}
+			}
+
+			for _, m := range sm.GetMetrics() {
+				converted, n := c.convertMetric(m, tags, now)
+				metrics = append(metrics, converted...)
+				rejected += n
+			}
+		}
+	}
+
+	return metrics, rejected
+}
+
+func (c *converter) convertMetric(m *metricspb.Metric, scopeTags map[string]string, now time.Time) ([]telegraf.Metric, int64) {
+	var metrics []telegraf.Metric
+	var rejected int64
+
+	add := func(ms []telegraf.Metric, err error) {
+		if err != nil {
+			rejected++
+			return
+		}
+		metrics = append(metrics, ms...)
+	}
+
+	switch data := m.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		for _, dp := range data.Gauge.GetDataPoints() {
+			add(c.number(m.GetName(), "gauge", telegraf.Gauge, dp, scopeTags, now, false))
+		}
+	case *metricspb.Metric_Sum:
+		// Only monotonic sums are counters, the others can go up and down
+		// and are gauges in the prometheus data model.
+		field, vt := "gauge", telegraf.Gauge
+		if data.Sum.GetIsMonotonic() {
+			field, vt = "counter", telegraf.Counter
+		}
+		for _, dp := range data.Sum.GetDataPoints() {
+			add(c.number(m.GetName(), field, vt, dp, scopeTags, now, true))
+		}
+	case *metricspb.Metric_Histogram:
+		for _, dp := range data.Histogram.GetDataPoints() {
+			add(c.histogram(m.GetName(), dp, scopeTags, now))
+		}
+	case *metricspb.Metric_Summary:
+		for _, dp := range data.Summary.GetDataPoints() {
+			add(c.summary(m.GetName(), dp, scopeTags, now))
+		}
+	case *metricspb.Metric_ExponentialHistogram:
+		rejected += int64(len(data.ExponentialHistogram.GetDataPoints()))
+	}
+
+	return metrics, rejected
+}
+
+func (c *converter) number(name, field string, vt telegraf.ValueType, dp *metricspb.NumberDataPoint, scopeTags map[string]string, now time.Time, withStart bool) ([]telegraf.Metric, error) {
+	var value interface{}
+	switch v := dp.GetValue().(type) {
+	case *metricspb.NumberDataPoint_AsDouble:
+		if math.IsNaN(v.AsDouble) {
+			return nil, nil
+		}
+		value = v.AsDouble
+	case *metricspb.NumberDataPoint_AsInt:
+		value = v.AsInt
+	default:
+		return nil, fmt.Errorf("data point of %q has no value", name)
+	}
+
+	measurement := name
+	fields := make(map[string]interface{})
+	if c.schema == schemaPrometheusV2 {
+		measurement = "prometheus"
+		fields[name] = value
+	} else {
+		fields[field] = value
+	}
+	if withStart {
+		addStartTime(fields, dp.GetStartTimeUnixNano())
+	}
+
+	m, err := metric.New(measurement, pointTags(scopeTags, dp.GetAttributes()), fields, timestamp(dp.GetTimeUnixNano(), now), vt)
+	if err != nil {
+		return nil, err
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+func (c *converter) histogram(name string, dp *metricspb.HistogramDataPoint, scopeTags map[string]string, now time.Time) ([]telegraf.Metric, error) {
+	bounds := dp.GetExplicitBounds()
+	counts := dp.GetBucketCounts()
+	if len(counts) > 0 && len(counts) != len(bounds)+1 {
+		return nil, fmt.Errorf("histogram %q has %d buckets for %d bounds", name, len(counts), len(bounds))
+	}
+
+	tags := pointTags(scopeTags, dp.GetAttributes())
+	t := timestamp(dp.GetTimeUnixNano(), now)
+
+	// OTLP has the count of each bucket, prometheus the cumulative count of
+	// all buckets up to the upper bound.
+	buckets := make(map[string]float64, len(counts))
+	var cumulative uint64
+	for i, count := range counts {
+		cumulative += count
+		le := "+Inf"
+		if i < len(bounds) {
+			le = formatFloat(bounds[i])
+		}
+		buckets[le] = float64(cumulative)
+	}
+
+	if c.schema == schemaPrometheusV2 {
+		fields := map[string]interface{}{
+			name + "_count": float64(dp.GetCount()),
+			name + "_sum":   dp.GetSum(),
+		}
+		addStartTime(fields, dp.GetStartTimeUnixNano())
+		return withSeries("prometheus", tags, fields, t, telegraf.Histogram, "le", name+"_bucket", buckets)
+	}
+
+	fields := map[string]interface{}{
+		"count": float64(dp.GetCount()),
+		"sum":   dp.GetSum(),
+	}
+	for le, count := range buckets {
+		fields[le] = count
+	}
+	addStartTime(fields, dp.GetStartTimeUnixNano())
+	m, err := metric.New(name, tags, fields, t, telegraf.Histogram)
+	if err != nil {
+		return nil, err
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+func (c *converter) summary(name string, dp *metricspb.SummaryDataPoint, scopeTags map[string]string, now time.Time) ([]telegraf.Metric, error) {
+	tags := pointTags(scopeTags, dp.GetAttributes())
+	t := timestamp(dp.GetTimeUnixNano(), now)
+
+	quantiles := make(map[string]float64, len(dp.GetQuantileValues()))
+	for _, q := range dp.GetQuantileValues() {
+		if !math.IsNaN(q.GetValue()) {
+			quantiles[formatFloat(q.GetQuantile())] = q.GetValue()
+		}
+	}
+
+	if c.schema == schemaPrometheusV2 {
+		fields := map[string]interface{}{
+			name + "_count": float64(dp.GetCount()),
+			name + "_sum":   dp.GetSum(),
+		}
+		addStartTime(fields, dp.GetStartTimeUnixNano())
+		return withSeries("prometheus", tags, fields, t, telegraf.Summary, "quantile", name, quantiles)
+	}
+
+	fields := map[string]interface{}{
+		"count": float64(dp.GetCount()),
+		"sum":   dp.GetSum(),
+	}
+	for q, value := range quantiles {
+		fields[q] = value
+	}
+	addStartTime(fields, dp.GetStartTimeUnixNano())
+	m, err := metric.New(name, tags, fields, t, telegraf.Summary)
+	if err != nil {
+		return nil, err
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+// withSeries returns the metric with the fields, followed by one metric per
+// series value with the key as tag, like the prometheus input does with
+// metric_version = 2.
+func withSeries(name string, tags map[string]string, fields map[string]interface{}, t time.Time, vt telegraf.ValueType, tagKey, field string, series map[string]float64) ([]telegraf.Metric, error) {
+	m, err := metric.New(name, tags, fields, t, vt)
+	if err != nil {
+		return nil, err
+	}
+	metrics := []telegraf.Metric{m}
+
+	for key, value := range series {
+		seriesTags := make(map[string]string, len(tags)+1)
+		for k, v := range tags {
+			seriesTags[k] = v
+		}
+		seriesTags[tagKey] = key
+
+		m, err := metric.New(name, seriesTags, map[string]interface{}{field: value}, t, vt)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func pointTags(scopeTags map[string]string, attributes []*commonpb.KeyValue) map[string]string {
+	tags := make(map[string]string, len(scopeTags)+len(attributes))
+	for k, v := range scopeTags {
+		tags[k] = v
+	}
+	addAttributes(tags, attributes)
+	return tags
+}
+
+func addAttributes(tags map[string]string, attributes []*commonpb.KeyValue) {
+	for _, kv := range attributes {
+		if kv.GetKey() == "" {
+			continue
+		}
+		tags[kv.GetKey()] = attributeValue(kv.GetValue())
+	}
+}
+
+// attributeValue formats the value of an attribute as tag value, arrays and
+// key/value lists are written in a JSON like notation.
+func attributeValue(v *commonpb.AnyValue) string {
+	switch value := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(value.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(value.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return formatFloat(value.DoubleValue)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(value.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		items := make([]string, 0, len(value.ArrayValue.GetValues()))
+		for _, item := range value.ArrayValue.GetValues() {
+			items = append(items, attributeValue(item))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	case *commonpb.AnyValue_KvlistValue:
+		items := make([]string, 0, len(value.KvlistValue.GetValues()))
+		for _, kv := range value.KvlistValue.GetValues() {
+			items = append(items, kv.GetKey()+":"+attributeValue(kv.GetValue()))
+		}
+		return "{" + strings.Join(items, ",") + "}"
+	default:
+		return ""
+	}
+}
+
+func addStartTime(fields map[string]interface{}, start uint64) {
+	if start > 0 {
+		fields[startTimeField] = int64(start)
+	}
+}
+
+func timestamp(unixNano uint64, now time.Time) time.Time {
+	if unixNano == 0 {
+		return now
+	}
+	return time.Unix(0, int64(unixNano))
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
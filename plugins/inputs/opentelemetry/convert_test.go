@@ -0,0 +1,261 @@
+package opentelemetry
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+var (
+	start = time.Unix(1600000000, 0)
+	ts    = time.Unix(1600000010, 0)
+)
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+// request wraps the metrics into an export request with a resource and an
+// instrumentation scope.
+func request(metrics ...*metricspb.Metric) *collectorpb.ExportMetricsServiceRequest {
+	return &collectorpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{stringAttr("service.name", "checkout")},
+				},
+				ScopeMetrics: []*metricspb.ScopeMetrics{
+					{
+						Scope:   &commonpb.InstrumentationScope{Name: "meter", Version: "1.0"},
+						Metrics: metrics,
+					},
+				},
+			},
+		},
+	}
+}
+
+func testMetrics() []*metricspb.Metric {
+	return []*metricspb.Metric{
+		{
+			Name: "memory",
+			Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+				DataPoints: []*metricspb.NumberDataPoint{
+					{
+						Attributes:   []*commonpb.KeyValue{stringAttr("state", "used")},
+						TimeUnixNano: uint64(ts.UnixNano()),
+						Value:        &metricspb.NumberDataPoint_AsInt{AsInt: 1024},
+					},
+				},
+			}},
+		},
+		{
+			Name: "requests",
+			Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+				IsMonotonic:            true,
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				DataPoints: []*metricspb.NumberDataPoint{
+					{
+						StartTimeUnixNano: uint64(start.UnixNano()),
+						TimeUnixNano:      uint64(ts.UnixNano()),
+						Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: 42},
+					},
+				},
+			}},
+		},
+		{
+			Name: "latency",
+			Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				DataPoints: []*metricspb.HistogramDataPoint{
+					{
+						StartTimeUnixNano: uint64(start.UnixNano()),
+						TimeUnixNano:      uint64(ts.UnixNano()),
+						Count:             6,
+						Sum:               func() *float64 { v := 3.5; return &v }(),
+						ExplicitBounds:    []float64{0.1, 1},
+						BucketCounts:      []uint64{1, 2, 3},
+					},
+				},
+			}},
+		},
+		{
+			Name: "duration",
+			Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+				DataPoints: []*metricspb.SummaryDataPoint{
+					{
+						TimeUnixNano: uint64(ts.UnixNano()),
+						Count:        10,
+						Sum:          20,
+						QuantileValues: []*metricspb.SummaryDataPoint_ValueAtQuantile{
+							{Quantile: 0.5, Value: 1.5},
+							{Quantile: 0.99, Value: math.NaN()},
+						},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func TestConvertPrometheusV1(t *testing.T) {
+	c := &converter{schema: schemaPrometheusV1, now: time.Now}
+	metrics, rejected := c.convert(request(testMetrics()...))
+	require.Zero(t, rejected)
+
+	tags := map[string]string{
+		"service.name":       "checkout",
+		"otel.scope.name":    "meter",
+		"otel.scope.version": "1.0",
+	}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("memory",
+			map[string]string{
+				"service.name":       "checkout",
+				"otel.scope.name":    "meter",
+				"otel.scope.version": "1.0",
+				"state":              "used",
+			},
+			map[string]interface{}{"gauge": int64(1024)},
+			ts,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("requests", tags,
+			map[string]interface{}{"counter": 42.0, "start_time_unix_nano": start.UnixNano()},
+			ts,
+			telegraf.Counter,
+		),
+		testutil.MustMetric("latency", tags,
+			map[string]interface{}{
+				"count":                6.0,
+				"sum":                  3.5,
+				"0.1":                  1.0,
+				"1":                    3.0,
+				"+Inf":                 6.0,
+				"start_time_unix_nano": start.UnixNano(),
+			},
+			ts,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric("duration", tags,
+			map[string]interface{}{"count": 10.0, "sum": 20.0, "0.5": 1.5},
+			ts,
+			telegraf.Summary,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestConvertPrometheusV2(t *testing.T) {
+	c := &converter{schema: schemaPrometheusV2, now: time.Now}
+	metrics, rejected := c.convert(request(testMetrics()[2:]...))
+	require.Zero(t, rejected)
+
+	tags := func(extra ...string) map[string]string {
+		tags := map[string]string{
+			"service.name":       "checkout",
+			"otel.scope.name":    "meter",
+			"otel.scope.version": "1.0",
+		}
+		for i := 0; i < len(extra); i += 2 {
+			tags[extra[i]] = extra[i+1]
+		}
+		return tags
+	}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("prometheus", tags(),
+			map[string]interface{}{"latency_count": 6.0, "latency_sum": 3.5, "start_time_unix_nano": start.UnixNano()},
+			ts, telegraf.Histogram,
+		),
+		testutil.MustMetric("prometheus", tags("le", "0.1"), map[string]interface{}{"latency_bucket": 1.0}, ts, telegraf.Histogram),
+		testutil.MustMetric("prometheus", tags("le", "1"), map[string]interface{}{"latency_bucket": 3.0}, ts, telegraf.Histogram),
+		testutil.MustMetric("prometheus", tags("le", "+Inf"), map[string]interface{}{"latency_bucket": 6.0}, ts, telegraf.Histogram),
+		testutil.MustMetric("prometheus", tags(),
+			map[string]interface{}{"duration_count": 10.0, "duration_sum": 20.0},
+			ts, telegraf.Summary,
+		),
+		testutil.MustMetric("prometheus", tags("quantile", "0.5"), map[string]interface{}{"duration": 1.5}, ts, telegraf.Summary),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics, testutil.SortMetrics())
+}
+
+func TestConvertNonMonotonicSum(t *testing.T) {
+	c := &converter{schema: schemaPrometheusV2, now: func() time.Time { return ts }}
+	metrics, rejected := c.convert(request(&metricspb.Metric{
+		Name: "queue_size",
+		Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints: []*metricspb.NumberDataPoint{
+				{Value: &metricspb.NumberDataPoint_AsInt{AsInt: -3}},
+			},
+		}},
+	}))
+	require.Zero(t, rejected)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("prometheus",
+			map[string]string{
+				"service.name":       "checkout",
+				"otel.scope.name":    "meter",
+				"otel.scope.version": "1.0",
+			},
+			map[string]interface{}{"queue_size": int64(-3)},
+			ts,
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestConvertRejected(t *testing.T) {
+	c := &converter{schema: schemaPrometheusV1, now: time.Now}
+	metrics, rejected := c.convert(request(
+		&metricspb.Metric{
+			Name: "exponential",
+			Data: &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+				DataPoints: []*metricspb.ExponentialHistogramDataPoint{{Count: 1}},
+			}},
+		},
+		&metricspb.Metric{
+			Name: "broken",
+			Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+				DataPoints: []*metricspb.HistogramDataPoint{
+					{ExplicitBounds: []float64{1, 2}, BucketCounts: []uint64{1}},
+				},
+			}},
+		},
+		&metricspb.Metric{
+			Name: "empty",
+			Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+				DataPoints: []*metricspb.NumberDataPoint{{}},
+			}},
+		},
+	))
+	require.Empty(t, metrics)
+	require.Equal(t, int64(3), rejected)
+}
+
+func TestAttributeValue(t *testing.T) {
+	value := &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+		Values: []*commonpb.KeyValue{
+			{Key: "a", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 1}}},
+			{Key: "b", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+				Values: []*commonpb.AnyValue{
+					{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}},
+					{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 0.5}},
+				},
+			}}}},
+		},
+	}}}
+	require.Equal(t, "{a:1,b:[true,0.5]}", attributeValue(value))
+}
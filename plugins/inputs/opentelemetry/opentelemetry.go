@@ -0,0 +1,318 @@
+package opentelemetry
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	// Register the gzip compressor used by most OTLP exporters
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// defaultMaxMsgSize is the default maximum size of an export request, in
+	// bytes, matching the gRPC default.
+	defaultMaxMsgSize = 4 * 1024 * 1024
+
+	metricsPath = "/v1/metrics"
+
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+type OpenTelemetry struct {
+	ServiceAddress     string            `toml:"service_address"`
+	HTTPServiceAddress string            `toml:"http_service_address"`
+	MetricsSchema      string            `toml:"metrics_schema"`
+	MaxMsgSize         internal.Size     `toml:"max_msg_size"`
+	ReadTimeout        internal.Duration `toml:"read_timeout"`
+	WriteTimeout       internal.Duration `toml:"write_timeout"`
+	tlsint.ServerConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	converter *converter
+	acc       telegraf.Accumulator
+
+	grpcServer   *grpc.Server
+	grpcListener net.Listener
+	httpServer   *http.Server
+	httpListener net.Listener
+	wg           sync.WaitGroup
+}
+
+const sampleConfig = `
+  ## Address and port to accept OTLP metrics over gRPC on, empty to disable
+  ## the gRPC endpoint.
+  service_address = ":4317"
+
+  ## Address and port to accept OTLP metrics over HTTP on, empty to disable
+  ## the HTTP endpoint.  Metrics are posted to the /v1/metrics path, encoded
+  ## as protobuf or JSON.
+  http_service_address = ":4318"
+
+  ## Layout of the metrics, following the metric_version of the prometheus
+  ## input:
+  ##   prometheus-v1 -- one measurement per metric name
+  ##   prometheus-v2 -- a single "prometheus" measurement, one field per name
+  # metrics_schema = "prometheus-v1"
+
+  ## Maximum size of an export request.
+  # max_msg_size = "4MiB"
+
+  ## Maximum duration before timing out read of the HTTP request, and write
+  ## of the response.
+  # read_timeout = "10s"
+  # write_timeout = "10s"
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Add service certificate and key
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+`
+
+func (o *OpenTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenTelemetry) Description() string {
+	return "Receive OpenTelemetry metrics over OTLP gRPC and HTTP"
+}
+
+func (o *OpenTelemetry) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (o *OpenTelemetry) Init() error {
+	if o.ServiceAddress == "" && o.HTTPServiceAddress == "" {
+		return fmt.Errorf("at least one of service_address and http_service_address is required")
+	}
+
+	switch o.MetricsSchema {
+	case "":
+		o.MetricsSchema = schemaPrometheusV1
+	case schemaPrometheusV1, schemaPrometheusV2:
+	default:
+		return fmt.Errorf("invalid metrics_schema %q", o.MetricsSchema)
+	}
+
+	if o.MaxMsgSize.Size == 0 {
+		o.MaxMsgSize.Size = defaultMaxMsgSize
+	}
+	if o.ReadTimeout.Duration < time.Second {
+		o.ReadTimeout.Duration = 10 * time.Second
+	}
+	if o.WriteTimeout.Duration < time.Second {
+		o.WriteTimeout.Duration = 10 * time.Second
+	}
+
+	o.converter = &converter{schema: o.MetricsSchema, now: time.Now}
+	return nil
+}
+
+// Start starts the gRPC and HTTP listeners.
+func (o *OpenTelemetry) Start(acc telegraf.Accumulator) error {
+	o.acc = acc
+
+	tlsConfig, err := o.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	if o.ServiceAddress != "" {
+		o.grpcListener, err = net.Listen("tcp", o.ServiceAddress)
+		if err != nil {
+			return err
+		}
+
+		opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(int(o.MaxMsgSize.Size))}
+		if tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+		o.grpcServer = grpc.NewServer(opts...)
+		collectorpb.RegisterMetricsServiceServer(o.grpcServer, &metricsService{plugin: o})
+
+		o.wg.Add(1)
+		go func() {
+			defer o.wg.Done()
+			if err := o.grpcServer.Serve(o.grpcListener); err != nil {
+				o.Log.Errorf("Error serving gRPC on %s: %v", o.ServiceAddress, err)
+			}
+		}()
+		o.Log.Infof("Started OTLP gRPC listener on %s", o.grpcListener.Addr())
+	}
+
+	if o.HTTPServiceAddress != "" {
+		if tlsConfig != nil {
+			o.httpListener, err = tls.Listen("tcp", o.HTTPServiceAddress, tlsConfig)
+		} else {
+			o.httpListener, err = net.Listen("tcp", o.HTTPServiceAddress)
+		}
+		if err != nil {
+			o.stopGRPC()
+			return err
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc(metricsPath, o.handleMetrics)
+		o.httpServer = &http.Server{
+			Handler:      mux,
+			ReadTimeout:  o.ReadTimeout.Duration,
+			WriteTimeout: o.WriteTimeout.Duration,
+		}
+
+		o.wg.Add(1)
+		go func() {
+			defer o.wg.Done()
+			if err := o.httpServer.Serve(o.httpListener); err != http.ErrServerClosed {
+				o.Log.Errorf("Error serving HTTP on %s: %v", o.HTTPServiceAddress, err)
+			}
+		}()
+		o.Log.Infof("Started OTLP HTTP listener on %s", o.httpListener.Addr())
+	}
+
+	return nil
+}
+
+// Stop shuts the listeners down and waits for pending requests.
+func (o *OpenTelemetry) Stop() {
+	o.stopGRPC()
+	if o.httpServer != nil {
+		if err := o.httpServer.Shutdown(context.Background()); err != nil {
+			o.Log.Errorf("Error shutting down HTTP server: %v", err)
+		}
+	}
+	o.wg.Wait()
+}
+
+func (o *OpenTelemetry) stopGRPC() {
+	if o.grpcServer != nil {
+		o.grpcServer.GracefulStop()
+	}
+}
+
+// export adds the metrics of the request to the accumulator.
+func (o *OpenTelemetry) export(req *collectorpb.ExportMetricsServiceRequest) *collectorpb.ExportMetricsServiceResponse {
+	metrics, rejected := o.converter.convert(req)
+	for _, m := range metrics {
+		o.acc.AddMetric(m)
+	}
+
+	resp := &collectorpb.ExportMetricsServiceResponse{}
+	if rejected > 0 {
+		o.Log.Debugf("Rejected %d unsupported or invalid data points", rejected)
+		resp.PartialSuccess = &collectorpb.ExportMetricsPartialSuccess{
+			RejectedDataPoints: rejected,
+			ErrorMessage:       "unsupported or invalid data points",
+		}
+	}
+	return resp
+}
+
+type metricsService struct {
+	collectorpb.UnimplementedMetricsServiceServer
+	plugin *OpenTelemetry
+}
+
+func (s *metricsService) Export(_ context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	return s.plugin.export(req), nil
+}
+
+// handleMetrics implements the OTLP/HTTP protocol for metrics.
+func (o *OpenTelemetry) handleMetrics(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.Header().Set("Allow", http.MethodPost)
+		http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if contentType != contentTypeProtobuf && contentType != contentTypeJSON {
+		http.Error(res, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if req.ContentLength > o.MaxMsgSize.Size {
+		http.Error(res, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(res, req.Body, o.MaxMsgSize.Size)
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		r, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.Close()
+		// Limit the decompressed size as well.
+		body = io.LimitReader(r, o.MaxMsgSize.Size+1)
+	}
+
+	buf, err := ioutil.ReadAll(body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if int64(len(buf)) > o.MaxMsgSize.Size {
+		http.Error(res, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var exportReq collectorpb.ExportMetricsServiceRequest
+	if contentType == contentTypeJSON {
+		err = protojson.Unmarshal(buf, &exportReq)
+	} else {
+		err = proto.Unmarshal(buf, &exportReq)
+	}
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	exportResp := o.export(&exportReq)
+
+	var out []byte
+	if contentType == contentTypeJSON {
+		out, err = protojson.Marshal(exportResp)
+	} else {
+		out, err = proto.Marshal(exportResp)
+	}
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", contentType)
+	res.WriteHeader(http.StatusOK)
+	res.Write(out)
+}
+
+func init() {
+	inputs.Add("opentelemetry", func() telegraf.Input {
+		return &OpenTelemetry{
+			ServiceAddress:     ":4317",
+			HTTPServiceAddress: ":4318",
+			MetricsSchema:      schemaPrometheusV1,
+		}
+	})
+}
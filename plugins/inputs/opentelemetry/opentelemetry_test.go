@@ -0,0 +1,134 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestPlugin(t *testing.T) *OpenTelemetry {
+	plugin := &OpenTelemetry{
+		ServiceAddress:     "localhost:0",
+		HTTPServiceAddress: "localhost:0",
+		Log:                testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	return plugin
+}
+
+func TestInit(t *testing.T) {
+	plugin := &OpenTelemetry{}
+	require.Error(t, plugin.Init())
+
+	plugin = &OpenTelemetry{ServiceAddress: ":4317", MetricsSchema: "unknown"}
+	require.Error(t, plugin.Init())
+}
+
+func TestGRPC(t *testing.T) {
+	plugin := newTestPlugin(t)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	conn, err := grpc.Dial(plugin.grpcListener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := collectorpb.NewMetricsServiceClient(conn).Export(ctx, request(testMetrics()...))
+	require.NoError(t, err)
+	require.Nil(t, resp.GetPartialSuccess())
+
+	acc.Wait(4)
+	expected, _ := plugin.converter.convert(request(testMetrics()...))
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestHTTP(t *testing.T) {
+	protobufBody, err := proto.Marshal(request(testMetrics()...))
+	require.NoError(t, err)
+	jsonBody, err := protojson.Marshal(request(testMetrics()...))
+	require.NoError(t, err)
+	var gzipBody bytes.Buffer
+	w := gzip.NewWriter(&gzipBody)
+	w.Write(protobufBody)
+	w.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+		body        []byte
+	}{
+		{name: "protobuf", contentType: contentTypeProtobuf, body: protobufBody},
+		{name: "json", contentType: contentTypeJSON, body: jsonBody},
+		{name: "gzip", contentType: contentTypeProtobuf, encoding: "gzip", body: gzipBody.Bytes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newTestPlugin(t)
+			acc := &testutil.Accumulator{}
+			require.NoError(t, plugin.Start(acc))
+			defer plugin.Stop()
+
+			req, err := http.NewRequest("POST", "http://"+plugin.httpListener.Addr().String()+metricsPath, bytes.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, tt.contentType, resp.Header.Get("Content-Type"))
+
+			expected, _ := plugin.converter.convert(request(testMetrics()...))
+			testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+		})
+	}
+}
+
+func TestHTTPErrors(t *testing.T) {
+	plugin := newTestPlugin(t)
+	plugin.MaxMsgSize.Size = 16
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	url := "http://" + plugin.httpListener.Addr().String() + metricsPath
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Post(url, "text/plain", bytes.NewReader([]byte("x")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = http.Post(url, contentTypeProtobuf, bytes.NewReader([]byte{0xff, 0xff}))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(url, contentTypeProtobuf, bytes.NewReader(make([]byte, 32)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
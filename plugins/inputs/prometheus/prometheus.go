@@ -211,6 +211,12 @@ func (p *Prometheus) GetAllURLs() (map[string]URLAndAddress, error) {
 // Reads stats from all configured servers accumulates stats.
 // Returns one of the errors encountered while gather stats (if any).
 func (p *Prometheus) Gather(acc telegraf.Accumulator) error {
+	return p.GatherContext(context.Background(), acc)
+}
+
+// GatherContext is like Gather, canceling the requests once the context is
+// done.
+func (p *Prometheus) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
 	if p.client == nil {
 		client, err := p.createHTTPClient()
 		if err != nil {
@@ -229,7 +235,7 @@ func (p *Prometheus) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(serviceURL URLAndAddress) {
 			defer wg.Done()
-			acc.AddError(p.gatherURL(serviceURL, acc))
+			acc.AddError(p.gatherURL(ctx, serviceURL, acc))
 		}(URL)
 	}
 
@@ -255,7 +261,7 @@ func (p *Prometheus) createHTTPClient() (*http.Client, error) {
 	return client, nil
 }
 
-func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error {
+func (p *Prometheus) gatherURL(ctx context.Context, u URLAndAddress, acc telegraf.Accumulator) error {
 	var req *http.Request
 	var err error
 	var uClient *http.Client
@@ -265,7 +271,7 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 		if path == "" {
 			path = "/metrics"
 		}
-		req, err = http.NewRequest("GET", "http://localhost"+path, nil)
+		req, err = http.NewRequestWithContext(ctx, "GET", "http://localhost"+path, nil)
 
 		// ignore error because it's been handled before getting here
 		tlsCfg, _ := p.ClientConfig.TLSConfig()
@@ -284,7 +290,7 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 		if u.URL.Path == "" {
 			u.URL.Path = "/metrics"
 		}
-		req, err = http.NewRequest("GET", u.URL.String(), nil)
+		req, err = http.NewRequestWithContext(ctx, "GET", u.URL.String(), nil)
 	}
 
 	req.Header.Add("Accept", acceptHeader)
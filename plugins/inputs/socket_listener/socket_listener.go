@@ -116,6 +116,11 @@ func (ssl *streamSocketListener) read(c net.Conn) {
 		ssl.Log.Error("Read error: %v", err)
 	}
 
+	if _, ok := ssl.Parser.(parsers.NotLineDelimitedParser); ok {
+		ssl.readStream(c, decoder)
+		return
+	}
+
 	scnr := bufio.NewScanner(decoder)
 	for {
 		if ssl.ReadTimeout != nil && ssl.ReadTimeout.Duration > 0 {
@@ -147,6 +152,32 @@ func (ssl *streamSocketListener) read(c net.Conn) {
 	}
 }
 
+// readStream parses the metrics as they are read from the connection.  The
+// position in the stream is lost on a parse error, so the connection is
+// closed.
+func (ssl *streamSocketListener) readStream(c net.Conn, r io.Reader) {
+	setDeadline := func() {
+		if ssl.ReadTimeout != nil && ssl.ReadTimeout.Duration > 0 {
+			c.SetReadDeadline(time.Now().Add(ssl.ReadTimeout.Duration))
+		}
+	}
+
+	setDeadline()
+	err := parsers.ParseStream(ssl.Parser, r, func(m telegraf.Metric) error {
+		ssl.AddMetric(m)
+		setDeadline()
+		return nil
+	})
+	if err == nil {
+		return
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		ssl.Log.Debugf("Timeout in plugin: %s", err.Error())
+	} else if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+		ssl.Log.Errorf("Unable to parse incoming data: %s", err.Error())
+	}
+}
+
 type packetSocketListener struct {
 	net.PacketConn
 	*SocketListener
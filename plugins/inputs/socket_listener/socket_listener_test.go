@@ -12,7 +12,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/parsers/msgpack"
+	msgpackSerializer "github.com/influxdata/telegraf/plugins/serializers/msgpack"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/assert"
@@ -111,6 +114,49 @@ func TestSocketListener_tcp(t *testing.T) {
 	testSocketListener(t, sl, client)
 }
 
+func TestSocketListenerMsgpack_tcp(t *testing.T) {
+	defer testEmptyLog(t)()
+
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.SetParser(&msgpack.Parser{})
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	client, err := net.Dial("tcp", sl.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+
+	// The value 10 is encoded as a newline byte.
+	expected := []telegraf.Metric{
+		testutil.MustMetric("test",
+			map[string]string{"foo": "bar"},
+			map[string]interface{}{"v": int64(10)},
+			time.Unix(0, 123456789)),
+		testutil.MustMetric("test",
+			map[string]string{"foo": "baz"},
+			map[string]interface{}{"v": int64(2)},
+			time.Unix(0, 123456790)),
+	}
+	s, err := msgpackSerializer.NewSerializer()
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch(expected)
+	require.NoError(t, err)
+
+	// A metric split across writes is read when it is complete.
+	_, err = client.Write(buf[:len(buf)-3])
+	require.NoError(t, err)
+	acc.Wait(1)
+	_, err = client.Write(buf[len(buf)-3:])
+	require.NoError(t, err)
+	acc.Wait(2)
+
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
 func TestSocketListener_udp(t *testing.T) {
 	defer testEmptyLog(t)()
 
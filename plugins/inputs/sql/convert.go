@@ -0,0 +1,140 @@
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// normalize converts a value scanned by a driver into a field value.  Drivers
+// using a text protocol return numbers as bytes, these are converted based on
+// the database type of the column.
+func normalize(value interface{}, dbType string) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return fromText(string(v), dbType)
+	case string:
+		return fromText(v, dbType)
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+func fromText(s, dbType string) interface{} {
+	dbType = strings.ToUpper(dbType)
+	switch {
+	case strings.Contains(dbType, "INT"):
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return v
+		}
+		if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return v
+		}
+	case strings.Contains(dbType, "DECIMAL"),
+		strings.Contains(dbType, "NUMERIC"),
+		strings.Contains(dbType, "FLOAT"),
+		strings.Contains(dbType, "DOUBLE"),
+		strings.Contains(dbType, "REAL"),
+		strings.Contains(dbType, "MONEY"):
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+	}
+	return s
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to float", value)
+}
+
+func toInt(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case float64:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to int", value)
+}
+
+func toUint(value interface{}) (uint64, error) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 {
+			return 0, fmt.Errorf("cannot convert negative value %v to uint", v)
+		}
+		return uint64(v), nil
+	case int64:
+		if v < 0 {
+			return 0, fmt.Errorf("cannot convert negative value %v to uint", v)
+		}
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseUint(v, 10, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to uint", value)
+}
+
+func toBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case float64:
+		return v != 0, nil
+	case int64:
+		return v != 0, nil
+	case uint64:
+		return v != 0, nil
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, fmt.Errorf("cannot convert %T to bool", value)
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%v", value)
+}
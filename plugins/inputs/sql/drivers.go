@@ -0,0 +1,18 @@
+package sql
+
+import (
+	// Blank imports to register the database/sql drivers
+	_ "github.com/denisenkom/go-mssqldb"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/stdlib"
+)
+
+// driverNames maps the driver option to the name the driver is registered
+// with in database/sql.
+var driverNames = map[string]string{
+	"postgres":  "pgx",
+	"pgx":       "pgx",
+	"mysql":     "mysql",
+	"sqlserver": "sqlserver",
+	"mssql":     "sqlserver",
+}
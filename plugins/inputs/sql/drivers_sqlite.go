@@ -0,0 +1,14 @@
+// +build linux,amd64 linux,386 linux,arm linux,arm64 linux,s390x darwin,amd64 darwin,arm64 freebsd,amd64 freebsd,386 netbsd,amd64 openbsd,amd64 windows,amd64 windows,386
+
+package sql
+
+import (
+	// Blank import to register the sqlite driver, it is only available on
+	// the platforms supported by the pure Go port of sqlite.
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	driverNames["sqlite"] = "sqlite"
+	driverNames["sqlite3"] = "sqlite"
+}
@@ -0,0 +1,150 @@
+package sql
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *SQL
+		err    string
+	}{
+		{
+			name:   "unknown driver",
+			plugin: &SQL{Driver: "oracle", Dsn: "x", Queries: []Query{{Query: "SELECT 1"}}},
+			err:    `unknown driver "oracle"`,
+		},
+		{
+			name:   "missing dsn",
+			plugin: &SQL{Driver: "mysql", Queries: []Query{{Query: "SELECT 1"}}},
+			err:    "dsn is required",
+		},
+		{
+			name:   "missing queries",
+			plugin: &SQL{Driver: "mysql", Dsn: "x"},
+			err:    "at least one query is required",
+		},
+		{
+			name:   "missing query",
+			plugin: &SQL{Driver: "mysql", Dsn: "x", Queries: []Query{{}}},
+			err:    "query 1: either query or query_script is required",
+		},
+		{
+			name: "invalid filter",
+			plugin: &SQL{Driver: "mysql", Dsn: "x", Queries: []Query{
+				{Query: "SELECT 1", FieldColumnsInt: []string{"a[b"}},
+			}},
+			err: "query 1: compiling type conversion",
+		},
+		{
+			name:   "zero timeout",
+			plugin: &SQL{Driver: "mysql", Dsn: "x", Queries: []Query{{Query: "SELECT 1"}}},
+			err:    "timeout must be positive",
+		},
+		{
+			name: "negative timeout",
+			plugin: &SQL{Driver: "mysql", Dsn: "x", Queries: []Query{{Query: "SELECT 1"}},
+				Timeout: internal.Duration{Duration: -time.Second}},
+			err: "timeout must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.plugin.Init()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestInitQueryScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sql")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "query.sql")
+	require.NoError(t, ioutil.WriteFile(script, []byte("SELECT 42 AS answer"), 0644))
+
+	plugin := &SQL{
+		Driver:  "postgres",
+		Dsn:     "x",
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+		Queries: []Query{{Script: script}},
+	}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "pgx", plugin.driverName)
+	require.Equal(t, "SELECT 42 AS answer", plugin.Queries[0].Query)
+	require.Equal(t, "sql", plugin.Queries[0].Measurement)
+}
+
+func TestQueryDue(t *testing.T) {
+	q := &Query{Interval: internal.Duration{Duration: time.Minute}}
+	start := time.Date(2020, 1, 1, 12, 0, 10, 0, time.UTC)
+
+	require.True(t, q.due(start))
+	require.False(t, q.due(start.Add(10*time.Second)))
+	require.False(t, q.due(start.Add(49*time.Second)))
+	require.True(t, q.due(start.Add(50*time.Second)))
+	require.False(t, q.due(start.Add(60*time.Second)))
+
+	always := &Query{}
+	require.True(t, always.due(start))
+	require.True(t, always.due(start))
+}
+
+func TestNormalize(t *testing.T) {
+	require.Equal(t, int64(42), normalize([]byte("42"), "BIGINT"))
+	require.Equal(t, uint64(18446744073709551615), normalize([]byte("18446744073709551615"), "UNSIGNED BIGINT"))
+	require.Equal(t, 4.2, normalize([]byte("4.2"), "DECIMAL"))
+	require.Equal(t, "4.2", normalize([]byte("4.2"), "VARCHAR"))
+	require.Equal(t, "n/a", normalize([]byte("n/a"), "NUMERIC"))
+	require.Equal(t, int64(42), normalize(int32(42), "INT4"))
+	require.Equal(t, float64(0.5), normalize(float32(0.5), "FLOAT4"))
+}
+
+func TestConvert(t *testing.T) {
+	q := &Query{
+		Query:              "SELECT 1",
+		FieldColumnsFloat:  []string{"float"},
+		FieldColumnsInt:    []string{"int"},
+		FieldColumnsUint:   []string{"uint"},
+		FieldColumnsBool:   []string{"bool_*"},
+		FieldColumnsString: []string{"string"},
+	}
+	require.NoError(t, q.init())
+
+	tests := []struct {
+		column   string
+		value    interface{}
+		expected interface{}
+	}{
+		{"float", int64(42), 42.0},
+		{"float", "4.2", 4.2},
+		{"int", 42.9, int64(42)},
+		{"int", "-42", int64(-42)},
+		{"uint", int64(42), uint64(42)},
+		{"bool_int", int64(1), true},
+		{"bool_string", "false", false},
+		{"string", int64(42), "42"},
+		{"other", int64(42), int64(42)},
+		{"other", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), "2020-01-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		actual, err := q.convert(tt.column, tt.value)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, actual, tt.column)
+	}
+
+	_, err := q.convert("uint", int64(-1))
+	require.Error(t, err)
+	_, err = q.convert("int", "abc")
+	require.Error(t, err)
+}
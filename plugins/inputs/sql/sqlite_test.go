@@ -0,0 +1,140 @@
+// +build linux,amd64 linux,386 linux,arm linux,arm64 linux,s390x darwin,amd64 darwin,arm64 freebsd,amd64 freebsd,386 netbsd,amd64 openbsd,amd64 windows,amd64 windows,386
+
+package sql
+
+import (
+	dbsql "database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const sqliteSchema = `
+CREATE TABLE orders (
+	region TEXT,
+	status TEXT,
+	total REAL,
+	items INTEGER,
+	paid INTEGER,
+	created_at INTEGER
+);
+INSERT INTO orders VALUES ('eu', 'open', 10.5, 2, 0, 1577836800);
+INSERT INTO orders VALUES ('us', 'closed', 20, 3, 1, 1577836860);
+INSERT INTO orders VALUES ('us', NULL, NULL, 1, 1, 1577836920);
+`
+
+func newSQLite(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "sql")
+	require.NoError(t, err)
+	dsn := "file:" + filepath.Join(dir, "test.db")
+
+	db, err := dbsql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec(sqliteSchema)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	return dsn, func() { os.RemoveAll(dir) }
+}
+
+func TestSQLite(t *testing.T) {
+	dsn, cleanup := newSQLite(t)
+	defer cleanup()
+
+	plugin := &SQL{
+		Driver:  "sqlite",
+		Dsn:     dsn,
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+		Queries: []Query{
+			{
+				Query:              "SELECT region, status, total, items, paid, created_at FROM orders ORDER BY created_at",
+				Measurement:        "orders",
+				TagColumnsInclude:  []string{"region", "status"},
+				TimeColumn:         "created_at",
+				FieldColumnsUint:   []string{"items"},
+				FieldColumnsBool:   []string{"paid"},
+				FieldColumnsString: []string{"total"},
+			},
+			{
+				Query:             "SELECT 'orders_' || region AS name, count(*) AS count FROM orders GROUP BY region ORDER BY region",
+				MeasurementColumn: "name",
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	plugin.now = func() time.Time { return time.Unix(42, 0) }
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"orders",
+			map[string]string{"region": "eu", "status": "open"},
+			map[string]interface{}{"total": "10.5", "items": uint64(2), "paid": false},
+			time.Unix(1577836800, 0),
+		),
+		testutil.MustMetric(
+			"orders",
+			map[string]string{"region": "us", "status": "closed"},
+			map[string]interface{}{"total": "20", "items": uint64(3), "paid": true},
+			time.Unix(1577836860, 0),
+		),
+		testutil.MustMetric(
+			"orders",
+			map[string]string{"region": "us"},
+			map[string]interface{}{"items": uint64(1), "paid": true},
+			time.Unix(1577836920, 0),
+		),
+		testutil.MustMetric(
+			"orders_eu",
+			map[string]string{},
+			map[string]interface{}{"count": int64(1)},
+			time.Unix(42, 0),
+		),
+		testutil.MustMetric(
+			"orders_us",
+			map[string]string{},
+			map[string]interface{}{"count": int64(2)},
+			time.Unix(42, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestSQLiteQueryError(t *testing.T) {
+	dsn, cleanup := newSQLite(t)
+	defer cleanup()
+
+	plugin := &SQL{
+		Driver:  "sqlite",
+		Dsn:     dsn,
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+		Queries: []Query{
+			{Query: "SELECT * FROM missing", Measurement: "missing"},
+			{Query: "SELECT count(*) AS count FROM orders", Measurement: "orders"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	require.NoError(t, plugin.Gather(&acc))
+
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), `executing query "missing"`)
+	require.True(t, acc.HasMeasurement("orders"))
+}
@@ -178,6 +178,10 @@ func (q *AMQP) SetSerializer(serializer serializers.Serializer) {
 	q.serializer = serializer
 }
 
+func (q *AMQP) UsesBatchFormat() bool {
+	return q.UseBatchFormat
+}
+
 func (q *AMQP) Connect() error {
 	if q.config == nil {
 		config, err := q.makeClientConfig()
@@ -100,6 +100,10 @@ func (ps *PubSub) SetSerializer(serializer serializers.Serializer) {
 	ps.serializer = serializer
 }
 
+func (ps *PubSub) UsesBatchFormat() bool {
+	return ps.SendBatched
+}
+
 func (ps *PubSub) Connect() error {
 	if ps.Topic == "" {
 		return fmt.Errorf(`"topic" is required`)
@@ -307,10 +307,30 @@ func (a *Elasticsearch) Write(metrics []telegraf.Metric) error {
 	}
 
 	if res.Errors {
-		for id, err := range res.Failed() {
-			log.Printf("E! Elasticsearch indexing failure, id: %d, error: %s, caused by: %s, %s", id, err.Error.Reason, err.Error.CausedBy["reason"], err.Error.CausedBy["type"])
+		// Documents refused by Elasticsearch, for example because of a
+		// mapping conflict, will never be indexed and are rejected instead
+		// of retrying the whole batch.
+		var rejected []int
+		for id, item := range res.Items {
+			for _, err := range item {
+				if err.Status >= 200 && err.Status <= 299 {
+					continue
+				}
+				log.Printf("E! Elasticsearch indexing failure, id: %d, error: %s, caused by: %s, %s", id, err.Error.Reason, err.Error.CausedBy["reason"], err.Error.CausedBy["type"])
+				if err.Status >= 400 && err.Status <= 499 && err.Status != http.StatusTooManyRequests {
+					rejected = append(rejected, id)
+				}
+			}
+		}
+
+		failed := len(res.Failed())
+		if failed > len(rejected) {
+			return fmt.Errorf("W! Elasticsearch failed to index %d metrics", failed)
+		}
+		return &telegraf.PartialWriteError{
+			Err:           fmt.Errorf("Elasticsearch refused to index %d metrics", len(rejected)),
+			MetricsReject: rejected,
 		}
-		return fmt.Errorf("W! Elasticsearch failed to index %d metrics", len(res.Failed()))
 	}
 
 	return nil
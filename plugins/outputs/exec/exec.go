@@ -44,6 +44,10 @@ func (e *Exec) SetSerializer(serializer serializers.Serializer) {
 	e.serializer = serializer
 }
 
+func (e *Exec) UsesBatchFormat() bool {
+	return true
+}
+
 // Connect satisfies the Output interface.
 func (e *Exec) Connect() error {
 	return nil
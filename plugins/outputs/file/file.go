@@ -57,6 +57,10 @@ func (f *File) SetSerializer(serializer serializers.Serializer) {
 	f.serializer = serializer
 }
 
+func (f *File) UsesBatchFormat() bool {
+	return f.UseBatchFormat
+}
+
 func (f *File) Connect() error {
 	writers := []io.Writer{}
 
@@ -64,9 +68,18 @@ func (f *File) Connect() error {
 		f.Files = []string{"stdout"}
 	}
 
+	// The header of the data format is written at the start of each file,
+	// including the files started by a rotation.
+	hs, withHeader := f.serializer.(serializers.HeaderSerializer)
+	if withHeader {
+		hs.OmitHeader()
+	}
+
 	for _, file := range f.Files {
+		var w io.Writer
 		if file == "stdout" {
-			writers = append(writers, os.Stdout)
+			w = os.Stdout
+			file = ""
 		} else {
 			of, err := rotate.NewFileWriter(
 				file, f.RotationInterval.Duration, f.RotationMaxSize.Size, f.RotationMaxArchives)
@@ -74,9 +87,14 @@ func (f *File) Connect() error {
 				return err
 			}
 
-			writers = append(writers, of)
+			w = of
 			f.closers = append(f.closers, of)
 		}
+
+		if withHeader {
+			w = &headerWriter{w: w, filename: file, header: hs.Header}
+		}
+		writers = append(writers, w)
 	}
 	f.writer = io.MultiWriter(writers...)
 	return nil
@@ -131,6 +149,34 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 	return writeErr
 }
 
+// headerWriter writes the header before the data written to a new file.  A
+// file is new while it is empty, so the header is written again once the
+// file is rotated.  Stdout gets the header once.
+type headerWriter struct {
+	w        io.Writer
+	filename string
+	header   func() []byte
+	written  bool
+}
+
+func (h *headerWriter) Write(p []byte) (int, error) {
+	if header := h.header(); len(header) > 0 && h.isNew() {
+		if _, err := h.w.Write(header); err != nil {
+			return 0, err
+		}
+		h.written = true
+	}
+	return h.w.Write(p)
+}
+
+func (h *headerWriter) isNew() bool {
+	if h.filename == "" {
+		return !h.written
+	}
+	info, err := os.Stat(h.filename)
+	return err == nil && info.Size() == 0
+}
+
 func init() {
 	outputs.Add("file", func() telegraf.Output {
 		return &File{}
@@ -5,9 +5,11 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/serializers"
@@ -180,6 +182,62 @@ func TestFileStdout(t *testing.T) {
 	assert.Equal(t, expNewFile, out)
 }
 
+func TestFileHeaderRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "metrics.csv")
+
+	s, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat: "csv",
+		CSVHeader:  true,
+	})
+	require.NoError(t, err)
+	f := File{
+		Files:               []string{filename},
+		RotationMaxSize:     internal.Size{Size: 60},
+		RotationMaxArchives: -1,
+		serializer:          s,
+	}
+	require.NoError(t, f.Connect())
+	defer f.Close()
+
+	// The file is rotated after the second write, so the third write starts
+	// a new file.
+	for i := 0; i < 3; i++ {
+		require.NoError(t, f.Write(testutil.MockMetrics()))
+	}
+
+	header := "timestamp,name,tag1,value\n"
+	row := "1257894000,test1,value1,1\n"
+	files, err := filepath.Glob(filepath.Join(dir, "metrics.*.csv"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	validateFile(files[0], header+row+row, t)
+	validateFile(filename, header+row, t)
+}
+
+func TestFileHeaderExistingFile(t *testing.T) {
+	fh := createFile()
+	defer os.Remove(fh.Name())
+
+	s, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat: "csv",
+		CSVHeader:  true,
+	})
+	require.NoError(t, err)
+	f := File{
+		Files:      []string{fh.Name()},
+		serializer: s,
+	}
+	require.NoError(t, f.Connect())
+
+	// The header is only written to empty files.
+	require.NoError(t, f.Write(testutil.MockMetrics()))
+	require.NoError(t, f.Close())
+	validateFile(fh.Name(), "cpu,cpu=cpu0 value=100 1455312810012459582\n1257894000,test1,value1,1\n", t)
+}
+
 func createFile() *os.File {
 	f, err := ioutil.TempFile("", "")
 	if err != nil {
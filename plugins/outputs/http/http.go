@@ -94,6 +94,10 @@ func (h *HTTP) SetSerializer(serializer serializers.Serializer) {
 	h.serializer = serializer
 }
 
+func (h *HTTP) UsesBatchFormat() bool {
+	return true
+}
+
 func (h *HTTP) createClient(ctx context.Context) (*http.Client, error) {
 	tlsCfg, err := h.ClientConfig.TLSConfig()
 	if err != nil {
@@ -263,7 +263,8 @@ func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error
 	}
 
 	batches := make(map[dbrp][]telegraf.Metric)
-	for _, metric := range metrics {
+	indices := make(map[dbrp][]int)
+	for i, metric := range metrics {
 		db, ok := metric.GetTag(c.config.DatabaseTag)
 		if !ok {
 			db = c.config.Database
@@ -292,8 +293,13 @@ func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error
 		}
 
 		batches[dbrp] = append(batches[dbrp], metric)
+		indices[dbrp] = append(indices[dbrp], i)
 	}
 
+	// The indices of the rejected metrics are translated from the batches
+	// back to the original batch.
+	var rejected *telegraf.PartialWriteError
+
 	for dbrp, batch := range batches {
 		if !c.config.SkipDatabaseCreation && !c.createDatabaseExecuted[dbrp.Database] {
 			err := c.CreateDatabase(ctx, dbrp.Database)
@@ -304,10 +310,22 @@ func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error
 		}
 
 		err := c.writeBatch(ctx, dbrp.Database, dbrp.RetentionPolicy, batch)
+		if perr, ok := err.(*telegraf.PartialWriteError); ok {
+			if rejected == nil {
+				rejected = &telegraf.PartialWriteError{Err: perr.Err}
+			}
+			for _, i := range perr.MetricsReject {
+				rejected.MetricsReject = append(rejected.MetricsReject, indices[dbrp][i])
+			}
+			continue
+		}
 		if err != nil {
 			return err
 		}
 	}
+	if rejected != nil {
+		return rejected
+	}
 	return nil
 }
 
@@ -375,20 +393,19 @@ func (c *httpClient) writeBatch(ctx context.Context, db, rp string, metrics []te
 	}
 
 	// Other partial write errors, such as "field type conflict", are not
-	// correctable at this point and so the point is dropped instead of
-	// retrying.
-	if strings.Contains(desc, errStringPartialWrite) {
-		c.log.Errorf("When writing to [%s]: received error %v; discarding points",
-			c.URL(), desc)
-		return nil
-	}
-
-	// This error indicates a bug in either Telegraf line protocol
-	// serialization, retries would not be successful.
-	if strings.Contains(desc, errStringUnableToParse) {
-		c.log.Errorf("When writing to [%s]: received error %v; discarding points",
-			c.URL(), desc)
-		return nil
+	// correctable at this point, and parse errors indicate a bug in either
+	// Telegraf line protocol serialization, so retries would not be
+	// successful.  The response does not tell which points were dropped, so
+	// all metrics of the request are rejected instead of retrying.
+	if strings.Contains(desc, errStringPartialWrite) || strings.Contains(desc, errStringUnableToParse) {
+		rejected := make([]int, len(metrics))
+		for i := range rejected {
+			rejected[i] = i
+		}
+		return &telegraf.PartialWriteError{
+			Err:           fmt.Errorf("received error %v", desc),
+			MetricsReject: rejected,
+		}
 	}
 
 	return &APIError{
@@ -381,7 +381,7 @@ func TestHTTP_Write(t *testing.T) {
 			},
 		},
 		{
-			name: "partial write errors are logged no error",
+			name: "partial write errors reject metrics",
 			config: influxdb.HTTPConfig{
 				URL:      u,
 				Database: "telegraf",
@@ -391,12 +391,15 @@ func TestHTTP_Write(t *testing.T) {
 				w.WriteHeader(http.StatusBadRequest)
 				w.Write([]byte(`{"error": "partial write: field type conflict:"}`))
 			},
-			logFunc: func(t *testing.T, str string) {
-				require.Contains(t, str, "partial write")
+			errFunc: func(t *testing.T, err error) {
+				perr, ok := err.(*telegraf.PartialWriteError)
+				require.True(t, ok)
+				require.Equal(t, []int{0}, perr.MetricsReject)
+				require.Contains(t, perr.Err.Error(), "field type conflict")
 			},
 		},
 		{
-			name: "parse errors are logged no error",
+			name: "parse errors reject metrics",
 			config: influxdb.HTTPConfig{
 				URL:      u,
 				Database: "telegraf",
@@ -406,8 +409,11 @@ func TestHTTP_Write(t *testing.T) {
 				w.WriteHeader(http.StatusBadRequest)
 				w.Write([]byte(`{"error": "unable to parse 'cpu value': invalid field format"}`))
 			},
-			logFunc: func(t *testing.T, str string) {
-				require.Contains(t, str, "unable to parse")
+			errFunc: func(t *testing.T, err error) {
+				perr, ok := err.(*telegraf.PartialWriteError)
+				require.True(t, ok)
+				require.Equal(t, []int{0}, perr.MetricsReject)
+				require.Contains(t, perr.Err.Error(), "unable to parse")
 			},
 		},
 		{
@@ -732,6 +738,73 @@ func TestHTTP_WriteDatabaseTagWorksOnRetry(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestHTTP_WriteDatabaseTagPartialWrite(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/write":
+			if r.FormValue("db") == "bad" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "partial write: field type conflict:"}`))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	addr := &url.URL{
+		Scheme: "http",
+		Host:   ts.Listener.Addr().String(),
+	}
+
+	config := influxdb.HTTPConfig{
+		URL:                  addr,
+		Database:             "telegraf",
+		DatabaseTag:          "database",
+		SkipDatabaseCreation: true,
+		Log:                  testutil.Logger{},
+	}
+
+	client, err := influxdb.NewHTTPClient(config)
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"database": "good"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"database": "bad"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"database": "good"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"database": "bad"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+
+	err = client.Write(context.Background(), metrics)
+	require.Error(t, err)
+	perr, ok := err.(*telegraf.PartialWriteError)
+	require.True(t, ok)
+	require.Equal(t, []int{1, 3}, perr.MetricsReject)
+	require.Contains(t, perr.Err.Error(), "field type conflict")
+}
+
 func TestDBRPTags(t *testing.T) {
 	ts := httptest.NewServer(http.NotFoundHandler())
 	defer ts.Close()
@@ -219,6 +219,11 @@ func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
 			return nil
 		}
 
+		// Rejected metrics would be rejected by the other servers as well.
+		if _, ok := err.(*telegraf.PartialWriteError); ok {
+			return err
+		}
+
 		switch apiError := err.(type) {
 		case *DatabaseNotFoundError:
 			if !i.SkipDatabaseCreation {
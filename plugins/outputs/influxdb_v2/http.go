@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
@@ -173,13 +172,14 @@ func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error
 	}
 
 	batches := make(map[string][]telegraf.Metric)
+	indices := make(map[string][]int)
 	if c.BucketTag == "" {
 		err := c.writeBatch(ctx, c.Bucket, metrics)
 		if err != nil {
 			return err
 		}
 	} else {
-		for _, metric := range metrics {
+		for i, metric := range metrics {
 			bucket, ok := metric.GetTag(c.BucketTag)
 			if !ok {
 				bucket = c.Bucket
@@ -197,14 +197,30 @@ func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error
 			}
 
 			batches[bucket] = append(batches[bucket], metric)
+			indices[bucket] = append(indices[bucket], i)
 		}
 
+		// The indices of the rejected metrics are translated from the bucket
+		// batches back to the original batch.
+		var rejected *telegraf.PartialWriteError
 		for bucket, batch := range batches {
 			err := c.writeBatch(ctx, bucket, batch)
+			if perr, ok := err.(*telegraf.PartialWriteError); ok {
+				if rejected == nil {
+					rejected = &telegraf.PartialWriteError{Err: perr.Err}
+				}
+				for _, i := range perr.MetricsReject {
+					rejected.MetricsReject = append(rejected.MetricsReject, indices[bucket][i])
+				}
+				continue
+			}
 			if err != nil {
 				return err
 			}
 		}
+		if rejected != nil {
+			return rejected
+		}
 	}
 	return nil
 }
@@ -246,8 +262,16 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []te
 
 	switch resp.StatusCode {
 	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
-		log.Printf("E! [outputs.influxdb_v2] Failed to write metric: %s\n", desc)
-		return nil
+		// The request will never be accepted, so all of its metrics are
+		// rejected instead of retrying.
+		rejected := make([]int, len(metrics))
+		for i := range rejected {
+			rejected[i] = i
+		}
+		return &telegraf.PartialWriteError{
+			Err:           fmt.Errorf("failed to write metric: %s", desc),
+			MetricsReject: rejected,
+		}
 	case http.StatusUnauthorized, http.StatusForbidden:
 		return fmt.Errorf("failed to write metric: %s", desc)
 	case http.StatusTooManyRequests:
@@ -111,3 +111,73 @@ func TestWriteBucketTagWorksOnRetry(t *testing.T) {
 	err = client.Write(ctx, metrics)
 	require.NoError(t, err)
 }
+
+func TestWriteBadRequestRejectsMetrics(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v2/write":
+				r.ParseForm()
+				if r.Form.Get("bucket") == "bad" {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"code":"invalid","message":"field type conflict"}`))
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		}),
+	)
+	defer ts.Close()
+
+	addr := &url.URL{
+		Scheme: "http",
+		Host:   ts.Listener.Addr().String(),
+	}
+
+	config := &influxdb.HTTPConfig{
+		URL:       addr,
+		Bucket:    "telegraf",
+		BucketTag: "bucket",
+	}
+
+	client, err := influxdb.NewHTTPClient(config)
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"bucket": "good"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"bucket": "bad"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"bucket": "good"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"bucket": "bad"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+
+	err = client.Write(context.Background(), metrics)
+	require.Error(t, err)
+	perr, ok := err.(*telegraf.PartialWriteError)
+	require.True(t, ok)
+	require.Equal(t, []int{1, 3}, perr.MetricsReject)
+	require.Contains(t, perr.Err.Error(), "field type conflict")
+}
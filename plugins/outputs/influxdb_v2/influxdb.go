@@ -165,6 +165,11 @@ func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
 			return nil
 		}
 
+		// Rejected metrics would be rejected by the other servers as well.
+		if _, ok := err.(*telegraf.PartialWriteError); ok {
+			return err
+		}
+
 		log.Printf("E! [outputs.influxdb_v2] when writing to [%s]: %v", client.URL(), err)
 	}
 
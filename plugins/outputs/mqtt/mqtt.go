@@ -107,6 +107,10 @@ func (m *MQTT) SetSerializer(serializer serializers.Serializer) {
 	m.serializer = serializer
 }
 
+func (m *MQTT) UsesBatchFormat() bool {
+	return m.BatchMessage
+}
+
 func (m *MQTT) Close() error {
 	if m.client.IsConnected() {
 		m.client.Disconnect(20)
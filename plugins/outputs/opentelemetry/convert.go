@@ -0,0 +1,527 @@
+package opentelemetry
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+const (
+	// Metric schemas, matching the ones of the opentelemetry input.
+	schemaPrometheusV1 = "prometheus-v1"
+	schemaPrometheusV2 = "prometheus-v2"
+
+	scopeNameTag    = "otel.scope.name"
+	scopeVersionTag = "otel.scope.version"
+	startTimeField  = "start_time_unix_nano"
+
+	defaultScopeName = "telegraf"
+)
+
+// pointKey identifies a data point, the fields of a histogram or summary can
+// be spread over several telegraf metrics.
+type pointKey struct {
+	resource   string
+	scope      string
+	name       string
+	valueType  telegraf.ValueType
+	attributes string
+	time       int64
+}
+
+type point struct {
+	key        pointKey
+	resource   []*commonpb.KeyValue
+	scope      *commonpb.InstrumentationScope
+	attributes []*commonpb.KeyValue
+	start      uint64
+
+	value     *metricspb.NumberDataPoint
+	count     uint64
+	sum       float64
+	buckets   map[float64]uint64
+	quantiles map[float64]float64
+}
+
+// converter builds an OTLP export request from telegraf metrics.
+type converter struct {
+	schema       string
+	resourceTags map[string]bool
+	attributes   []*commonpb.KeyValue
+
+	points map[pointKey]*point
+	order  []*point
+}
+
+func newConverter(schema string, resourceTags []string, attributes map[string]string) *converter {
+	c := &converter{
+		schema:       schema,
+		resourceTags: make(map[string]bool, len(resourceTags)),
+	}
+	for _, tag := range resourceTags {
+		c.resourceTags[tag] = true
+	}
+	for k, v := range attributes {
+		c.attributes = append(c.attributes, stringAttribute(k, v))
+	}
+	sortAttributes(c.attributes)
+	return c
+}
+
+func (c *converter) reset() {
+	c.points = make(map[pointKey]*point)
+	c.order = nil
+}
+
+// add adds the fields of the metric to the data points, it returns false if
+// none of the fields could be converted.
+func (c *converter) add(m telegraf.Metric) bool {
+	resource := append([]*commonpb.KeyValue{}, c.attributes...)
+	scope := &commonpb.InstrumentationScope{Name: defaultScopeName}
+	var attributes []*commonpb.KeyValue
+	for _, tag := range m.TagList() {
+		switch {
+		case tag.Key == scopeNameTag:
+			scope.Name = tag.Value
+		case tag.Key == scopeVersionTag:
+			scope.Version = tag.Value
+		case c.resourceTags[tag.Key]:
+			resource = setAttribute(resource, tag.Key, tag.Value)
+		case m.Type() == telegraf.Histogram && tag.Key == "le":
+		case m.Type() == telegraf.Summary && tag.Key == "quantile":
+		default:
+			attributes = append(attributes, stringAttribute(tag.Key, tag.Value))
+		}
+	}
+	sortAttributes(resource)
+
+	base := pointKey{
+		resource:   attributesKey(resource),
+		scope:      scope.Name + "\x00" + scope.Version,
+		valueType:  m.Type(),
+		attributes: attributesKey(attributes),
+		time:       m.Time().UnixNano(),
+	}
+
+	var start uint64
+	if v, ok := m.GetField(startTimeField); ok {
+		if n, ok := toUint(v); ok {
+			start = n
+		}
+	}
+
+	get := func(name string) *point {
+		key := base
+		key.name = name
+		p, ok := c.points[key]
+		if !ok {
+			p = &point{
+				key:        key,
+				resource:   resource,
+				scope:      scope,
+				attributes: attributes,
+			}
+			c.points[key] = p
+			c.order = append(c.order, p)
+		}
+		if start > 0 {
+			p.start = start
+		}
+		return p
+	}
+
+	added := false
+	for _, field := range m.FieldList() {
+		if field.Key == startTimeField {
+			continue
+		}
+
+		switch m.Type() {
+		case telegraf.Histogram:
+			added = c.addHistogramField(m, field, get) || added
+		case telegraf.Summary:
+			added = c.addSummaryField(m, field, get) || added
+		default:
+			dp := numberDataPoint(field.Value)
+			if dp == nil {
+				continue
+			}
+			get(c.numberName(m.Name(), field.Key)).value = dp
+			added = true
+		}
+	}
+	return added
+}
+
+func (c *converter) numberName(measurement, field string) string {
+	if c.schema == schemaPrometheusV2 {
+		if measurement == "prometheus" {
+			return field
+		}
+		return measurement + "_" + field
+	}
+
+	switch field {
+	case "gauge", "counter", "value":
+		return measurement
+	}
+	return measurement + "_" + field
+}
+
+// seriesName returns the name of a histogram or summary, and the field name
+// without the suffix.
+func (c *converter) seriesName(measurement, field string) (string, string) {
+	if c.schema == schemaPrometheusV1 {
+		return measurement, field
+	}
+
+	suffix := ""
+	for _, s := range []string{"_bucket", "_count", "_sum"} {
+		if strings.HasSuffix(field, s) {
+			suffix = s
+			field = strings.TrimSuffix(field, s)
+			break
+		}
+	}
+	if measurement == "prometheus" {
+		return field, suffix
+	}
+	return measurement + "_" + field, suffix
+}
+
+func (c *converter) addHistogramField(m telegraf.Metric, field *telegraf.Field, get func(string) *point) bool {
+	name, kind := c.seriesName(m.Name(), field.Key)
+
+	switch kind {
+	case "count", "_count":
+		count, ok := toUint(field.Value)
+		if !ok {
+			return false
+		}
+		get(name).count = count
+	case "sum", "_sum":
+		sum, ok := toFloat(field.Value)
+		if !ok {
+			return false
+		}
+		get(name).sum = sum
+	default:
+		bound := kind
+		if c.schema == schemaPrometheusV2 {
+			if kind != "_bucket" {
+				return false
+			}
+			bound, _ = m.GetTag("le")
+		}
+		le, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return false
+		}
+		count, ok := toUint(field.Value)
+		if !ok {
+			return false
+		}
+		p := get(name)
+		if p.buckets == nil {
+			p.buckets = make(map[float64]uint64)
+		}
+		p.buckets[le] = count
+	}
+	return true
+}
+
+func (c *converter) addSummaryField(m telegraf.Metric, field *telegraf.Field, get func(string) *point) bool {
+	name, kind := c.seriesName(m.Name(), field.Key)
+
+	switch kind {
+	case "count", "_count":
+		count, ok := toUint(field.Value)
+		if !ok {
+			return false
+		}
+		get(name).count = count
+	case "sum", "_sum":
+		sum, ok := toFloat(field.Value)
+		if !ok {
+			return false
+		}
+		get(name).sum = sum
+	default:
+		quantile := kind
+		if c.schema == schemaPrometheusV2 {
+			if kind != "" {
+				return false
+			}
+			quantile, _ = m.GetTag("quantile")
+		}
+		q, err := strconv.ParseFloat(quantile, 64)
+		if err != nil {
+			return false
+		}
+		value, ok := toFloat(field.Value)
+		if !ok {
+			return false
+		}
+		p := get(name)
+		if p.quantiles == nil {
+			p.quantiles = make(map[float64]float64)
+		}
+		p.quantiles[q] = value
+	}
+	return true
+}
+
+// request returns the export request for the data points, batched by
+// resource and instrumentation scope.
+func (c *converter) request() *collectorpb.ExportMetricsServiceRequest {
+	req := &collectorpb.ExportMetricsServiceRequest{}
+	resources := make(map[string]*metricspb.ResourceMetrics)
+	scopes := make(map[string]*metricspb.ScopeMetrics)
+	metrics := make(map[pointKey]*metricspb.Metric)
+
+	for _, p := range c.order {
+		rm, ok := resources[p.key.resource]
+		if !ok {
+			rm = &metricspb.ResourceMetrics{
+				Resource: &resourcepb.Resource{Attributes: p.resource},
+			}
+			resources[p.key.resource] = rm
+			req.ResourceMetrics = append(req.ResourceMetrics, rm)
+		}
+
+		scopeKey := p.key.resource + "\x00" + p.key.scope
+		sm, ok := scopes[scopeKey]
+		if !ok {
+			sm = &metricspb.ScopeMetrics{Scope: p.scope}
+			scopes[scopeKey] = sm
+			rm.ScopeMetrics = append(rm.ScopeMetrics, sm)
+		}
+
+		metricKey := pointKey{
+			resource:  p.key.resource,
+			scope:     p.key.scope,
+			name:      p.key.name,
+			valueType: p.key.valueType,
+		}
+		metric, ok := metrics[metricKey]
+		if !ok {
+			metric = newMetric(p.key.name, p.key.valueType)
+			metrics[metricKey] = metric
+			sm.Metrics = append(sm.Metrics, metric)
+		}
+
+		p.addTo(metric)
+	}
+
+	return req
+}
+
+func newMetric(name string, vt telegraf.ValueType) *metricspb.Metric {
+	m := &metricspb.Metric{Name: name}
+	cumulative := metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	switch vt {
+	case telegraf.Counter:
+		m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			AggregationTemporality: cumulative,
+			IsMonotonic:            true,
+		}}
+	case telegraf.Histogram:
+		m.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			AggregationTemporality: cumulative,
+		}}
+	case telegraf.Summary:
+		m.Data = &metricspb.Metric_Summary{Summary: &metricspb.Summary{}}
+	default:
+		m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}
+	}
+	return m
+}
+
+func (p *point) addTo(m *metricspb.Metric) {
+	switch data := m.Data.(type) {
+	case *metricspb.Metric_Gauge:
+		dp := p.numberDataPoint()
+		data.Gauge.DataPoints = append(data.Gauge.DataPoints, dp)
+	case *metricspb.Metric_Sum:
+		dp := p.numberDataPoint()
+		dp.StartTimeUnixNano = p.start
+		data.Sum.DataPoints = append(data.Sum.DataPoints, dp)
+	case *metricspb.Metric_Histogram:
+		data.Histogram.DataPoints = append(data.Histogram.DataPoints, p.histogramDataPoint())
+	case *metricspb.Metric_Summary:
+		data.Summary.DataPoints = append(data.Summary.DataPoints, p.summaryDataPoint())
+	}
+}
+
+func (p *point) numberDataPoint() *metricspb.NumberDataPoint {
+	dp := p.value
+	dp.Attributes = p.attributes
+	dp.TimeUnixNano = uint64(p.key.time)
+	return dp
+}
+
+// histogramDataPoint converts the cumulative bucket counts of the prometheus
+// layout into the counts per bucket used by OTLP.
+func (p *point) histogramDataPoint() *metricspb.HistogramDataPoint {
+	sum := p.sum
+	dp := &metricspb.HistogramDataPoint{
+		Attributes:        p.attributes,
+		StartTimeUnixNano: p.start,
+		TimeUnixNano:      uint64(p.key.time),
+		Count:             p.count,
+		Sum:               &sum,
+	}
+	if len(p.buckets) == 0 {
+		return dp
+	}
+
+	bounds := make([]float64, 0, len(p.buckets))
+	for bound := range p.buckets {
+		if !math.IsInf(bound, 1) {
+			bounds = append(bounds, bound)
+		}
+	}
+	sort.Float64s(bounds)
+
+	var previous uint64
+	for _, bound := range bounds {
+		cumulative := p.buckets[bound]
+		if cumulative < previous {
+			cumulative = previous
+		}
+		dp.ExplicitBounds = append(dp.ExplicitBounds, bound)
+		dp.BucketCounts = append(dp.BucketCounts, cumulative-previous)
+		previous = cumulative
+	}
+
+	// The overflow bucket, its cumulative count is the total count.
+	total := p.count
+	if inf, ok := p.buckets[math.Inf(1)]; ok && inf > total {
+		total = inf
+	}
+	if total < previous {
+		total = previous
+	}
+	dp.BucketCounts = append(dp.BucketCounts, total-previous)
+	return dp
+}
+
+func (p *point) summaryDataPoint() *metricspb.SummaryDataPoint {
+	dp := &metricspb.SummaryDataPoint{
+		Attributes:        p.attributes,
+		StartTimeUnixNano: p.start,
+		TimeUnixNano:      uint64(p.key.time),
+		Count:             p.count,
+		Sum:               p.sum,
+	}
+
+	quantiles := make([]float64, 0, len(p.quantiles))
+	for q := range p.quantiles {
+		quantiles = append(quantiles, q)
+	}
+	sort.Float64s(quantiles)
+	for _, q := range quantiles {
+		dp.QuantileValues = append(dp.QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{
+			Quantile: q,
+			Value:    p.quantiles[q],
+		})
+	}
+	return dp
+}
+
+func numberDataPoint(value interface{}) *metricspb.NumberDataPoint {
+	switch v := value.(type) {
+	case int64:
+		return &metricspb.NumberDataPoint{Value: &metricspb.NumberDataPoint_AsInt{AsInt: v}}
+	case uint64:
+		if v > math.MaxInt64 {
+			return &metricspb.NumberDataPoint{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(v)}}
+		}
+		return &metricspb.NumberDataPoint{Value: &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}}
+	case float64:
+		return &metricspb.NumberDataPoint{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: v}}
+	case bool:
+		var n int64
+		if v {
+			n = 1
+		}
+		return &metricspb.NumberDataPoint{Value: &metricspb.NumberDataPoint_AsInt{AsInt: n}}
+	default:
+		return nil
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func toUint(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 {
+			return 0, false
+		}
+		return uint64(v), true
+	case int64:
+		if v < 0 {
+			return 0, false
+		}
+		return uint64(v), true
+	case uint64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+// setAttribute sets the attribute, replacing an existing one with the key.
+func setAttribute(attributes []*commonpb.KeyValue, key, value string) []*commonpb.KeyValue {
+	for i, kv := range attributes {
+		if kv.Key == key {
+			attributes[i] = stringAttribute(key, value)
+			return attributes
+		}
+	}
+	return append(attributes, stringAttribute(key, value))
+}
+
+func sortAttributes(attributes []*commonpb.KeyValue) {
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].Key < attributes[j].Key
+	})
+}
+
+// attributesKey returns a key for sorted string attributes.
+func attributesKey(attributes []*commonpb.KeyValue) string {
+	var b strings.Builder
+	for _, kv := range attributes {
+		b.WriteString(kv.Key)
+		b.WriteByte(0)
+		b.WriteString(kv.Value.GetStringValue())
+		b.WriteByte(0)
+	}
+	return b.String()
+}
@@ -0,0 +1,244 @@
+package opentelemetry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+var ts = time.Unix(1600000010, 0)
+
+func convert(c *converter, metrics ...telegraf.Metric) *collectorpb.ExportMetricsServiceRequest {
+	c.reset()
+	for _, m := range metrics {
+		c.add(m)
+	}
+	return c.request()
+}
+
+func requireProtoEqual(t *testing.T, expected, actual proto.Message) {
+	require.True(t, proto.Equal(expected, actual),
+		"expected:\n%s\nactual:\n%s", prototext.Format(expected), prototext.Format(actual))
+}
+
+func scope(metrics ...*metricspb.Metric) *metricspb.ScopeMetrics {
+	return &metricspb.ScopeMetrics{
+		Scope:   &commonpb.InstrumentationScope{Name: defaultScopeName},
+		Metrics: metrics,
+	}
+}
+
+func resource(attributes []*commonpb.KeyValue, scopes ...*metricspb.ScopeMetrics) *metricspb.ResourceMetrics {
+	return &metricspb.ResourceMetrics{
+		Resource:     &resourcepb.Resource{Attributes: attributes},
+		ScopeMetrics: scopes,
+	}
+}
+
+func TestConvertGaugeAndSum(t *testing.T) {
+	c := newConverter(schemaPrometheusV1, []string{"host"}, map[string]string{"service.name": "telegraf"})
+	req := convert(c,
+		testutil.MustMetric("mem",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"free": 2.5, "text": "ignored"},
+			ts,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("mem",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"gauge": int64(10)},
+			ts,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("requests",
+			map[string]string{"host": "b", "method": "GET"},
+			map[string]interface{}{"counter": uint64(7), "start_time_unix_nano": int64(1000)},
+			ts,
+			telegraf.Counter,
+		),
+	)
+
+	hostA := []*commonpb.KeyValue{stringAttribute("host", "a"), stringAttribute("service.name", "telegraf")}
+	hostB := []*commonpb.KeyValue{stringAttribute("host", "b"), stringAttribute("service.name", "telegraf")}
+	expected := &collectorpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			resource(hostA, scope(
+				&metricspb.Metric{
+					Name: "mem_free",
+					Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+						DataPoints: []*metricspb.NumberDataPoint{
+							{TimeUnixNano: uint64(ts.UnixNano()), Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 2.5}},
+						},
+					}},
+				},
+				&metricspb.Metric{
+					Name: "mem",
+					Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+						DataPoints: []*metricspb.NumberDataPoint{
+							{TimeUnixNano: uint64(ts.UnixNano()), Value: &metricspb.NumberDataPoint_AsInt{AsInt: 10}},
+						},
+					}},
+				},
+			)),
+			resource(hostB, scope(
+				&metricspb.Metric{
+					Name: "requests",
+					Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+						AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+						IsMonotonic:            true,
+						DataPoints: []*metricspb.NumberDataPoint{
+							{
+								Attributes:        []*commonpb.KeyValue{stringAttribute("method", "GET")},
+								StartTimeUnixNano: 1000,
+								TimeUnixNano:      uint64(ts.UnixNano()),
+								Value:             &metricspb.NumberDataPoint_AsInt{AsInt: 7},
+							},
+						},
+					}},
+				},
+			)),
+		},
+	}
+	requireProtoEqual(t, expected, req)
+}
+
+func TestConvertBatchesResources(t *testing.T) {
+	c := newConverter(schemaPrometheusV1, []string{"host"}, nil)
+	req := convert(c,
+		testutil.MustMetric("cpu", map[string]string{"host": "a", "cpu": "0"}, map[string]interface{}{"value": 1.0}, ts),
+		testutil.MustMetric("cpu", map[string]string{"host": "b", "cpu": "0"}, map[string]interface{}{"value": 2.0}, ts),
+		testutil.MustMetric("cpu", map[string]string{"host": "a", "cpu": "1"}, map[string]interface{}{"value": 3.0}, ts),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", "otel.scope.name": "meter", "otel.scope.version": "2"},
+			map[string]interface{}{"value": 4.0},
+			ts,
+		),
+	)
+
+	require.Len(t, req.ResourceMetrics, 2)
+	require.Len(t, req.ResourceMetrics[0].ScopeMetrics, 2)
+	require.Len(t, req.ResourceMetrics[0].ScopeMetrics[0].Metrics, 1)
+	require.Len(t, req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].GetGauge().DataPoints, 2)
+	require.Equal(t, "meter", req.ResourceMetrics[0].ScopeMetrics[1].Scope.Name)
+	require.Equal(t, "2", req.ResourceMetrics[0].ScopeMetrics[1].Scope.Version)
+	require.Len(t, req.ResourceMetrics[1].ScopeMetrics[0].Metrics[0].GetGauge().DataPoints, 1)
+}
+
+func expectedHistogram() *collectorpb.ExportMetricsServiceRequest {
+	sum := 3.5
+	return &collectorpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			resource(nil, scope(
+				&metricspb.Metric{
+					Name: "latency",
+					Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+						AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+						DataPoints: []*metricspb.HistogramDataPoint{
+							{
+								Attributes:     []*commonpb.KeyValue{stringAttribute("path", "/")},
+								TimeUnixNano:   uint64(ts.UnixNano()),
+								Count:          6,
+								Sum:            &sum,
+								ExplicitBounds: []float64{0.1, 1},
+								BucketCounts:   []uint64{1, 2, 3},
+							},
+						},
+					}},
+				},
+			)),
+		},
+	}
+}
+
+func TestConvertHistogramV1(t *testing.T) {
+	c := newConverter(schemaPrometheusV1, nil, nil)
+	req := convert(c,
+		testutil.MustMetric("latency",
+			map[string]string{"path": "/"},
+			map[string]interface{}{"count": 6.0, "sum": 3.5, "0.1": 1.0, "1": 3.0, "+Inf": 6.0},
+			ts,
+			telegraf.Histogram,
+		),
+	)
+	requireProtoEqual(t, expectedHistogram(), req)
+}
+
+func TestConvertHistogramV2(t *testing.T) {
+	c := newConverter(schemaPrometheusV2, nil, nil)
+	req := convert(c,
+		testutil.MustMetric("prometheus",
+			map[string]string{"path": "/"},
+			map[string]interface{}{"latency_count": 6.0, "latency_sum": 3.5},
+			ts, telegraf.Histogram,
+		),
+		testutil.MustMetric("prometheus",
+			map[string]string{"path": "/", "le": "1"},
+			map[string]interface{}{"latency_bucket": 3.0},
+			ts, telegraf.Histogram,
+		),
+		testutil.MustMetric("prometheus",
+			map[string]string{"path": "/", "le": "0.1"},
+			map[string]interface{}{"latency_bucket": 1.0},
+			ts, telegraf.Histogram,
+		),
+		testutil.MustMetric("prometheus",
+			map[string]string{"path": "/", "le": "+Inf"},
+			map[string]interface{}{"latency_bucket": 6.0},
+			ts, telegraf.Histogram,
+		),
+	)
+	requireProtoEqual(t, expectedHistogram(), req)
+}
+
+func TestConvertSummaryV2(t *testing.T) {
+	c := newConverter(schemaPrometheusV2, nil, nil)
+	req := convert(c,
+		testutil.MustMetric("prometheus", nil,
+			map[string]interface{}{"duration_count": 10.0, "duration_sum": 20.0},
+			ts, telegraf.Summary,
+		),
+		testutil.MustMetric("prometheus",
+			map[string]string{"quantile": "0.99"},
+			map[string]interface{}{"duration": 4.0},
+			ts, telegraf.Summary,
+		),
+		testutil.MustMetric("prometheus",
+			map[string]string{"quantile": "0.5"},
+			map[string]interface{}{"duration": 1.5},
+			ts, telegraf.Summary,
+		),
+	)
+
+	expected := &collectorpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			resource(nil, scope(
+				&metricspb.Metric{
+					Name: "duration",
+					Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+						DataPoints: []*metricspb.SummaryDataPoint{
+							{
+								TimeUnixNano: uint64(ts.UnixNano()),
+								Count:        10,
+								Sum:          20,
+								QuantileValues: []*metricspb.SummaryDataPoint_ValueAtQuantile{
+									{Quantile: 0.5, Value: 1.5},
+									{Quantile: 0.99, Value: 4},
+								},
+							},
+						},
+					}},
+				},
+			)),
+		},
+	}
+	requireProtoEqual(t, expected, req)
+}
@@ -0,0 +1,252 @@
+package opentelemetry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultServiceAddress = "localhost:4317"
+	defaultTimeout        = 5 * time.Second
+	defaultMaxRetries     = 3
+	defaultRetryInterval  = time.Second
+)
+
+var sampleConfig = `
+  ## Address and port of the OTLP gRPC endpoint, for example an
+  ## OpenTelemetry collector.
+  # service_address = "localhost:4317"
+
+  ## Timeout of a write, including the retries of its export requests.
+  # timeout = "5s"
+
+  ## Compression of the export requests, "gzip" or "none".
+  # compression = "gzip"
+
+  ## Layout of the metrics, matching the metrics_schema of the opentelemetry
+  ## input and the metric_version of the prometheus input:
+  ##   prometheus-v1 -- one measurement per metric name
+  ##   prometheus-v2 -- a single "prometheus" measurement, one field per name
+  # metrics_schema = "prometheus-v1"
+
+  ## Tags sent as resource attributes instead of data point attributes.
+  ## Metrics are batched by the values of these tags.
+  # resource_tags = ["host"]
+
+  ## Retries of export requests failing with a retryable status, the
+  ## interval doubles with each retry.  Retries stop when the timeout of the
+  ## write is reached.
+  # max_retries = 3
+  # retry_interval = "1s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Additional resource attributes
+  # [outputs.opentelemetry.attributes]
+  #   "service.name" = "telegraf"
+
+  ## Additional gRPC request metadata
+  # [outputs.opentelemetry.headers]
+  #   key1 = "value1"
+`
+
+type OpenTelemetry struct {
+	ServiceAddress string            `toml:"service_address"`
+	Timeout        internal.Duration `toml:"timeout"`
+	Compression    string            `toml:"compression"`
+	MetricsSchema  string            `toml:"metrics_schema"`
+	ResourceTags   []string          `toml:"resource_tags"`
+	Attributes     map[string]string `toml:"attributes"`
+	Headers        map[string]string `toml:"headers"`
+	MaxRetries     int               `toml:"max_retries"`
+	RetryInterval  internal.Duration `toml:"retry_interval"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	converter   *converter
+	ctx         context.Context
+	cancel      context.CancelFunc
+	conn        *grpc.ClientConn
+	client      collectorpb.MetricsServiceClient
+	callOptions []grpc.CallOption
+}
+
+func (o *OpenTelemetry) Description() string {
+	return "Send metrics to an OpenTelemetry collector over OTLP gRPC"
+}
+
+func (o *OpenTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenTelemetry) Init() error {
+	if o.ServiceAddress == "" {
+		o.ServiceAddress = defaultServiceAddress
+	}
+	if o.Timeout.Duration <= 0 {
+		o.Timeout.Duration = defaultTimeout
+	}
+	if o.RetryInterval.Duration <= 0 {
+		o.RetryInterval.Duration = defaultRetryInterval
+	}
+	if o.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+
+	switch o.Compression {
+	case "", "none":
+	case "gzip":
+		o.callOptions = append(o.callOptions, grpc.UseCompressor(gzip.Name))
+	default:
+		return fmt.Errorf("invalid compression %q", o.Compression)
+	}
+
+	switch o.MetricsSchema {
+	case "":
+		o.MetricsSchema = schemaPrometheusV1
+	case schemaPrometheusV1, schemaPrometheusV2:
+	default:
+		return fmt.Errorf("invalid metrics_schema %q", o.MetricsSchema)
+	}
+
+	o.converter = newConverter(o.MetricsSchema, o.ResourceTags, o.Attributes)
+	return nil
+}
+
+func (o *OpenTelemetry) Connect() error {
+	tlsConfig, err := o.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	var opts []grpc.DialOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	// The connection is established in the background, and re-established
+	// whenever it breaks.
+	o.conn, err = grpc.Dial(o.ServiceAddress, opts...)
+	if err != nil {
+		return fmt.Errorf("connecting to %q: %v", o.ServiceAddress, err)
+	}
+	o.ctx, o.cancel = context.WithCancel(context.Background())
+	o.client = collectorpb.NewMetricsServiceClient(o.conn)
+	return nil
+}
+
+func (o *OpenTelemetry) Close() error {
+	if o.conn == nil {
+		return nil
+	}
+	o.cancel()
+	err := o.conn.Close()
+	o.conn = nil
+	return err
+}
+
+func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
+	o.converter.reset()
+	for _, m := range metrics {
+		if !o.converter.add(m) {
+			o.Log.Debugf("Dropping metric %q without numeric fields", m.Name())
+		}
+	}
+
+	req := o.converter.request()
+	if len(req.ResourceMetrics) == 0 {
+		return nil
+	}
+
+	// The timeout covers the retries, so that a write does not hold up the
+	// flush of the agent, the output retries the metrics later.
+	ctx, cancel := context.WithTimeout(o.ctx, o.Timeout.Duration)
+	defer cancel()
+
+	interval := o.RetryInterval.Duration
+	for retry := 0; ; retry++ {
+		err := o.export(ctx, req)
+		if err == nil {
+			return nil
+		}
+		if retry >= o.MaxRetries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		o.Log.Debugf("Retrying export in %s: %v", interval, err)
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		interval *= 2
+	}
+}
+
+func (o *OpenTelemetry) export(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) error {
+	if len(o.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.Headers))
+	}
+
+	resp, err := o.client.Export(ctx, req, o.callOptions...)
+	if err != nil {
+		return err
+	}
+
+	if partial := resp.GetPartialSuccess(); partial != nil && partial.GetRejectedDataPoints() > 0 {
+		o.Log.Warnf("%d data points were rejected: %s", partial.GetRejectedDataPoints(), partial.GetErrorMessage())
+	}
+	return nil
+}
+
+// retryable returns true for the status codes OTLP clients should retry on.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Canceled,
+		codes.DeadlineExceeded,
+		codes.ResourceExhausted,
+		codes.Aborted,
+		codes.OutOfRange,
+		codes.Unavailable,
+		codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+func init() {
+	outputs.Add("opentelemetry", func() telegraf.Output {
+		return &OpenTelemetry{
+			ServiceAddress: defaultServiceAddress,
+			Timeout:        internal.Duration{Duration: defaultTimeout},
+			Compression:    "gzip",
+			MetricsSchema:  schemaPrometheusV1,
+			MaxRetries:     defaultMaxRetries,
+			RetryInterval:  internal.Duration{Duration: defaultRetryInterval},
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package opentelemetry
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is an in-process OTLP metrics server recording the requests.
+type collector struct {
+	collectorpb.UnimplementedMetricsServiceServer
+
+	sync.Mutex
+	requests []*collectorpb.ExportMetricsServiceRequest
+	metadata []metadata.MD
+	// errors are returned, in order, before accepting requests.
+	errors []error
+
+	listener net.Listener
+	server   *grpc.Server
+}
+
+func newCollector(t *testing.T) *collector {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	c := &collector{listener: listener, server: grpc.NewServer()}
+	collectorpb.RegisterMetricsServiceServer(c.server, c)
+	go c.server.Serve(listener)
+	return c
+}
+
+func (c *collector) Export(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.metadata = append(c.metadata, md)
+	if len(c.errors) > 0 {
+		err := c.errors[0]
+		c.errors = c.errors[1:]
+		return nil, err
+	}
+	c.requests = append(c.requests, req)
+	return &collectorpb.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *collector) stop() {
+	c.server.Stop()
+}
+
+func newTestOutput(t *testing.T, address string) *OpenTelemetry {
+	o := &OpenTelemetry{
+		ServiceAddress: address,
+		Compression:    "gzip",
+		MaxRetries:     2,
+		RetryInterval:  internal.Duration{Duration: time.Millisecond},
+		ResourceTags:   []string{"host"},
+		Headers:        map[string]string{"authorization": "secret"},
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+	require.NoError(t, o.Connect())
+	return o
+}
+
+func TestWrite(t *testing.T) {
+	c := newCollector(t)
+	defer c.stop()
+
+	o := newTestOutput(t, c.listener.Addr().String())
+	defer o.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 99.5},
+			time.Unix(10, 0),
+			telegraf.Gauge,
+		),
+	}
+	require.NoError(t, o.Write(metrics))
+
+	c.Lock()
+	defer c.Unlock()
+	require.Len(t, c.requests, 1)
+	expected := newConverter(schemaPrometheusV1, []string{"host"}, nil)
+	expected.reset()
+	expected.add(metrics[0])
+	require.True(t, proto.Equal(expected.request(), c.requests[0]))
+	require.Equal(t, []string{"secret"}, c.metadata[0].Get("authorization"))
+}
+
+func TestWriteRetry(t *testing.T) {
+	c := newCollector(t)
+	defer c.stop()
+	c.errors = []error{
+		status.Error(codes.Unavailable, "busy"),
+		status.Error(codes.ResourceExhausted, "busy"),
+	}
+
+	o := newTestOutput(t, c.listener.Addr().String())
+	defer o.Close()
+
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(10, 0))
+	require.NoError(t, o.Write([]telegraf.Metric{m}))
+
+	c.Lock()
+	defer c.Unlock()
+	require.Len(t, c.metadata, 3)
+	require.Len(t, c.requests, 1)
+}
+
+func TestWriteRetriesExhausted(t *testing.T) {
+	c := newCollector(t)
+	defer c.stop()
+	for i := 0; i < 3; i++ {
+		c.errors = append(c.errors, status.Error(codes.Unavailable, "busy"))
+	}
+
+	o := newTestOutput(t, c.listener.Addr().String())
+	defer o.Close()
+
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(10, 0))
+	require.Error(t, o.Write([]telegraf.Metric{m}))
+
+	c.Lock()
+	defer c.Unlock()
+	require.Len(t, c.metadata, 3)
+	require.Empty(t, c.requests)
+}
+
+func TestWriteRetriesTimeout(t *testing.T) {
+	c := newCollector(t)
+	defer c.stop()
+	for i := 0; i < 3; i++ {
+		c.errors = append(c.errors, status.Error(codes.Unavailable, "busy"))
+	}
+
+	o := newTestOutput(t, c.listener.Addr().String())
+	defer o.Close()
+	o.Timeout.Duration = 50 * time.Millisecond
+	o.RetryInterval.Duration = time.Hour
+
+	// The wait for the retry ends with the timeout of the write.
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(10, 0))
+	start := time.Now()
+	require.Error(t, o.Write([]telegraf.Metric{m}))
+	require.Less(t, int64(time.Since(start)), int64(10*time.Second))
+
+	c.Lock()
+	defer c.Unlock()
+	require.Len(t, c.metadata, 1)
+}
+
+func TestWriteNotRetryable(t *testing.T) {
+	c := newCollector(t)
+	defer c.stop()
+	c.errors = []error{status.Error(codes.InvalidArgument, "bad")}
+
+	o := newTestOutput(t, c.listener.Addr().String())
+	defer o.Close()
+
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(10, 0))
+	err := o.Write([]telegraf.Metric{m})
+	require.Error(t, err)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	c.Lock()
+	defer c.Unlock()
+	require.Len(t, c.metadata, 1)
+}
+
+func TestWriteSkipsNonNumeric(t *testing.T) {
+	c := newCollector(t)
+	defer c.stop()
+
+	o := newTestOutput(t, c.listener.Addr().String())
+	defer o.Close()
+
+	m := testutil.MustMetric("log", nil, map[string]interface{}{"message": "hello"}, time.Unix(10, 0))
+	require.NoError(t, o.Write([]telegraf.Metric{m}))
+
+	c.Lock()
+	defer c.Unlock()
+	require.Empty(t, c.metadata)
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name   string
+		output OpenTelemetry
+	}{
+		{name: "compression", output: OpenTelemetry{Compression: "zstd"}},
+		{name: "schema", output: OpenTelemetry{MetricsSchema: "v3"}},
+		{name: "retries", output: OpenTelemetry{MaxRetries: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.output.Init())
+		})
+	}
+}
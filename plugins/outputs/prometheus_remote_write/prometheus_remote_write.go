@@ -0,0 +1,187 @@
+package prometheus_remote_write
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheusremotewrite"
+)
+
+var sampleConfig = `
+  ## URL of the remote write endpoint.
+  url = "http://127.0.0.1:9090/api/v1/write"
+
+  ## Timeout for HTTP message
+  # timeout = "5s"
+
+  ## HTTP Basic Auth credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Bearer token sent in the Authorization header
+  # bearer_token = ""
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Output string fields as metric labels; when false string fields are
+  ## discarded.
+  # string_as_label = false
+
+  ## Additional HTTP headers, for example the tenant of a multi-tenant
+  ## endpoint.
+  # [outputs.prometheus_remote_write.headers]
+  #   X-Scope-OrgID = "telegraf"
+`
+
+const (
+	defaultURL           = "http://127.0.0.1:9090/api/v1/write"
+	defaultClientTimeout = 5 * time.Second
+	remoteWriteVersion   = "0.1.0"
+)
+
+type PrometheusRemoteWrite struct {
+	URL           string            `toml:"url"`
+	Timeout       internal.Duration `toml:"timeout"`
+	Username      string            `toml:"username"`
+	Password      string            `toml:"password"`
+	BearerToken   string            `toml:"bearer_token"`
+	Headers       map[string]string `toml:"headers"`
+	StringAsLabel bool              `toml:"string_as_label"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client     *http.Client
+	serializer *prometheusremotewrite.Serializer
+}
+
+func (p *PrometheusRemoteWrite) Description() string {
+	return "Send metrics to a Prometheus remote write endpoint"
+}
+
+func (p *PrometheusRemoteWrite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWrite) Init() error {
+	if p.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+
+	stringHandling := prometheusremotewrite.DiscardStrings
+	if p.StringAsLabel {
+		stringHandling = prometheusremotewrite.StringAsLabel
+	}
+
+	serializer, err := prometheusremotewrite.NewSerializer(prometheusremotewrite.FormatConfig{
+		StringHandling: stringHandling,
+	})
+	if err != nil {
+		return err
+	}
+	p.serializer = serializer
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Connect() error {
+	if p.Timeout.Duration == 0 {
+		p.Timeout.Duration = defaultClientTimeout
+	}
+
+	tlsCfg, err := p.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	p.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: p.Timeout.Duration,
+	}
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Close() error {
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Write(metrics []telegraf.Metric) error {
+	body, err := p.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+
+	if p.Username != "" || p.Password != "" {
+		req.SetBasicAuth(p.Username, p.Password)
+	}
+	if p.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.BearerToken)
+	}
+
+	req.Header.Set("User-Agent", internal.ProductToken())
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	for k, v := range p.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, err = io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("when writing to [%s] received status code %d: %s",
+		p.URL, resp.StatusCode, strings.TrimSpace(string(msg)))
+
+	// Server errors and throttling are retried with the next write, other
+	// client errors would be rejected again so the batch is dropped.
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return err
+	}
+	p.Log.Errorf("Dropping %d metrics: %v", len(metrics), err)
+	return nil
+}
+
+func init() {
+	outputs.Add("prometheus_remote_write", func() telegraf.Output {
+		return &PrometheusRemoteWrite{
+			URL:     defaultURL,
+			Timeout: internal.Duration{Duration: defaultClientTimeout},
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package prometheus_remote_write
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/prompb"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func getMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{
+				"time_idle": 42.0,
+				"state":     "idle",
+			},
+			time.Unix(1, 0),
+		),
+	}
+}
+
+func newPlugin(t *testing.T, url string) *PrometheusRemoteWrite {
+	plugin := &PrometheusRemoteWrite{
+		URL: url,
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	return plugin
+}
+
+func TestWrite(t *testing.T) {
+	var req prompb.WriteRequest
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		data, err := snappy.Decode(nil, body)
+		require.NoError(t, err)
+		require.NoError(t, req.Unmarshal(data))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.BearerToken = "token"
+	plugin.Headers = map[string]string{"X-Scope-OrgID": "telegraf"}
+	require.NoError(t, plugin.Write(getMetrics()))
+	require.NoError(t, plugin.Close())
+
+	require.Equal(t, "application/x-protobuf", header.Get("Content-Type"))
+	require.Equal(t, "snappy", header.Get("Content-Encoding"))
+	require.Equal(t, "0.1.0", header.Get("X-Prometheus-Remote-Write-Version"))
+	require.Equal(t, "Bearer token", header.Get("Authorization"))
+	require.Equal(t, "telegraf", header.Get("X-Scope-OrgID"))
+
+	require.Equal(t, []prompb.TimeSeries{
+		{
+			Labels: []prompb.Label{
+				{Name: "__name__", Value: "cpu_time_idle"},
+				{Name: "cpu", Value: "cpu0"},
+			},
+			Samples: []prompb.Sample{{Value: 42, Timestamp: 1000}},
+		},
+	}, req.Timeseries)
+}
+
+func TestWriteStringAsLabel(t *testing.T) {
+	var req prompb.WriteRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		data, err := snappy.Decode(nil, body)
+		require.NoError(t, err)
+		require.NoError(t, req.Unmarshal(data))
+	}))
+	defer ts.Close()
+
+	plugin := &PrometheusRemoteWrite{
+		URL:           ts.URL,
+		StringAsLabel: true,
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	require.NoError(t, plugin.Write(getMetrics()))
+
+	require.Len(t, req.Timeseries, 1)
+	require.Contains(t, req.Timeseries[0].Labels, prompb.Label{Name: "state", Value: "idle"})
+}
+
+func TestWriteBasicAuth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "user", username)
+		require.Equal(t, "secret", password)
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.Username = "user"
+	plugin.Password = "secret"
+	require.NoError(t, plugin.Write(getMetrics()))
+}
+
+func TestWriteStatusCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		retry      bool
+	}{
+		{
+			name:       "server error is retried",
+			statusCode: http.StatusServiceUnavailable,
+			retry:      true,
+		},
+		{
+			name:       "throttling is retried",
+			statusCode: http.StatusTooManyRequests,
+			retry:      true,
+		},
+		{
+			name:       "bad request is dropped",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "out of order sample", tt.statusCode)
+			}))
+			defer ts.Close()
+
+			plugin := newPlugin(t, ts.URL)
+			err := plugin.Write(getMetrics())
+			if tt.retry {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "out of order sample")
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestInitMissingURL(t *testing.T) {
+	plugin := &PrometheusRemoteWrite{}
+	require.Error(t, plugin.Init())
+}
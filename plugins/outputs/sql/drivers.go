@@ -0,0 +1,89 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx"
+
+	// Blank import to register the database/sql driver
+	_ "github.com/jackc/pgx/stdlib"
+)
+
+// dialect holds the differences between the supported databases.
+type dialect struct {
+	// driverName is the name the driver is registered with in database/sql.
+	driverName string
+	// quote is the character used to quote identifiers.
+	quote string
+	// numbered placeholders ($1) instead of question marks.
+	numbered bool
+	// maxParams is the maximum number of parameters of a statement.
+	maxParams int
+	// types are the default column types.
+	types ConvertStruct
+	// tableMissing returns true if the error of a statement reports a
+	// table that does not exist.
+	tableMissing func(error) bool
+}
+
+var dialects = map[string]dialect{
+	"postgres": {
+		driverName: "pgx",
+		quote:      `"`,
+		numbered:   true,
+		maxParams:  65535,
+		types: ConvertStruct{
+			Integer:   "BIGINT",
+			Real:      "DOUBLE PRECISION",
+			Text:      "TEXT",
+			Timestamp: "TIMESTAMP",
+			Unsigned:  "NUMERIC(20)",
+			Bool:      "BOOLEAN",
+		},
+		tableMissing: func(err error) bool {
+			// undefined_table
+			switch e := err.(type) {
+			case pgx.PgError:
+				return e.Code == "42P01"
+			case *pgx.PgError:
+				return e.Code == "42P01"
+			}
+			return false
+		},
+	},
+	"mysql": {
+		driverName: "mysql",
+		quote:      "`",
+		maxParams:  65535,
+		types: ConvertStruct{
+			Integer:   "BIGINT",
+			Real:      "DOUBLE",
+			Text:      "TEXT",
+			Timestamp: "DATETIME(6)",
+			Unsigned:  "BIGINT UNSIGNED",
+			Bool:      "BOOLEAN",
+		},
+		tableMissing: func(err error) bool {
+			// ER_NO_SUCH_TABLE
+			e, ok := err.(*mysql.MySQLError)
+			return ok && e.Number == 1146
+		},
+	},
+}
+
+func init() {
+	dialects["pgx"] = dialects["postgres"]
+}
+
+func (d *dialect) quoteIdent(name string) string {
+	return d.quote + strings.Replace(name, d.quote, d.quote+d.quote, -1) + d.quote
+}
+
+func (d *dialect) placeholder(i int) string {
+	if d.numbered {
+		return fmt.Sprintf("$%d", i)
+	}
+	return "?"
+}
@@ -0,0 +1,33 @@
+// +build linux,amd64 linux,386 linux,arm linux,arm64 linux,s390x darwin,amd64 darwin,arm64 freebsd,amd64 freebsd,386 netbsd,amd64 openbsd,amd64 windows,amd64 windows,386
+
+package sql
+
+import (
+	"strings"
+
+	// Blank import to register the sqlite driver, it is only available on
+	// the platforms supported by the pure Go port of sqlite.
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	sqlite := dialect{
+		driverName: "sqlite",
+		quote:      `"`,
+		maxParams:  999,
+		types: ConvertStruct{
+			Integer:   "INTEGER",
+			Real:      "REAL",
+			Text:      "TEXT",
+			Timestamp: "TIMESTAMP",
+			Unsigned:  "UNSIGNED BIG INT",
+			Bool:      "BOOLEAN",
+		},
+		tableMissing: func(err error) bool {
+			// sqlite reports all statement errors with the same code.
+			return strings.Contains(err.Error(), "no such table")
+		},
+	}
+	dialects["sqlite"] = sqlite
+	dialects["sqlite3"] = sqlite
+}
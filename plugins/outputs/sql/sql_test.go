@@ -0,0 +1,153 @@
+package sql
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/jackc/pgx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQL(driver, dsn string) *SQL {
+	return &SQL{
+		Driver:              driver,
+		Dsn:                 dsn,
+		Timeout:             internal.Duration{Duration: defaultTimeout},
+		MaxIdleConnections:  defaultMaxIdleConnections,
+		TableNameTemplate:   defaultNameTemplate,
+		TagColumnTemplate:   defaultColumnTemplate,
+		FieldColumnTemplate: defaultColumnTemplate,
+		TimestampColumn:     defaultTimestampColumn,
+		TableTemplate:       defaultTableTemplate,
+		TableExistsTemplate: defaultTableExists,
+		TableUpdateTemplate: defaultTableUpdate,
+		Log:                 testutil.Logger{},
+	}
+}
+
+func TestInit(t *testing.T) {
+	plugin := newSQL("oracle", "x")
+	err := plugin.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown driver "oracle"`)
+
+	plugin = newSQL("mysql", "")
+	require.EqualError(t, plugin.Init(), "dsn is required")
+
+	plugin = newSQL("mysql", "x")
+	plugin.UseCopy = true
+	require.EqualError(t, plugin.Init(), "use_copy is only supported by the postgres driver")
+
+	plugin = newSQL("postgres", "x")
+	plugin.TableNameTemplate = "{{.Name"
+	require.Error(t, plugin.Init())
+
+	plugin = newSQL("postgres", "x")
+	plugin.Timeout.Duration = 0
+	require.EqualError(t, plugin.Init(), "timeout must be positive, got 0s")
+
+	plugin = newSQL("postgres", "x")
+	plugin.Convert.Text = "VARCHAR(255)"
+	require.NoError(t, plugin.Init())
+	require.Equal(t, ConvertStruct{
+		Integer:   "BIGINT",
+		Real:      "DOUBLE PRECISION",
+		Text:      "VARCHAR(255)",
+		Timestamp: "TIMESTAMP",
+		Unsigned:  "NUMERIC(20)",
+		Bool:      "BOOLEAN",
+	}, plugin.Convert)
+}
+
+func TestExpand(t *testing.T) {
+	plugin := newSQL("postgres", "x")
+	plugin.TableTemplate = "CREATE TABLE {TABLE}({COLUMNS}); SELECT create_hypertable({TABLELITERAL}, 'timestamp')"
+	require.NoError(t, plugin.Init())
+
+	require.Equal(t,
+		`CREATE TABLE "it's ""cpu"""("timestamp" TIMESTAMP); SELECT create_hypertable('it''s "cpu"', 'timestamp')`,
+		plugin.expand(plugin.TableTemplate, `it's "cpu"`, `"timestamp" TIMESTAMP`, ""))
+
+	plugin = newSQL("mysql", "x")
+	require.NoError(t, plugin.Init())
+	require.Equal(t,
+		"ALTER TABLE `cpu` ADD COLUMN `usage` DOUBLE",
+		plugin.expand(plugin.TableUpdateTemplate, "cpu", "", plugin.columnDefinition(column{name: "usage", sqlType: "DOUBLE"})))
+	require.Equal(t, "?", plugin.dialect.placeholder(2))
+}
+
+func TestConvertMetric(t *testing.T) {
+	plugin := newSQL("postgres", "x")
+	plugin.TableNameTemplate = `{{.Name}}_{{.Tag "region"}}`
+	plugin.TagColumnTemplate = "tag_{{.}}"
+	require.NoError(t, plugin.Init())
+
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{"region": "eu"},
+		map[string]interface{}{
+			"usage":  42.5,
+			"cores":  int64(4),
+			"ticks":  uint64(18446744073709551615),
+			"online": true,
+			"state":  "ok",
+		},
+		time.Unix(0, 0),
+	)
+	table, row, columns, err := plugin.convertMetric(m)
+	require.NoError(t, err)
+	require.Equal(t, "cpu_eu", table)
+	require.Equal(t, map[string]interface{}{
+		"timestamp":  time.Unix(0, 0).UTC(),
+		"tag_region": "eu",
+		"usage":      42.5,
+		"cores":      int64(4),
+		"ticks":      "18446744073709551615",
+		"online":     true,
+		"state":      "ok",
+	}, row)
+
+	types := make(map[string]string)
+	for _, c := range columns {
+		types[c.name] = c.sqlType
+	}
+	require.Equal(t, map[string]string{
+		"timestamp":  "TIMESTAMP",
+		"tag_region": "TEXT",
+		"usage":      "DOUBLE PRECISION",
+		"cores":      "BIGINT",
+		"ticks":      "NUMERIC(20)",
+		"online":     "BOOLEAN",
+		"state":      "TEXT",
+	}, types)
+
+	plugin.TableNameTemplate = `{{.Tag "missing"}}`
+	require.NoError(t, plugin.Init())
+	_, _, _, err = plugin.convertMetric(m)
+	require.Error(t, err)
+}
+
+func TestTableMissing(t *testing.T) {
+	tests := []struct {
+		driver  string
+		err     error
+		missing bool
+	}{
+		{driver: "postgres", err: pgx.PgError{Code: "42P01"}, missing: true},
+		{driver: "postgres", err: pgx.PgError{Code: "42501"}},
+		{driver: "postgres", err: errors.New("connection refused")},
+		{driver: "mysql", err: &mysql.MySQLError{Number: 1146}, missing: true},
+		{driver: "mysql", err: &mysql.MySQLError{Number: 1142}},
+		{driver: "mysql", err: errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver+" "+tt.err.Error(), func(t *testing.T) {
+			d := dialects[tt.driver]
+			require.Equal(t, tt.missing, d.tableMissing(tt.err))
+		})
+	}
+}
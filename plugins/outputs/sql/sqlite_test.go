@@ -0,0 +1,266 @@
+// +build linux,amd64 linux,386 linux,arm linux,arm64 linux,s390x darwin,amd64 darwin,arm64 freebsd,amd64 freebsd,386 netbsd,amd64 openbsd,amd64 windows,amd64 windows,386
+
+package sql
+
+import (
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLiteDSN(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "sql")
+	require.NoError(t, err)
+	return "file:" + filepath.Join(dir, "test.db"), func() { os.RemoveAll(dir) }
+}
+
+// dump returns the column names and types of a table, and its rows in
+// insert order.
+func dump(t *testing.T, dsn, table string) ([]string, [][]interface{}) {
+	db, err := dbsql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	schema, err := db.Query(fmt.Sprintf("SELECT name, type FROM pragma_table_info('%s')", table))
+	require.NoError(t, err)
+	defer schema.Close()
+	var columns []string
+	for schema.Next() {
+		var name, typ string
+		require.NoError(t, schema.Scan(&name, &typ))
+		columns = append(columns, name+" "+typ)
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT * FROM "%s" ORDER BY rowid`, table))
+	require.NoError(t, err)
+	defer rows.Close()
+	var result [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		require.NoError(t, rows.Scan(ptrs...))
+		result = append(result, values)
+	}
+	return columns, result
+}
+
+func TestSQLite(t *testing.T) {
+	dsn, cleanup := newSQLiteDSN(t)
+	defer cleanup()
+
+	plugin := newSQL("sqlite", dsn)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.5, "cores": int64(4)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{"free": uint64(1024)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "b", "region": "eu"},
+			map[string]interface{}{"usage": 10.0, "online": true},
+			time.Unix(1, 0),
+		),
+	}))
+
+	// New tags and fields of later writes add columns.
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "c", "rack": "r1"},
+			map[string]interface{}{"usage": 1.0, "state": "ok"},
+			time.Unix(2, 0),
+		),
+	}))
+
+	columns, rows := dump(t, dsn, "cpu")
+	require.Equal(t, []string{
+		"timestamp TIMESTAMP",
+		"host TEXT",
+		"usage REAL",
+		"cores INTEGER",
+		"region TEXT",
+		"online BOOLEAN",
+		"rack TEXT",
+		"state TEXT",
+	}, columns)
+	require.Len(t, rows, 3)
+	require.Equal(t, []interface{}{"a", 42.5, int64(4), nil, nil, nil, nil}, rows[0][1:])
+	require.Equal(t, []interface{}{"b", 10.0, nil, "eu", int64(1), nil, nil}, rows[1][1:])
+	require.Equal(t, []interface{}{"c", 1.0, nil, nil, nil, "r1", "ok"}, rows[2][1:])
+
+	columns, rows = dump(t, dsn, "mem")
+	require.Equal(t, []string{"timestamp TIMESTAMP", "free UNSIGNED BIG INT"}, columns)
+	require.Equal(t, [][]interface{}{{rows[0][0], int64(1024)}}, rows)
+}
+
+func TestSQLiteExistingTable(t *testing.T) {
+	dsn, cleanup := newSQLiteDSN(t)
+	defer cleanup()
+
+	plugin := newSQL("sqlite", dsn)
+	plugin.InitSQL = `CREATE TABLE "cpu" ("timestamp" TIMESTAMP, "host" TEXT, "usage" REAL)`
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.5, "idle": 57.5},
+			time.Unix(0, 0),
+		),
+	}))
+
+	columns, rows := dump(t, dsn, "cpu")
+	require.Equal(t, []string{"timestamp TIMESTAMP", "host TEXT", "usage REAL", "idle REAL"}, columns)
+	require.Equal(t, []interface{}{"a", 42.5, 57.5}, rows[0][1:])
+}
+
+func TestSQLiteTypeConflict(t *testing.T) {
+	dsn, cleanup := newSQLiteDSN(t)
+	defer cleanup()
+
+	plugin := newSQL("sqlite", dsn)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.5},
+			time.Unix(0, 0)),
+	}))
+
+	// The metric with the conflicting value is rejected, the others are
+	// written.
+	err := plugin.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"usage": "high"},
+			time.Unix(10, 0)),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "c"},
+			map[string]interface{}{"usage": 1.5},
+			time.Unix(10, 0)),
+	})
+	var partial *telegraf.PartialWriteError
+	require.True(t, errors.As(err, &partial))
+	require.Equal(t, []int{0}, partial.MetricsReject)
+
+	_, rows := dump(t, dsn, "cpu")
+	require.Len(t, rows, 2)
+	require.Equal(t, []interface{}{"c", 1.5}, rows[1][1:])
+}
+
+func TestSQLiteTableCheckError(t *testing.T) {
+	dsn, cleanup := newSQLiteDSN(t)
+	defer cleanup()
+
+	// The table is only created if the check reports it as missing.
+	plugin := newSQL("sqlite", dsn)
+	plugin.TableExistsTemplate = "SELECT missing_column FROM {TABLE} LIMIT 0"
+	plugin.InitSQL = `CREATE TABLE "cpu" ("timestamp" TIMESTAMP)`
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	err := plugin.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{"usage": 42.5},
+			time.Unix(0, 0),
+		),
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `checking table "cpu"`)
+}
+
+func TestSQLiteTemplates(t *testing.T) {
+	dsn, cleanup := newSQLiteDSN(t)
+	defer cleanup()
+
+	plugin := newSQL("sqlite", dsn)
+	plugin.TableNameTemplate = `metrics_{{.Tag "region"}}`
+	plugin.TagColumnTemplate = "tag_{{.}}"
+	plugin.FieldColumnTemplate = "{{.}}_value"
+	plugin.TimestampColumn = "time"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"region": "eu"},
+			map[string]interface{}{"usage": 42.5},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{"usage": 42.5},
+			time.Unix(0, 0),
+		),
+	}))
+
+	columns, rows := dump(t, dsn, "metrics_eu")
+	require.Equal(t, []string{"time TIMESTAMP", "tag_region TEXT", "usage_value REAL"}, columns)
+	require.Len(t, rows, 1)
+
+	columns, rows = dump(t, dsn, "metrics_")
+	require.Equal(t, []string{"time TIMESTAMP", "usage_value REAL"}, columns)
+	require.Len(t, rows, 1)
+}
+
+func TestSQLiteLargeBatch(t *testing.T) {
+	dsn, cleanup := newSQLiteDSN(t)
+	defer cleanup()
+
+	plugin := newSQL("sqlite", dsn)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// More rows than fit into the parameter limit of a single statement.
+	metrics := make([]telegraf.Metric, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		metrics = append(metrics, testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": int64(i)},
+			time.Unix(int64(i), 0),
+		))
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	_, rows := dump(t, dsn, "cpu")
+	require.Len(t, rows, 1000)
+	require.Equal(t, int64(999), rows[999][2])
+}
@@ -134,6 +134,10 @@ func (s *SumoLogic) SetSerializer(serializer serializers.Serializer) {
 	s.serializer = serializer
 }
 
+func (s *SumoLogic) UsesBatchFormat() bool {
+	return true
+}
+
 func (s *SumoLogic) createClient(ctx context.Context) (*http.Client, error) {
 	return &http.Client{
 		Transport: &http.Transport{
@@ -0,0 +1,354 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// magicByte starts the messages in the Confluent wire format, followed by
+// the schema ID as 4 byte big-endian integer.
+const magicByte = 0
+
+type Config struct {
+	MetricName string
+	// SchemaRegistry is the URL of a Confluent schema registry, the
+	// messages must be in its wire format.
+	SchemaRegistry string
+	// Schema is the schema of all messages, used instead of a registry.
+	Schema string
+	// Measurement is the name of the metrics, MeasurementField the field of
+	// the record holding the name instead.
+	Measurement      string
+	MeasurementField string
+	// Tags and Fields are the flattened names of the record fields, all
+	// fields except the tags and timestamp are added if no fields are set.
+	Tags            []string
+	Fields          []string
+	Timestamp       string
+	TimestampFormat string
+	DefaultTags     map[string]string
+}
+
+// Parser parses Avro records, one record per call to Parse.
+type Parser struct {
+	metricName       string
+	measurementField string
+	tags             []string
+	fields           []string
+	timestamp        string
+	timestampFormat  string
+	defaultTags      map[string]string
+
+	// schema is the static schema, registry fetches the schema of each
+	// message instead.
+	schema   *schema
+	registry *registry
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	p := &Parser{
+		metricName:       config.MetricName,
+		measurementField: config.MeasurementField,
+		tags:             config.Tags,
+		fields:           config.Fields,
+		timestamp:        config.Timestamp,
+		timestampFormat:  config.TimestampFormat,
+		defaultTags:      config.DefaultTags,
+		TimeFunc:         time.Now,
+	}
+	if config.Measurement != "" {
+		p.metricName = config.Measurement
+	}
+	if p.timestampFormat == "" {
+		p.timestampFormat = "unix"
+	}
+
+	switch {
+	case config.Schema != "" && config.SchemaRegistry != "":
+		return nil, errors.New("only one of avro_schema and avro_schema_registry can be set")
+	case config.Schema != "":
+		s, err := newSchema(config.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("parsing avro_schema: %v", err)
+		}
+		p.schema = s
+	case config.SchemaRegistry != "":
+		p.registry = newRegistry(config.SchemaRegistry)
+	default:
+		return nil, errors.New("one of avro_schema or avro_schema_registry is required")
+	}
+	return p, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	s := p.schema
+	if p.registry != nil {
+		if len(buf) < 5 || buf[0] != magicByte {
+			return nil, errors.New("message is not in the schema registry wire format")
+		}
+		var err error
+		s, err = p.registry.schema(int(binary.BigEndian.Uint32(buf[1:5])))
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[5:]
+	}
+
+	native, rest, err := s.codec.NativeFromBinary(buf)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%d bytes left after decoding the record", len(rest))
+	}
+
+	values := make(map[string]interface{})
+	s.flatten(values, "", s.root, "", native)
+
+	m, err := p.createMetric(values)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return []telegraf.Metric{}, nil
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+func (p *Parser) createMetric(values map[string]interface{}) (telegraf.Metric, error) {
+	name := p.metricName
+	if p.measurementField != "" {
+		if v, ok := values[p.measurementField]; ok {
+			name = toTag(v)
+		}
+	}
+
+	tags := make(map[string]string, len(p.defaultTags)+len(p.tags))
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	for _, key := range p.tags {
+		if v, ok := values[key]; ok {
+			tags[key] = toTag(v)
+		}
+	}
+
+	fields := make(map[string]interface{})
+	if len(p.fields) == 0 {
+		for key, v := range values {
+			fields[key] = v
+		}
+		for _, key := range p.tags {
+			delete(fields, key)
+		}
+		delete(fields, p.timestamp)
+		delete(fields, p.measurementField)
+	} else {
+		for _, key := range p.fields {
+			if v, ok := values[key]; ok {
+				fields[key] = v
+			}
+		}
+	}
+	for key, v := range fields {
+		if field := toField(v); field != nil {
+			fields[key] = field
+		} else {
+			delete(fields, key)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	timestamp := p.TimeFunc()
+	if p.timestamp != "" {
+		v, ok := values[p.timestamp]
+		if !ok {
+			return nil, fmt.Errorf("timestamp %q not set", p.timestamp)
+		}
+		var err error
+		timestamp, err = p.parseTimestamp(v)
+		if err != nil {
+			return nil, fmt.Errorf("parsing timestamp %q: %v", p.timestamp, err)
+		}
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+func (p *Parser) parseTimestamp(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case int32:
+		value = int64(v)
+	case float32:
+		value = float64(v)
+	case []byte:
+		value = string(v)
+	}
+	return internal.ParseTimestamp(p.timestampFormat, value, "")
+}
+
+// toField converts the native values of goavro to field values, nil if the
+// type is not supported.
+func toField(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case int64, float64, bool, string:
+		return v
+	case float32:
+		return float64(v)
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UnixNano()
+	case time.Duration:
+		return int64(v)
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f
+	default:
+		return nil
+	}
+}
+
+func toTag(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case *big.Rat:
+		f, _ := v.Float64()
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("no metric in message")
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+// registry fetches schemas by their ID from a schema registry and caches
+// them, a schema of an ID never changes.  Failures are cached for the retry
+// interval, so that a missing schema is not requested for every message.
+type registry struct {
+	url           string
+	client        *http.Client
+	retryInterval time.Duration
+
+	mu      sync.Mutex
+	entries map[int]*registryEntry
+}
+
+// registryEntry is the schema of an ID, done is closed once it is fetched.
+type registryEntry struct {
+	done    chan struct{}
+	schema  *schema
+	err     error
+	expires time.Time
+}
+
+func newRegistry(url string) *registry {
+	return &registry{
+		url:           strings.TrimSuffix(url, "/"),
+		client:        &http.Client{Timeout: 10 * time.Second},
+		retryInterval: 10 * time.Second,
+		entries:       make(map[int]*registryEntry),
+	}
+}
+
+func (r *registry) schema(id int) (*schema, error) {
+	for {
+		r.mu.Lock()
+		e, ok := r.entries[id]
+		if !ok {
+			// The schema is fetched without holding the lock, so that other
+			// IDs are not blocked.  Callers of the same ID wait for it.
+			e = &registryEntry{done: make(chan struct{})}
+			r.entries[id] = e
+			r.mu.Unlock()
+
+			e.schema, e.err = r.fetch(id)
+			if e.err != nil {
+				e.err = fmt.Errorf("fetching schema %d: %v", id, e.err)
+				e.expires = time.Now().Add(r.retryInterval)
+			}
+			close(e.done)
+			return e.schema, e.err
+		}
+		r.mu.Unlock()
+
+		<-e.done
+		if e.err == nil || time.Now().Before(e.expires) {
+			return e.schema, e.err
+		}
+
+		// The failure expired, remove it unless it was already replaced.
+		r.mu.Lock()
+		if r.entries[id] == e {
+			delete(r.entries, id)
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *registry) fetch(id int) (*schema, error) {
+	req, err := http.NewRequest("GET", r.url+"/schemas/ids/"+strconv.Itoa(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var body struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.SchemaType != "" && body.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("unsupported schema type %q", body.SchemaType)
+	}
+	return newSchema(body.Schema)
+}
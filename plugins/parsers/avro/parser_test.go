@@ -0,0 +1,313 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const sensorSchema = `{
+	"type": "record",
+	"name": "Reading",
+	"namespace": "com.example",
+	"fields": [
+		{"name": "sensor", "type": "string"},
+		{"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "value", "type": "double"},
+		{"name": "count", "type": "int"},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["OK", "FAILED"]}},
+		{"name": "note", "type": ["null", "string"], "default": null},
+		{"name": "location", "type": {
+			"type": "record",
+			"name": "Location",
+			"fields": [
+				{"name": "site", "type": "string"},
+				{"name": "floor", "type": ["null", "int"]}
+			]
+		}},
+		{"name": "previous", "type": ["null", "Location"], "default": null},
+		{"name": "labels", "type": {"type": "map", "values": "string"}},
+		{"name": "history", "type": {"type": "array", "items": "float"}}
+	]
+}`
+
+var sensorRecord = map[string]interface{}{
+	"sensor": "temp1",
+	"time":   time.Date(2020, 11, 2, 12, 0, 0, 0, time.UTC),
+	"value":  21.5,
+	"count":  int32(3),
+	"status": "OK",
+	"note":   goavro.Union("string", "calibrated"),
+	"location": map[string]interface{}{
+		"site":  "lab",
+		"floor": goavro.Union("int", int32(2)),
+	},
+	"previous": goavro.Union("com.example.Location", map[string]interface{}{
+		"site":  "office",
+		"floor": nil,
+	}),
+	"labels":  map[string]interface{}{"owner": "ops"},
+	"history": []interface{}{float32(20.5), float32(21)},
+}
+
+func encode(t *testing.T, spec string, record map[string]interface{}) []byte {
+	codec, err := goavro.NewCodec(spec)
+	require.NoError(t, err)
+	buf, err := codec.BinaryFromNative(nil, record)
+	require.NoError(t, err)
+	return buf
+}
+
+// frame prefixes the message with the header of the wire format.
+func frame(id uint32, buf []byte) []byte {
+	header := make([]byte, 5)
+	binary.BigEndian.PutUint32(header[1:], id)
+	return append(header, buf...)
+}
+
+func TestParseStaticSchema(t *testing.T) {
+	p, err := New(&Config{
+		MetricName:  "avro",
+		Schema:      sensorSchema,
+		Tags:        []string{"sensor", "location_site"},
+		Timestamp:   "time",
+		DefaultTags: map[string]string{"source": "kafka"},
+	})
+	require.NoError(t, err)
+
+	metrics, err := p.Parse(encode(t, sensorSchema, sensorRecord))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("avro",
+			map[string]string{
+				"sensor":        "temp1",
+				"location_site": "lab",
+				"source":        "kafka",
+			},
+			map[string]interface{}{
+				"value":          21.5,
+				"count":          int64(3),
+				"status":         "OK",
+				"note":           "calibrated",
+				"location_floor": int64(2),
+				"previous_site":  "office",
+				"labels_owner":   "ops",
+				"history_0":      20.5,
+				"history_1":      21.0,
+			},
+			time.Date(2020, 11, 2, 12, 0, 0, 0, time.UTC)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseFields(t *testing.T) {
+	p, err := New(&Config{
+		MetricName:       "avro",
+		Schema:           sensorSchema,
+		MeasurementField: "sensor",
+		Fields:           []string{"value", "note", "missing"},
+	})
+	require.NoError(t, err)
+	p.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	record := make(map[string]interface{})
+	for k, v := range sensorRecord {
+		record[k] = v
+	}
+	record["note"] = nil
+
+	metrics, err := p.Parse(encode(t, sensorSchema, record))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("temp1",
+			map[string]string{},
+			map[string]interface{}{"value": 21.5},
+			time.Unix(42, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseTimestampFormat(t *testing.T) {
+	spec := `{
+		"type": "record",
+		"name": "Event",
+		"fields": [
+			{"name": "ts", "type": "long"},
+			{"name": "value", "type": "long"}
+		]
+	}`
+	p, err := New(&Config{
+		MetricName:      "avro",
+		Measurement:     "event",
+		Schema:          spec,
+		Timestamp:       "ts",
+		TimestampFormat: "unix_ms",
+	})
+	require.NoError(t, err)
+
+	metrics, err := p.Parse(encode(t, spec, map[string]interface{}{"ts": int64(1604318400123), "value": int64(7)}))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("event",
+			map[string]string{},
+			map[string]interface{}{"value": int64(7)},
+			time.Unix(1604318400, 123000000)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseSchemaRegistry(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/schemas/ids/7" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]string{"schema": sensorSchema}))
+	}))
+	defer ts.Close()
+
+	p, err := New(&Config{
+		MetricName:     "avro",
+		SchemaRegistry: ts.URL + "/",
+		Tags:           []string{"sensor"},
+		Fields:         []string{"value"},
+		Timestamp:      "time",
+	})
+	require.NoError(t, err)
+
+	msg := frame(7, encode(t, sensorSchema, sensorRecord))
+	expected := []telegraf.Metric{
+		testutil.MustMetric("avro",
+			map[string]string{"sensor": "temp1"},
+			map[string]interface{}{"value": 21.5},
+			time.Date(2020, 11, 2, 12, 0, 0, 0, time.UTC)),
+	}
+	for i := 0; i < 3; i++ {
+		metrics, err := p.Parse(msg)
+		require.NoError(t, err)
+		testutil.RequireMetricsEqual(t, expected, metrics)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	_, err = p.Parse(frame(8, encode(t, sensorSchema, sensorRecord)))
+	require.EqualError(t, err, "fetching schema 8: unexpected status 404 Not Found")
+
+	_, err = p.Parse(encode(t, sensorSchema, sensorRecord))
+	require.EqualError(t, err, "message is not in the schema registry wire format")
+}
+
+func TestRegistryFetchConcurrent(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/schemas/ids/7" {
+			atomic.AddInt32(&requests, 1)
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]string{"schema": sensorSchema}))
+	}))
+	defer ts.Close()
+
+	r := newRegistry(ts.URL)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.schema(7)
+			errs <- err
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&requests) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Other IDs are fetched while the schema 7 is in flight.
+	_, err := r.schema(8)
+	require.NoError(t, err)
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestRegistryFailureCached(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	r := newRegistry(ts.URL)
+	for i := 0; i < 3; i++ {
+		_, err := r.schema(8)
+		require.EqualError(t, err, "fetching schema 8: unexpected status 404 Not Found")
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// The schema is requested again once the failure expired.
+	r.entries[8].expires = time.Now().Add(-time.Second)
+	for i := 0; i < 3; i++ {
+		_, err := r.schema(8)
+		require.Error(t, err)
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestParseErrors(t *testing.T) {
+	p, err := New(&Config{
+		MetricName: "avro",
+		Schema:     sensorSchema,
+		Timestamp:  "note",
+	})
+	require.NoError(t, err)
+
+	record := make(map[string]interface{})
+	for k, v := range sensorRecord {
+		record[k] = v
+	}
+	record["note"] = nil
+	_, err = p.Parse(encode(t, sensorSchema, record))
+	require.EqualError(t, err, `timestamp "note" not set`)
+
+	_, err = p.Parse(append(encode(t, sensorSchema, sensorRecord), 0))
+	require.EqualError(t, err, "1 bytes left after decoding the record")
+}
+
+func TestNewErrors(t *testing.T) {
+	_, err := New(&Config{})
+	require.EqualError(t, err, "one of avro_schema or avro_schema_registry is required")
+
+	_, err = New(&Config{Schema: sensorSchema, SchemaRegistry: "http://localhost:8081"})
+	require.EqualError(t, err, "only one of avro_schema and avro_schema_registry can be set")
+
+	_, err = New(&Config{Schema: `{"type": "unknown"}`})
+	require.Error(t, err)
+}
@@ -0,0 +1,219 @@
+package avro
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// schema is a codec with the parsed schema, used to walk the decoded values
+// since the unions are only recognized by their schema.
+type schema struct {
+	codec *goavro.Codec
+	root  interface{}
+	// named are the definitions of the records, enums and fixed types by
+	// their full name.
+	named map[string]interface{}
+}
+
+func newSchema(spec string) (*schema, error) {
+	codec, err := goavro.NewCodec(spec)
+	if err != nil {
+		return nil, err
+	}
+	s := &schema{codec: codec, named: make(map[string]interface{})}
+	if err := json.Unmarshal([]byte(spec), &s.root); err != nil {
+		return nil, err
+	}
+	s.collect(s.root, "")
+	return s, nil
+}
+
+// collect registers the named types of the schema.
+func (s *schema) collect(def interface{}, namespace string) {
+	switch d := def.(type) {
+	case []interface{}:
+		for _, member := range d {
+			s.collect(member, namespace)
+		}
+	case map[string]interface{}:
+		switch d["type"] {
+		case "record", "error", "enum", "fixed":
+			name := fullName(d, namespace)
+			s.named[name] = d
+			namespace = nameSpace(name)
+		}
+		if fields, ok := d["fields"].([]interface{}); ok {
+			for _, field := range fields {
+				if f, ok := field.(map[string]interface{}); ok {
+					s.collect(f["type"], namespace)
+				}
+			}
+		}
+		for _, key := range []string{"items", "values"} {
+			if sub, ok := d[key]; ok {
+				s.collect(sub, namespace)
+			}
+		}
+		if _, ok := d["type"].(string); !ok {
+			s.collect(d["type"], namespace)
+		}
+	}
+}
+
+// flatten adds the values of the datum to values, nested names are joined
+// with underscores.
+func (s *schema) flatten(values map[string]interface{}, key string, def interface{}, namespace string, datum interface{}) {
+	if datum == nil {
+		return
+	}
+
+	switch d := def.(type) {
+	case []interface{}:
+		// A union is decoded as a map with the type name as single key.
+		union, ok := datum.(map[string]interface{})
+		if !ok || len(union) != 1 {
+			values[key] = datum
+			return
+		}
+		for name, value := range union {
+			s.flatten(values, key, s.member(d, namespace, name), namespace, value)
+		}
+	case string:
+		if named, ok := s.named[qualify(d, namespace)]; ok {
+			s.flatten(values, key, named, namespace, datum)
+			return
+		}
+		values[key] = datum
+	case map[string]interface{}:
+		switch d["type"] {
+		case "record", "error":
+			record, ok := datum.(map[string]interface{})
+			if !ok {
+				return
+			}
+			namespace = nameSpace(fullName(d, namespace))
+			fields, _ := d["fields"].([]interface{})
+			for _, field := range fields {
+				f, ok := field.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := f["name"].(string)
+				s.flatten(values, join(key, name), f["type"], namespace, record[name])
+			}
+		case "map":
+			m, ok := datum.(map[string]interface{})
+			if !ok {
+				return
+			}
+			for k, v := range m {
+				s.flatten(values, join(key, k), d["values"], namespace, v)
+			}
+		case "array":
+			a, ok := datum.([]interface{})
+			if !ok {
+				return
+			}
+			for i, v := range a {
+				s.flatten(values, join(key, strconv.Itoa(i)), d["items"], namespace, v)
+			}
+		default:
+			// A primitive, enum or fixed type, or a nested definition.
+			if name, ok := d["type"].(string); ok {
+				if named, ok := s.named[qualify(name, namespace)]; ok {
+					s.flatten(values, key, named, namespace, datum)
+					return
+				}
+				values[key] = datum
+				return
+			}
+			s.flatten(values, key, d["type"], namespace, datum)
+		}
+	default:
+		// Without a definition, for example for union members with logical
+		// types unknown to this parser, the value is flattened as is.
+		switch v := datum.(type) {
+		case map[string]interface{}:
+			for k, item := range v {
+				s.flatten(values, join(key, k), nil, namespace, item)
+			}
+		case []interface{}:
+			for i, item := range v {
+				s.flatten(values, join(key, strconv.Itoa(i)), nil, namespace, item)
+			}
+		default:
+			values[key] = datum
+		}
+	}
+}
+
+// member returns the definition of the union member with the type name.
+func (s *schema) member(union []interface{}, namespace, name string) interface{} {
+	for _, member := range union {
+		if typeName(member, namespace) == name {
+			return member
+		}
+	}
+	return nil
+}
+
+// typeName returns the name goavro uses for the type in unions.
+func typeName(def interface{}, namespace string) string {
+	switch d := def.(type) {
+	case string:
+		switch d {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return d
+		}
+		return qualify(d, namespace)
+	case map[string]interface{}:
+		switch t := d["type"].(type) {
+		case string:
+			switch t {
+			case "record", "error", "enum", "fixed":
+				return fullName(d, namespace)
+			case "array", "map":
+				return t
+			}
+			if logical, ok := d["logicalType"].(string); ok {
+				return t + "." + logical
+			}
+			return typeName(t, namespace)
+		default:
+			return typeName(t, namespace)
+		}
+	}
+	return ""
+}
+
+func fullName(def map[string]interface{}, namespace string) string {
+	name, _ := def["name"].(string)
+	if ns, ok := def["namespace"].(string); ok {
+		namespace = ns
+	}
+	return qualify(name, namespace)
+}
+
+func qualify(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func nameSpace(fullName string) string {
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		return fullName[:i]
+	}
+	return ""
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "_" + key
+}
@@ -90,16 +90,26 @@ func (p *Parser) compile(r io.Reader) (*csv.Reader, error) {
 }
 
 func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
-	r := bytes.NewReader(buf)
+	metrics := make([]telegraf.Metric, 0)
+	err := p.ParseStream(bytes.NewReader(buf), func(m telegraf.Metric) error {
+		metrics = append(metrics, m)
+		return nil
+	})
+	return metrics, err
+}
+
+// ParseStream parses the records read from r one at a time and calls fn with
+// the metric of each record.
+func (p *Parser) ParseStream(r io.Reader, fn func(telegraf.Metric) error) error {
 	csvReader, err := p.compile(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	// skip first rows
 	for i := 0; i < p.SkipRows; i++ {
 		_, err := csvReader.Read()
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 	// if there is a header and nothing in DataColumns
@@ -109,7 +119,7 @@ func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
 		for i := 0; i < p.HeaderRowCount; i++ {
 			header, err := csvReader.Read()
 			if err != nil {
-				return nil, err
+				return err
 			}
 			//concatenate header names
 			for i := range header {
@@ -130,25 +140,30 @@ func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
 		for i := 0; i < p.HeaderRowCount; i++ {
 			_, err := csvReader.Read()
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
 	}
 
-	table, err := csvReader.ReadAll()
-	if err != nil {
-		return nil, err
-	}
+	// reuse the record slice, the values are copied into the metric
+	csvReader.ReuseRecord = true
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 
-	metrics := make([]telegraf.Metric, 0)
-	for _, record := range table {
 		m, err := p.parseRecord(record)
 		if err != nil {
-			return metrics, err
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
 		}
-		metrics = append(metrics, m)
 	}
-	return metrics, nil
 }
 
 // ParseLine does not use any information in header and assumes DataColumns is set
@@ -1,7 +1,9 @@
 package csv
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -593,3 +595,63 @@ func TestStaticMeasurementName(t *testing.T) {
 	}
 	testutil.RequireMetricsEqual(t, expected, metrics, testutil.IgnoreTime())
 }
+
+func TestParseStreamReader(t *testing.T) {
+	p, err := NewParser(
+		&Config{
+			HeaderRowCount:  1,
+			TagColumns:      []string{"host"},
+			TimestampColumn: "time",
+			TimestampFormat: "unix",
+			MetricName:      "csv",
+		},
+	)
+	require.NoError(t, err)
+
+	var metrics []telegraf.Metric
+	csv := "host,value,time\nserver01,42,1551129661\nserver02,3.5,1551129662\n"
+	err = p.ParseStream(strings.NewReader(csv), func(m telegraf.Metric) error {
+		metrics = append(metrics, m)
+		return nil
+	})
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("csv",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"value": int64(42)},
+			time.Unix(1551129661, 0),
+		),
+		testutil.MustMetric("csv",
+			map[string]string{"host": "server02"},
+			map[string]interface{}{"value": 3.5},
+			time.Unix(1551129662, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseStreamStopsOnError(t *testing.T) {
+	p, err := NewParser(
+		&Config{
+			ColumnNames: []string{"a", "b"},
+			ColumnTypes: []string{"int", "int"},
+			TimeFunc:    DefaultTime,
+		},
+	)
+	require.NoError(t, err)
+
+	var metrics []telegraf.Metric
+	err = p.ParseStream(strings.NewReader("1,2\n3,x\n5,6\n"), func(m telegraf.Metric) error {
+		metrics = append(metrics, m)
+		return nil
+	})
+	require.Error(t, err)
+	require.Len(t, metrics, 1)
+
+	stop := errors.New("stop")
+	err = p.ParseStream(strings.NewReader("1,2\n5,6\n"), func(m telegraf.Metric) error {
+		return stop
+	})
+	require.Equal(t, stop, err)
+}
@@ -653,6 +653,64 @@ func TestParser(t *testing.T) {
 	}
 }
 
+func TestParserParseStream(t *testing.T) {
+	for _, tt := range ptests {
+		if tt.err != nil {
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMetricHandler()
+			parser := NewParser(handler)
+			parser.SetTimeFunc(DefaultTime)
+			if tt.timeFunc != nil {
+				parser.SetTimeFunc(tt.timeFunc)
+			}
+
+			var metrics []telegraf.Metric
+			err := parser.ParseStream(bytes.NewReader(tt.input), func(m telegraf.Metric) error {
+				metrics = append(metrics, m)
+				return nil
+			})
+			require.NoError(t, err)
+
+			require.Equal(t, len(tt.metrics), len(metrics))
+			for i, expected := range tt.metrics {
+				require.Equal(t, expected.Name(), metrics[i].Name())
+				require.Equal(t, expected.Tags(), metrics[i].Tags())
+				require.Equal(t, expected.Fields(), metrics[i].Fields())
+				require.Equal(t, expected.Time(), metrics[i].Time())
+			}
+		})
+	}
+}
+
+func TestParserParseStreamErrors(t *testing.T) {
+	parser := NewParser(NewMetricHandler())
+	parser.SetDefaultTags(map[string]string{"host": "localhost"})
+
+	var metrics []telegraf.Metric
+	err := parser.ParseStream(strings.NewReader("cpu value=1\ncpu value=\ncpu value=3\n"),
+		func(m telegraf.Metric) error {
+			metrics = append(metrics, m)
+			return nil
+		})
+	require.Error(t, err)
+	require.IsType(t, &ParseError{}, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]string{"host": "localhost"}, metrics[0].Tags())
+
+	// An error of the callback stops the parsing.
+	stop := errors.New("stop")
+	calls := 0
+	err = parser.ParseStream(strings.NewReader("cpu value=1\ncpu value=2\n"),
+		func(m telegraf.Metric) error {
+			calls++
+			return stop
+		})
+	require.Equal(t, stop, err)
+	require.Equal(t, 1, calls)
+}
+
 func BenchmarkParser(b *testing.B) {
 	for _, tt := range ptests {
 		b.Run(tt.name, func(b *testing.B) {
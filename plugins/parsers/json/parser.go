@@ -1,10 +1,13 @@
 package json
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"strconv"
 	"time"
@@ -216,6 +219,123 @@ func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
 	}
 }
 
+// ParseStream parses the JSON read from r and calls fn with each metric.
+// Arrays of objects are decoded one element at a time, and the stream may
+// contain several documents, such as newline delimited JSON.  When a query is
+// set each document is read into memory to evaluate the query.
+func (p *Parser) ParseStream(r io.Reader, fn func(telegraf.Metric) error) error {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(bom, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+
+	if p.query != "" {
+		buf, err := ioutil.ReadAll(br)
+		if err != nil {
+			return err
+		}
+		metrics, err := p.Parse(buf)
+		if err != nil {
+			return err
+		}
+		return emit(metrics, fn)
+	}
+
+	timestamp := time.Now().UTC()
+	decoder := json.NewDecoder(br)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'):
+			object, err := decodeObject(decoder)
+			if err != nil {
+				return err
+			}
+			metrics, err := p.parseObject(object, timestamp)
+			if err != nil {
+				return err
+			}
+			if err := emit(metrics, fn); err != nil {
+				return err
+			}
+		case json.Delim('['):
+			for decoder.More() {
+				token, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				if token != json.Delim('{') {
+					return ErrWrongType
+				}
+				object, err := decodeObject(decoder)
+				if err != nil {
+					return err
+				}
+				metrics, err := p.parseObject(object, timestamp)
+				if err != nil {
+					if p.strict {
+						return err
+					}
+					continue
+				}
+				if err := emit(metrics, fn); err != nil {
+					return err
+				}
+			}
+			// Consume the closing bracket.
+			if _, err := decoder.Token(); err != nil {
+				return err
+			}
+		default:
+			return ErrWrongType
+		}
+	}
+}
+
+// decodeObject decodes the members of an object whose opening brace has
+// already been consumed.
+func decodeObject(decoder *json.Decoder) (map[string]interface{}, error) {
+	object := make(map[string]interface{})
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected object key %v", token)
+		}
+
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		object[key] = value
+	}
+
+	// Consume the closing brace.
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func emit(metrics []telegraf.Metric, fn func(telegraf.Metric) error) error {
+	for _, m := range metrics {
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 	metrics, err := p.Parse([]byte(line + "\n"))
 
@@ -2,6 +2,7 @@ package json
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -948,3 +949,113 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []map[string]interface{}
+	}{
+		{
+			name:     "object",
+			input:    validJSON,
+			expected: []map[string]interface{}{{"a": 5.0, "b_c": 6.0}},
+		},
+		{
+			name:  "array",
+			input: validJSONArrayMultiple,
+			expected: []map[string]interface{}{
+				{"a": 5.0, "b_c": 6.0},
+				{"a": 7.0, "b_c": 8.0},
+			},
+		},
+		{
+			name:  "newline delimited",
+			input: validJSON + "\n" + validJSONNewline,
+			expected: []map[string]interface{}{
+				{"a": 5.0, "b_c": 6.0},
+				{"d": 7.0, "b_d": 8.0},
+			},
+		},
+		{
+			name:     "byte order mark",
+			input:    "\xef\xbb\xbf" + validJSONArray,
+			expected: []map[string]interface{}{{"a": 5.0, "b_c": 6.0}},
+		},
+		{
+			name:  "empty",
+			input: " \n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := New(&Config{MetricName: "json_test"})
+			require.NoError(t, err)
+
+			var fields []map[string]interface{}
+			err = parser.ParseStream(strings.NewReader(tt.input), func(m telegraf.Metric) error {
+				require.Equal(t, "json_test", m.Name())
+				fields = append(fields, m.Fields())
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, fields)
+		})
+	}
+}
+
+func TestParseStreamErrors(t *testing.T) {
+	for _, input := range []string{invalidJSON, invalidJSON2, "[1, 2]", "42"} {
+		parser, err := New(&Config{MetricName: "json_test"})
+		require.NoError(t, err)
+
+		err = parser.ParseStream(strings.NewReader(input), func(m telegraf.Metric) error {
+			return nil
+		})
+		require.Error(t, err, input)
+	}
+}
+
+func TestParseStreamStrict(t *testing.T) {
+	config := &Config{
+		MetricName: "json_test",
+		TimeKey:    "time",
+		TimeFormat: "2006-01-02T15:04:05",
+	}
+
+	parser, err := New(config)
+	require.NoError(t, err)
+	var metrics []telegraf.Metric
+	err = parser.ParseStream(strings.NewReader(mixedValidityJSON), func(m telegraf.Metric) error {
+		metrics = append(metrics, m)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	config.Strict = true
+	parser, err = New(config)
+	require.NoError(t, err)
+	err = parser.ParseStream(strings.NewReader(mixedValidityJSON), func(m telegraf.Metric) error {
+		return nil
+	})
+	require.Error(t, err)
+}
+
+func TestParseStreamQuery(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "json_test",
+		Query:      "data",
+	})
+	require.NoError(t, err)
+
+	var metrics []telegraf.Metric
+	err = parser.ParseStream(strings.NewReader(`{"data": [{"a": 1}, {"a": 2}]}`), func(m telegraf.Metric) error {
+		metrics = append(metrics, m)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]interface{}{"a": 2.0}, metrics[1].Fields())
+}
@@ -0,0 +1,341 @@
+package json_v2
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/tidwall/gjson"
+)
+
+// Object is the configuration of a `[[json_v2.object]]` section.  All keys,
+// except the path, are dot separated keys relative to the selected object.
+type Object struct {
+	// Path is the GJSON path of the object, an array selects every element.
+	Path              string            `toml:"path"`
+	MeasurementName   string            `toml:"measurement_name"`
+	Tags              []string          `toml:"tags"`
+	IncludedKeys      []string          `toml:"included_keys"`
+	ExcludedKeys      []string          `toml:"excluded_keys"`
+	Explode           []string          `toml:"explode"`
+	Renames           map[string]string `toml:"renames"`
+	Types             map[string]string `toml:"types"`
+	TimestampPath     string            `toml:"timestamp_path"`
+	TimestampFormat   string            `toml:"timestamp_format"`
+	TimestampTimezone string            `toml:"timestamp_timezone"`
+}
+
+type Config struct {
+	MetricName  string
+	Objects     []Object
+	DefaultTags map[string]string
+}
+
+// object is a compiled Object.
+type object struct {
+	Object
+	tags     map[string]bool
+	explode  map[string]bool
+	included filter.Filter
+	excluded filter.Filter
+}
+
+// Parser creates metrics from the objects of a JSON document.
+type Parser struct {
+	metricName  string
+	objects     []*object
+	defaultTags map[string]string
+
+	TimeFunc func() time.Time
+}
+
+// row is the flattened key/values of an object, one per element of the
+// exploded arrays.
+type row map[string]gjson.Result
+
+func New(config *Config) (*Parser, error) {
+	if len(config.Objects) == 0 {
+		return nil, errors.New("at least one json_v2 object must be configured")
+	}
+
+	p := &Parser{
+		metricName:  config.MetricName,
+		defaultTags: config.DefaultTags,
+		TimeFunc:    time.Now,
+	}
+	for i, cfg := range config.Objects {
+		o, err := compile(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("object %d: %w", i+1, err)
+		}
+		p.objects = append(p.objects, o)
+	}
+	return p, nil
+}
+
+func compile(cfg Object) (*object, error) {
+	o := &object{
+		Object:  cfg,
+		tags:    make(map[string]bool, len(cfg.Tags)),
+		explode: make(map[string]bool, len(cfg.Explode)),
+	}
+	for _, key := range cfg.Tags {
+		o.tags[key] = true
+	}
+	for _, key := range cfg.Explode {
+		o.explode[key] = true
+	}
+	for key, typ := range cfg.Types {
+		switch typ {
+		case "int", "uint", "float", "string", "bool":
+		default:
+			return nil, fmt.Errorf("invalid type %q of %q, must be one of int, uint, float, string or bool", typ, key)
+		}
+	}
+	if cfg.TimestampPath != "" && cfg.TimestampFormat == "" {
+		return nil, errors.New("timestamp_format is required with timestamp_path")
+	}
+
+	var err error
+	if o.included, err = filter.Compile(cfg.IncludedKeys); err != nil {
+		return nil, err
+	}
+	if o.excluded, err = filter.Compile(cfg.ExcludedKeys); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if !gjson.ValidBytes(buf) {
+		return nil, errors.New("invalid JSON")
+	}
+	doc := gjson.ParseBytes(buf)
+
+	now := p.TimeFunc()
+	metrics := make([]telegraf.Metric, 0)
+	for _, o := range p.objects {
+		result := doc
+		if o.Path != "" {
+			result = doc.Get(o.Path)
+		}
+		if !result.Exists() {
+			continue
+		}
+
+		var err error
+		if result.IsArray() {
+			result.ForEach(func(_, elem gjson.Result) bool {
+				metrics, err = p.parseObject(metrics, o, elem, now)
+				return err == nil
+			})
+		} else {
+			metrics, err = p.parseObject(metrics, o, result, now)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return metrics, nil
+}
+
+// parseObject appends a metric for every row of the object.
+func (p *Parser) parseObject(metrics []telegraf.Metric, o *object, obj gjson.Result, now time.Time) ([]telegraf.Metric, error) {
+	if !obj.IsObject() {
+		return nil, fmt.Errorf("%q must be an object or an array of objects", o.Path)
+	}
+
+	name := o.MeasurementName
+	if name == "" {
+		name = p.metricName
+	}
+
+	for _, r := range o.expand(obj, "") {
+		tags := make(map[string]string, len(p.defaultTags)+len(o.tags))
+		for k, v := range p.defaultTags {
+			tags[k] = v
+		}
+		fields := make(map[string]interface{}, len(r))
+		for key, value := range r {
+			if key == o.TimestampPath {
+				continue
+			}
+			if o.tags[key] {
+				tags[o.rename(key)] = value.String()
+				continue
+			}
+			if o.included != nil && !o.included.Match(key) {
+				continue
+			}
+			if o.excluded != nil && o.excluded.Match(key) {
+				continue
+			}
+			v, err := o.convert(key, value)
+			if err != nil {
+				return nil, err
+			}
+			fields[o.rename(key)] = v
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		timestamp := now
+		if o.TimestampPath != "" {
+			value, ok := r[o.TimestampPath]
+			if !ok {
+				return nil, fmt.Errorf("timestamp %q not found", o.TimestampPath)
+			}
+			// The string of a number keeps the precision of nanoseconds.
+			var err error
+			timestamp, err = internal.ParseTimestamp(o.TimestampFormat, value.String(), o.TimestampTimezone)
+			if err != nil {
+				return nil, fmt.Errorf("parsing timestamp %q: %w", o.TimestampPath, err)
+			}
+		}
+
+		m, err := metric.New(name, tags, fields, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// expand flattens the value into rows.  Every element of an exploded array
+// is a row of its own that shares the other key/values of the object, so an
+// empty exploded array has no rows.  Arrays that are not exploded are
+// flattened with the index as key.
+func (o *object) expand(value gjson.Result, key string) []row {
+	switch {
+	case value.IsObject():
+		rows := []row{{}}
+		value.ForEach(func(k, v gjson.Result) bool {
+			rows = product(rows, o.expand(v, join(key, k.String())))
+			return true
+		})
+		return rows
+	case value.IsArray() && o.explode[key]:
+		var rows []row
+		value.ForEach(func(_, v gjson.Result) bool {
+			rows = append(rows, o.expand(v, key)...)
+			return true
+		})
+		return rows
+	case value.IsArray():
+		rows := []row{{}}
+		i := 0
+		value.ForEach(func(_, v gjson.Result) bool {
+			rows = product(rows, o.expand(v, join(key, strconv.Itoa(i))))
+			i++
+			return true
+		})
+		return rows
+	case value.Type == gjson.Null:
+		return []row{{}}
+	default:
+		return []row{{key: value}}
+	}
+}
+
+// product returns every combination of the rows of a and b.
+func product(a, b []row) []row {
+	if len(b) == 1 && len(b[0]) == 0 {
+		return a
+	}
+	rows := make([]row, 0, len(a)*len(b))
+	for _, ra := range a {
+		for _, rb := range b {
+			r := make(row, len(ra)+len(rb))
+			for k, v := range ra {
+				r[k] = v
+			}
+			for k, v := range rb {
+				r[k] = v
+			}
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// rename returns the tag or field name of the key, the key with underscores
+// instead of dots if it is not renamed.
+func (o *object) rename(key string) string {
+	if name, ok := o.Renames[key]; ok {
+		return name
+	}
+	return strings.Replace(key, ".", "_", -1)
+}
+
+// convert returns the field value of the key, numbers are floats unless
+// another type is configured.
+func (o *object) convert(key string, value gjson.Result) (interface{}, error) {
+	typ, ok := o.Types[key]
+	if !ok {
+		switch value.Type {
+		case gjson.Number:
+			return value.Float(), nil
+		case gjson.True, gjson.False:
+			return value.Bool(), nil
+		default:
+			return value.String(), nil
+		}
+	}
+
+	var v interface{}
+	var err error
+	switch typ {
+	case "int":
+		v, err = strconv.ParseInt(value.String(), 10, 64)
+		if err != nil && value.Type == gjson.Number {
+			v, err = int64(value.Float()), nil
+		}
+	case "uint":
+		v, err = strconv.ParseUint(value.String(), 10, 64)
+		if err != nil && value.Type == gjson.Number && value.Float() >= 0 {
+			v, err = uint64(value.Float()), nil
+		}
+	case "float":
+		v, err = strconv.ParseFloat(value.String(), 64)
+	case "bool":
+		v, err = strconv.ParseBool(value.String())
+	default:
+		v = value.String()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("converting %q to %s: %w", key, typ, err)
+	}
+	return v, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("can not parse the line: %s, for data format: json_v2 ", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
@@ -0,0 +1,221 @@
+package json_v2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const devices = `
+{
+  "site": "lab",
+  "updated": 1577836800,
+  "devices": [
+    {
+      "id": "dev1",
+      "location": {"room": "101", "floor": 1},
+      "online": true,
+      "firmware": "1.2",
+      "sensors": [
+        {"name": "temp", "value": 21.5, "readings": [{"t": 1577836801000, "v": 1}, {"t": 1577836802000, "v": 2}]},
+        {"name": "hum", "value": 40, "readings": []}
+      ],
+      "history": [1, 2]
+    },
+    {
+      "id": "dev2",
+      "location": {"room": "102", "floor": 2},
+      "online": false,
+      "firmware": "2",
+      "sensors": [],
+      "history": []
+    }
+  ],
+  "summary": {"count": "2", "errors": 0}
+}
+`
+
+func newParser(t *testing.T, objects ...Object) *Parser {
+	p, err := New(&Config{MetricName: "json_v2", Objects: objects})
+	require.NoError(t, err)
+	p.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+	return p
+}
+
+func TestParseFlatten(t *testing.T) {
+	p := newParser(t, Object{
+		Path: "devices",
+		Tags: []string{"id", "location.room"},
+	})
+	metrics, err := p.Parse([]byte(devices))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("json_v2",
+			map[string]string{"id": "dev1", "location_room": "101"},
+			map[string]interface{}{
+				"location_floor":         1.0,
+				"online":                 true,
+				"firmware":               "1.2",
+				"sensors_0_name":         "temp",
+				"sensors_0_value":        21.5,
+				"sensors_0_readings_0_t": 1577836801000.0,
+				"sensors_0_readings_0_v": 1.0,
+				"sensors_0_readings_1_t": 1577836802000.0,
+				"sensors_0_readings_1_v": 2.0,
+				"sensors_1_name":         "hum",
+				"sensors_1_value":        40.0,
+				"history_0":              1.0,
+				"history_1":              2.0,
+			},
+			time.Unix(42, 0)),
+		testutil.MustMetric("json_v2",
+			map[string]string{"id": "dev2", "location_room": "102"},
+			map[string]interface{}{
+				"location_floor": 2.0,
+				"online":         false,
+				"firmware":       "2",
+			},
+			time.Unix(42, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseExplode(t *testing.T) {
+	p := newParser(t, Object{
+		Path:            "devices",
+		MeasurementName: "reading",
+		Tags:            []string{"id", "sensors.name"},
+		IncludedKeys:    []string{"sensors.readings.*", "firmware"},
+		Explode:         []string{"sensors", "sensors.readings"},
+		Renames:         map[string]string{"sensors.name": "sensor", "sensors.readings.v": "value"},
+		Types:           map[string]string{"firmware": "float", "sensors.readings.v": "int"},
+		TimestampPath:   "sensors.readings.t",
+		TimestampFormat: "unix_ms",
+	})
+	metrics, err := p.Parse([]byte(devices))
+	require.NoError(t, err)
+
+	// The hum sensor has no readings and dev2 has no sensors, the empty
+	// arrays have no rows.
+	expected := []telegraf.Metric{
+		testutil.MustMetric("reading",
+			map[string]string{"id": "dev1", "sensor": "temp"},
+			map[string]interface{}{"firmware": 1.2, "value": int64(1)},
+			time.Unix(1577836801, 0)),
+		testutil.MustMetric("reading",
+			map[string]string{"id": "dev1", "sensor": "temp"},
+			map[string]interface{}{"firmware": 1.2, "value": int64(2)},
+			time.Unix(1577836802, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseMultipleObjects(t *testing.T) {
+	p := newParser(t,
+		Object{
+			Path:            "devices",
+			MeasurementName: "device",
+			Tags:            []string{"id"},
+			IncludedKeys:    []string{"online"},
+		},
+		Object{
+			MeasurementName: "summary",
+			Tags:            []string{"site"},
+			IncludedKeys:    []string{"summary.*"},
+			Renames:         map[string]string{"summary.count": "devices"},
+			Types:           map[string]string{"summary.count": "uint"},
+			TimestampPath:   "updated",
+			TimestampFormat: "unix",
+		},
+		Object{Path: "missing"},
+	)
+	metrics, err := p.Parse([]byte(devices))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("device",
+			map[string]string{"id": "dev1"},
+			map[string]interface{}{"online": true},
+			time.Unix(42, 0)),
+		testutil.MustMetric("device",
+			map[string]string{"id": "dev2"},
+			map[string]interface{}{"online": false},
+			time.Unix(42, 0)),
+		testutil.MustMetric("summary",
+			map[string]string{"site": "lab"},
+			map[string]interface{}{"devices": uint64(2), "summary_errors": 0.0},
+			time.Unix(1577836800, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	p := newParser(t, Object{Path: "summary"})
+	p.SetDefaultTags(map[string]string{"source": "api"})
+
+	m, err := p.ParseLine(`{"summary": {"errors": 3}}`)
+	require.NoError(t, err)
+	testutil.RequireMetricEqual(t,
+		testutil.MustMetric("json_v2",
+			map[string]string{"source": "api"},
+			map[string]interface{}{"errors": 3.0},
+			time.Unix(42, 0)),
+		m)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		object Object
+		input  string
+		err    string
+	}{
+		{
+			name:   "invalid json",
+			object: Object{},
+			input:  `{"a": `,
+			err:    "invalid JSON",
+		},
+		{
+			name:   "not an object",
+			object: Object{Path: "values"},
+			input:  `{"values": [1, 2]}`,
+			err:    `"values" must be an object or an array of objects`,
+		},
+		{
+			name:   "conversion",
+			object: Object{Types: map[string]string{"a": "int"}},
+			input:  `{"a": "one"}`,
+			err:    `converting "a" to int: strconv.ParseInt: parsing "one": invalid syntax`,
+		},
+		{
+			name:   "missing timestamp",
+			object: Object{TimestampPath: "time", TimestampFormat: "unix"},
+			input:  `{"a": 1}`,
+			err:    `timestamp "time" not found`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newParser(t, tt.object)
+			_, err := p.Parse([]byte(tt.input))
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	_, err := New(&Config{})
+	require.EqualError(t, err, "at least one json_v2 object must be configured")
+
+	_, err = New(&Config{Objects: []Object{{Types: map[string]string{"a": "number"}}}})
+	require.EqualError(t, err,
+		`object 1: invalid type "number" of "a", must be one of int, uint, float, string or bool`)
+
+	_, err = New(&Config{Objects: []Object{{TimestampPath: "time"}}})
+	require.EqualError(t, err, "object 1: timestamp_format is required with timestamp_path")
+}
@@ -1,6 +1,7 @@
 package logfmt
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -224,3 +225,41 @@ func TestParseLine(t *testing.T) {
 		})
 	}
 }
+
+func TestParseStream(t *testing.T) {
+	l := NewParser("testlog", map[string]string{"host": "localhost"})
+	l.Now = func() time.Time { return time.Unix(0, 0) }
+
+	var got []telegraf.Metric
+	input := "lvl=5 msg=\"Write failed\"\n\nmethod=POST duration=7.45 host=server01\n"
+	err := l.ParseStream(strings.NewReader(input), func(m telegraf.Metric) error {
+		got = append(got, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []telegraf.Metric{
+		testutil.MustMetric(
+			"testlog",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{
+				"lvl": int64(5),
+				"msg": "Write failed",
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"testlog",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{
+				"method":   "POST",
+				"duration": 7.45,
+				"host":     "server01",
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, want, got)
+}
@@ -0,0 +1,195 @@
+package msgpack
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Parser reads the metrics written by the msgpack serializer, a sequence of
+// MessagePack maps with the keys "name", "time", "tags" and "fields".
+type Parser struct {
+	DefaultTags map[string]string
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for len(buf) > 0 {
+		var m telegraf.Metric
+		var err error
+		m, buf, err = p.readMetric(buf)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// ParseStream reads the metrics from r one map at a time and calls fn with
+// each of them.
+func (p *Parser) ParseStream(r io.Reader, fn func(telegraf.Metric) error) error {
+	reader := msgp.NewReader(r)
+	var buf bytes.Buffer
+	for {
+		buf.Reset()
+		if _, err := reader.CopyNext(&buf); err != nil {
+			if err == io.EOF {
+				if buf.Len() == 0 {
+					return nil
+				}
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		m, _, err := p.readMetric(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+}
+
+// NotLineDelimited marks the binary format as not line-delimited.
+func (p *Parser) NotLineDelimited() {}
+
+func (p *Parser) readMetric(buf []byte) (telegraf.Metric, []byte, error) {
+	n, buf, err := msgp.ReadMapHeaderBytes(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var name string
+	var timestamp int64
+	var hasTime bool
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	for i := uint32(0); i < n; i++ {
+		var key string
+		key, buf, err = msgp.ReadStringBytes(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch key {
+		case "name":
+			name, buf, err = msgp.ReadStringBytes(buf)
+		case "time":
+			timestamp, buf, err = msgp.ReadInt64Bytes(buf)
+			hasTime = true
+		case "tags":
+			buf, err = readTags(buf, tags)
+		case "fields":
+			buf, err = readFields(buf, fields)
+		default:
+			buf, err = msgp.Skip(buf)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %q: %v", key, err)
+		}
+	}
+
+	if name == "" {
+		return nil, nil, fmt.Errorf("metric without name")
+	}
+	if !hasTime {
+		return nil, nil, fmt.Errorf("metric %q without time", name)
+	}
+
+	for k, v := range p.DefaultTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+
+	m, err := metric.New(name, tags, fields, time.Unix(0, timestamp))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, buf, nil
+}
+
+func readTags(buf []byte, tags map[string]string) ([]byte, error) {
+	n, buf, err := msgp.ReadMapHeaderBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < n; i++ {
+		var key, value string
+		if key, buf, err = msgp.ReadStringBytes(buf); err != nil {
+			return nil, err
+		}
+		if value, buf, err = msgp.ReadStringBytes(buf); err != nil {
+			return nil, err
+		}
+		tags[key] = value
+	}
+	return buf, nil
+}
+
+func readFields(buf []byte, fields map[string]interface{}) ([]byte, error) {
+	n, buf, err := msgp.ReadMapHeaderBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < n; i++ {
+		var key string
+		if key, buf, err = msgp.ReadStringBytes(buf); err != nil {
+			return nil, err
+		}
+
+		var value interface{}
+		switch t := msgp.NextType(buf); t {
+		case msgp.IntType:
+			value, buf, err = msgp.ReadInt64Bytes(buf)
+		case msgp.UintType:
+			value, buf, err = msgp.ReadUint64Bytes(buf)
+		case msgp.Float64Type:
+			value, buf, err = msgp.ReadFloat64Bytes(buf)
+		case msgp.Float32Type:
+			var f float32
+			f, buf, err = msgp.ReadFloat32Bytes(buf)
+			value = float64(f)
+		case msgp.BoolType:
+			value, buf, err = msgp.ReadBoolBytes(buf)
+		case msgp.StrType:
+			value, buf, err = msgp.ReadStringBytes(buf)
+		case msgp.BinType:
+			var b []byte
+			b, buf, err = msgp.ReadBytesBytes(buf, nil)
+			value = string(b)
+		default:
+			return nil, fmt.Errorf("unsupported type %s of field %q", t, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", key, err)
+		}
+		fields[key] = value
+	}
+	return buf, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("no metric in message")
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
@@ -0,0 +1,174 @@
+package msgpack
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestRoundTrip(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "localhost", "cpu": "cpu0"},
+			map[string]interface{}{
+				"small_int":  int64(1),
+				"int":        int64(-1234567890123),
+				"small_uint": uint64(1),
+				"uint":       uint64(math.MaxUint64),
+				"float":      42.0,
+				"bool":       true,
+				"string":     "value",
+			},
+			time.Unix(1604318400, 123456789)),
+		testutil.MustMetric("mem",
+			map[string]string{},
+			map[string]interface{}{"used": int64(0)},
+			time.Unix(-1, 0)),
+	}
+
+	s, err := msgpack.NewSerializer()
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	p := &Parser{}
+	actual, err := p.Parse(buf)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, metrics, actual)
+}
+
+func TestParseStream(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(0, 0)),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"value": 2.0},
+			time.Unix(10, 0)),
+	}
+
+	s, err := msgpack.NewSerializer()
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	p := &Parser{}
+	var actual []telegraf.Metric
+	err = p.ParseStream(iotest.OneByteReader(bytes.NewReader(buf)), func(m telegraf.Metric) error {
+		actual = append(actual, m)
+		return nil
+	})
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, metrics, actual)
+
+	// A truncated stream fails after the complete metrics.
+	actual = nil
+	err = p.ParseStream(bytes.NewReader(buf[:len(buf)-1]), func(m telegraf.Metric) error {
+		actual = append(actual, m)
+		return nil
+	})
+	require.Error(t, err)
+	require.NotEqual(t, io.EOF, err)
+	testutil.RequireMetricsEqual(t, metrics[:1], actual)
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	s, err := msgpack.NewSerializer()
+	require.NoError(t, err)
+	buf, err := s.Serialize(testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0)))
+	require.NoError(t, err)
+
+	p := &Parser{}
+	p.SetDefaultTags(map[string]string{"host": "default", "region": "eu"})
+	m, err := p.ParseLine(string(buf))
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost", "region": "eu"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, []telegraf.Metric{m})
+}
+
+func TestParseForeignTypes(t *testing.T) {
+	var buf []byte
+	buf = msgp.AppendMapHeader(buf, 5)
+	buf = msgp.AppendString(buf, "fields")
+	buf = msgp.AppendMapHeader(buf, 2)
+	buf = msgp.AppendString(buf, "float32")
+	buf = msgp.AppendFloat32(buf, 1.5)
+	buf = msgp.AppendString(buf, "bin")
+	buf = msgp.AppendBytes(buf, []byte("raw"))
+	buf = msgp.AppendString(buf, "name")
+	buf = msgp.AppendString(buf, "foreign")
+	buf = msgp.AppendString(buf, "unknown")
+	buf = msgp.AppendArrayHeader(buf, 1)
+	buf = msgp.AppendInt(buf, 1)
+	buf = msgp.AppendString(buf, "time")
+	buf = msgp.AppendInt64(buf, 42)
+	buf = msgp.AppendString(buf, "tags")
+	buf = msgp.AppendMapHeader(buf, 0)
+
+	p := &Parser{}
+	metrics, err := p.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("foreign",
+			map[string]string{},
+			map[string]interface{}{"float32": 1.5, "bin": "raw"},
+			time.Unix(0, 42)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseErrors(t *testing.T) {
+	var noTime []byte
+	noTime = msgp.AppendMapHeader(noTime, 1)
+	noTime = msgp.AppendString(noTime, "name")
+	noTime = msgp.AppendString(noTime, "cpu")
+
+	var noName []byte
+	noName = msgp.AppendMapHeader(noName, 1)
+	noName = msgp.AppendString(noName, "time")
+	noName = msgp.AppendInt64(noName, 0)
+
+	var arrayField []byte
+	arrayField = msgp.AppendMapHeader(arrayField, 1)
+	arrayField = msgp.AppendString(arrayField, "fields")
+	arrayField = msgp.AppendMapHeader(arrayField, 1)
+	arrayField = msgp.AppendString(arrayField, "values")
+	arrayField = msgp.AppendArrayHeader(arrayField, 0)
+
+	tests := []struct {
+		name  string
+		input []byte
+		err   string
+	}{
+		{"missing time", noTime, `metric "cpu" without time`},
+		{"missing name", noName, "metric without name"},
+		{"unsupported field", arrayField, `reading "fields": unsupported type array of field "values"`},
+		{"truncated", noTime[:len(noTime)-1], `reading "name": msgp: too few bytes left to read object`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{}
+			_, err := p.Parse(tt.input)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package prometheusremotewrite
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/prompb"
+)
+
+// Parser decodes snappy compressed Prometheus remote write requests.
+type Parser struct {
+	// MetricVersion selects the metric layout, it follows the metric_version
+	// option of the prometheus input.  Version 1 uses the series name as
+	// measurement with a value field, version 2 uses the prometheus
+	// measurement with the series name as field key.
+	MetricVersion int
+	DefaultTags   map[string]string
+	TimeFunc      func() time.Time
+}
+
+func NewParser(metricVersion int, defaultTags map[string]string) (*Parser, error) {
+	// Version 2 is the default as it round trips with the
+	// prometheusremotewrite serializer.
+	if metricVersion == 0 {
+		metricVersion = 2
+	}
+	if metricVersion != 1 && metricVersion != 2 {
+		return nil, fmt.Errorf("unsupported metric version %d", metricVersion)
+	}
+
+	return &Parser{
+		MetricVersion: metricVersion,
+		DefaultTags:   defaultTags,
+		TimeFunc:      time.Now,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	data, err := snappy.Decode(nil, buf)
+	if err != nil {
+		return nil, fmt.Errorf("decoding snappy: %v", err)
+	}
+
+	var req prompb.WriteRequest
+	if err := req.Unmarshal(data); err != nil {
+		return nil, fmt.Errorf("unmarshaling write request: %v", err)
+	}
+
+	now := p.TimeFunc()
+	metrics := make([]telegraf.Metric, 0)
+	for _, ts := range req.Timeseries {
+		var name string
+		tags := make(map[string]string, len(ts.Labels)+len(p.DefaultTags))
+		for k, v := range p.DefaultTags {
+			tags[k] = v
+		}
+		for _, label := range ts.Labels {
+			if label.Name == "__name__" {
+				name = label.Value
+				continue
+			}
+			tags[label.Name] = label.Value
+		}
+		if name == "" {
+			return nil, fmt.Errorf("time series without metric name")
+		}
+
+		for _, sample := range ts.Samples {
+			// Stale markers and other NaN values cannot be represented
+			// as field values.
+			if math.IsNaN(sample.Value) {
+				continue
+			}
+
+			t := now
+			if sample.Timestamp != 0 {
+				t = time.Unix(0, sample.Timestamp*int64(time.Millisecond))
+			}
+
+			var m telegraf.Metric
+			if p.MetricVersion == 1 {
+				m, err = metric.New(name, tags,
+					map[string]interface{}{"value": sample.Value}, t, telegraf.Untyped)
+			} else {
+				m, err = metric.New("prometheus", tags,
+					map[string]interface{}{name: sample.Value}, t, telegraf.Untyped)
+			}
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("no metric in write request")
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
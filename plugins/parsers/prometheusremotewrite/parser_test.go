@@ -0,0 +1,173 @@
+package prometheusremotewrite
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/prompb"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheusremotewrite"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T, series ...prompb.TimeSeries) []byte {
+	req := &prompb.WriteRequest{Timeseries: series}
+	data, err := req.Marshal()
+	require.NoError(t, err)
+	return snappy.Encode(nil, data)
+}
+
+var testRequest = []prompb.TimeSeries{
+	{
+		Labels: []prompb.Label{
+			{Name: "__name__", Value: "go_gc_duration_seconds"},
+			{Name: "quantile", Value: "0.99"},
+		},
+		Samples: []prompb.Sample{
+			{Value: 0.25, Timestamp: 1614889298859},
+			{Value: math.NaN(), Timestamp: 1614889299859},
+		},
+	},
+	{
+		Labels: []prompb.Label{
+			{Name: "__name__", Value: "http_requests_total"},
+			{Name: "code", Value: "200"},
+		},
+		Samples: []prompb.Sample{
+			{Value: 42, Timestamp: 1614889298859},
+		},
+	},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		metricVersion int
+		expected      []telegraf.Metric
+	}{
+		{
+			name:          "metric version 2",
+			metricVersion: 2,
+			expected: []telegraf.Metric{
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{"quantile": "0.99", "host": "localhost"},
+					map[string]interface{}{"go_gc_duration_seconds": 0.25},
+					time.Unix(0, 1614889298859000000),
+					telegraf.Untyped,
+				),
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{"code": "200", "host": "localhost"},
+					map[string]interface{}{"http_requests_total": 42.0},
+					time.Unix(0, 1614889298859000000),
+					telegraf.Untyped,
+				),
+			},
+		},
+		{
+			name:          "metric version 1",
+			metricVersion: 1,
+			expected: []telegraf.Metric{
+				testutil.MustMetric(
+					"go_gc_duration_seconds",
+					map[string]string{"quantile": "0.99", "host": "localhost"},
+					map[string]interface{}{"value": 0.25},
+					time.Unix(0, 1614889298859000000),
+					telegraf.Untyped,
+				),
+				testutil.MustMetric(
+					"http_requests_total",
+					map[string]string{"code": "200", "host": "localhost"},
+					map[string]interface{}{"value": 42.0},
+					time.Unix(0, 1614889298859000000),
+					telegraf.Untyped,
+				),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParser(tt.metricVersion, map[string]string{"host": "localhost"})
+			require.NoError(t, err)
+
+			metrics, err := parser.Parse(encode(t, testRequest...))
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, tt.expected, metrics)
+		})
+	}
+}
+
+func TestParseLabelsOverrideDefaultTags(t *testing.T) {
+	parser, err := NewParser(0, map[string]string{"code": "default"})
+	require.NoError(t, err)
+
+	m, err := parser.ParseLine(string(encode(t, testRequest[1])))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"code": "200"}, m.Tags())
+}
+
+func TestParseInvalid(t *testing.T) {
+	parser, err := NewParser(2, nil)
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("cpu value=42"))
+	require.Error(t, err)
+
+	_, err = parser.Parse(encode(t, prompb.TimeSeries{
+		Samples: []prompb.Sample{{Value: 42, Timestamp: 1}},
+	}))
+	require.Error(t, err)
+
+	_, err = NewParser(3, nil)
+	require.Error(t, err)
+}
+
+func TestParseMissingTimestamp(t *testing.T) {
+	parser, err := NewParser(2, nil)
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	metrics, err := parser.Parse(encode(t, prompb.TimeSeries{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "up"}},
+		Samples: []prompb.Sample{{Value: 1}},
+	}))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, time.Unix(42, 0), metrics[0].Time())
+}
+
+func TestRoundTrip(t *testing.T) {
+	input := []telegraf.Metric{
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"code": "200", "method": "get"},
+			map[string]interface{}{"http_requests_total": 42.0},
+			time.Unix(0, 1614889298859000000),
+			telegraf.Untyped,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"le": "0.5"},
+			map[string]interface{}{"http_request_duration_seconds_bucket": 3.0},
+			time.Unix(0, 1614889298859000000),
+			telegraf.Untyped,
+		),
+	}
+
+	serializer, err := prometheusremotewrite.NewSerializer(prometheusremotewrite.FormatConfig{
+		MetricSortOrder: prometheusremotewrite.SortMetrics,
+	})
+	require.NoError(t, err)
+	data, err := serializer.SerializeBatch(input)
+	require.NoError(t, err)
+
+	parser, err := NewParser(2, nil)
+	require.NoError(t, err)
+	metrics, err := parser.Parse(data)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, input, metrics, testutil.SortMetrics())
+}
@@ -0,0 +1,568 @@
+package protobuf
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+const timestampType = "google.protobuf.Timestamp"
+
+type Config struct {
+	MetricName string
+	// File is a .proto file, parsed at startup.
+	File string
+	// DescriptorSet is a binary FileDescriptorSet, as written by
+	// `protoc --descriptor_set_out`.
+	DescriptorSet string
+	// ImportPaths are the directories searched for the imports of File.
+	ImportPaths []string
+	// MessageType is the fully qualified name of the message to parse.
+	MessageType string
+	// MetricSelection is the path of the messages to create metrics from.
+	// A metric is created for every element of a repeated field.
+	MetricSelection string
+	// Tags and Fields map the tag and field names to the path of the value.
+	Tags            map[string]string
+	Fields          map[string]string
+	TimestampPath   string
+	TimestampFormat string
+	DefaultTags     map[string]string
+}
+
+// Parser parses protocol buffer messages, one message per call to Parse.
+type Parser struct {
+	metricName      string
+	message         *desc.MessageDescriptor
+	selection       *path
+	tags            map[string]*path
+	fields          map[string]*path
+	timestamp       *path
+	timestampFormat string
+	defaultTags     map[string]string
+
+	// exclude are the field names of the tags and timestamp, removed when
+	// all fields of the message are added.
+	exclude []string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.MessageType == "" {
+		return nil, errors.New("protobuf_message_type is required")
+	}
+
+	files, err := loadFiles(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var message *desc.MessageDescriptor
+	for _, file := range files {
+		if message = file.FindMessage(config.MessageType); message != nil {
+			break
+		}
+	}
+	if message == nil {
+		return nil, fmt.Errorf("message type %q not found", config.MessageType)
+	}
+
+	p := &Parser{
+		metricName:      config.MetricName,
+		message:         message,
+		tags:            make(map[string]*path),
+		fields:          make(map[string]*path),
+		timestampFormat: config.TimestampFormat,
+		defaultTags:     config.DefaultTags,
+		TimeFunc:        time.Now,
+	}
+
+	p.selection, err = compileSelection(message, config.MetricSelection)
+	if err != nil {
+		return nil, fmt.Errorf("metric selection %q: %v", config.MetricSelection, err)
+	}
+
+	for name, expr := range config.Tags {
+		p.tags[name], err = compilePath(message, p.selection.target, expr)
+		if err != nil {
+			return nil, fmt.Errorf("tag %q: %v", name, err)
+		}
+		if p.tags[name].field.IsRepeated() || p.tags[name].field.GetMessageType() != nil {
+			return nil, fmt.Errorf("tag %q: %q is not a scalar field", name, expr)
+		}
+		p.exclude = append(p.exclude, p.tags[name].fieldName())
+	}
+
+	for name, expr := range config.Fields {
+		p.fields[name], err = compilePath(message, p.selection.target, expr)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", name, err)
+		}
+	}
+
+	if config.TimestampPath != "" {
+		p.timestamp, err = compilePath(message, p.selection.target, config.TimestampPath)
+		if err != nil {
+			return nil, fmt.Errorf("timestamp: %v", err)
+		}
+
+		fd := p.timestamp.field
+		switch {
+		case fd.IsRepeated():
+			return nil, fmt.Errorf("timestamp: %q is a repeated field", config.TimestampPath)
+		case fd.GetMessageType() != nil:
+			if fd.GetMessageType().GetFullyQualifiedName() != timestampType {
+				return nil, fmt.Errorf("timestamp: %q is not a %s", config.TimestampPath, timestampType)
+			}
+		case p.timestampFormat == "":
+			return nil, errors.New("protobuf_timestamp_format is required for a scalar timestamp")
+		}
+		p.exclude = append(p.exclude, p.timestamp.fieldName())
+	}
+
+	return p, nil
+}
+
+func loadFiles(config *Config) ([]*desc.FileDescriptor, error) {
+	switch {
+	case config.File != "" && config.DescriptorSet != "":
+		return nil, errors.New("only one of protobuf_file and protobuf_descriptor_set can be set")
+	case config.File != "":
+		// The file is looked up in its own directory, after the import
+		// paths, so that imports relative to the file are found.
+		parser := protoparse.Parser{
+			ImportPaths: append(append([]string{}, config.ImportPaths...), filepath.Dir(config.File)),
+		}
+		files, err := parser.ParseFiles(filepath.Base(config.File))
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %v", config.File, err)
+		}
+		return files, nil
+	case config.DescriptorSet != "":
+		buf, err := ioutil.ReadFile(config.DescriptorSet)
+		if err != nil {
+			return nil, err
+		}
+		var set dpb.FileDescriptorSet
+		if err := proto.Unmarshal(buf, &set); err != nil {
+			return nil, fmt.Errorf("decoding descriptor set %q: %v", config.DescriptorSet, err)
+		}
+		byName, err := desc.CreateFileDescriptorsFromSet(&set)
+		if err != nil {
+			return nil, fmt.Errorf("loading descriptor set %q: %v", config.DescriptorSet, err)
+		}
+		files := make([]*desc.FileDescriptor, 0, len(byName))
+		for _, file := range set.GetFile() {
+			files = append(files, byName[file.GetName()])
+		}
+		return files, nil
+	default:
+		return nil, errors.New("one of protobuf_file or protobuf_descriptor_set is required")
+	}
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	msg := dynamic.NewMessage(p.message)
+	if err := msg.Unmarshal(buf); err != nil {
+		return nil, err
+	}
+
+	now := p.TimeFunc()
+	metrics := make([]telegraf.Metric, 0)
+	for _, selected := range p.selection.selectMessages(msg) {
+		m, err := p.parseMessage(msg, selected, now)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) parseMessage(root, msg *dynamic.Message, now time.Time) (telegraf.Metric, error) {
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	for name, path := range p.tags {
+		value, ok, err := path.value(root, msg)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			tags[name] = toTag(path.field, value)
+		}
+	}
+
+	fields := make(map[string]interface{})
+	if len(p.fields) == 0 {
+		flatten(fields, "", msg)
+		for _, name := range p.exclude {
+			delete(fields, name)
+		}
+	}
+	for name, path := range p.fields {
+		value, ok, err := path.value(root, msg)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			addField(fields, name, path.field, value)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	timestamp := now
+	if p.timestamp != nil {
+		value, ok, err := p.timestamp.value(root, msg)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("timestamp %q not set", p.timestamp)
+		}
+		timestamp, err = p.parseTimestamp(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return metric.New(p.metricName, tags, fields, timestamp)
+}
+
+func (p *Parser) parseTimestamp(value interface{}) (time.Time, error) {
+	if msg := asMessage(value); msg != nil {
+		value = msg
+	}
+
+	switch v := value.(type) {
+	case *dynamic.Message:
+		seconds, err := v.TryGetFieldByName("seconds")
+		if err != nil {
+			return time.Time{}, err
+		}
+		nanos, err := v.TryGetFieldByName("nanos")
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(seconds.(int64), int64(nanos.(int32))).UTC(), nil
+	case int32:
+		value = int64(v)
+	case uint32:
+		value = int64(v)
+	case uint64:
+		value = int64(v)
+	case float32:
+		value = float64(v)
+	}
+	return internal.ParseTimestamp(p.timestampFormat, value, "")
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("no metric in message")
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+// flatten adds all set scalar fields of the message, nested messages and
+// repeated fields are added with the name of the field as prefix.
+func flatten(fields map[string]interface{}, prefix string, msg *dynamic.Message) {
+	proto3 := msg.GetMessageDescriptor().GetFile().IsProto3()
+	for _, fd := range msg.GetKnownFields() {
+		if !msg.HasField(fd) && (fd.IsRepeated() || fd.GetMessageType() != nil || !proto3) {
+			continue
+		}
+		addField(fields, prefix+fd.GetName(), fd, msg.GetField(fd))
+	}
+}
+
+// addField adds the value of the field, flattening messages and repeated
+// fields.
+func addField(fields map[string]interface{}, name string, fd *desc.FieldDescriptor, value interface{}) {
+	switch v := value.(type) {
+	case []interface{}:
+		for i, item := range v {
+			addElement(fields, name+"_"+strconv.Itoa(i), fd, item)
+		}
+	case map[interface{}]interface{}:
+		valueField := fd.GetMapValueType()
+		for key, item := range v {
+			addElement(fields, name+"_"+fmt.Sprint(key), valueField, item)
+		}
+	default:
+		addElement(fields, name, fd, value)
+	}
+}
+
+func addElement(fields map[string]interface{}, name string, fd *desc.FieldDescriptor, value interface{}) {
+	switch v := value.(type) {
+	case *dynamic.Message:
+		flatten(fields, name+"_", v)
+	case proto.Message:
+		if dm, err := dynamic.AsDynamicMessage(v); err == nil {
+			flatten(fields, name+"_", dm)
+		}
+	case int32:
+		if enum := fd.GetEnumType(); enum != nil {
+			fields[name] = enumName(fd, v)
+			return
+		}
+		fields[name] = int64(v)
+	case int64:
+		fields[name] = v
+	case uint32:
+		fields[name] = uint64(v)
+	case uint64:
+		fields[name] = v
+	case float32:
+		fields[name] = float64(v)
+	case float64:
+		fields[name] = v
+	case bool:
+		fields[name] = v
+	case string:
+		fields[name] = v
+	case []byte:
+		fields[name] = string(v)
+	}
+}
+
+func toTag(fd *desc.FieldDescriptor, value interface{}) string {
+	switch v := value.(type) {
+	case int32:
+		if fd.GetEnumType() != nil {
+			return enumName(fd, v)
+		}
+		return strconv.FormatInt(int64(v), 10)
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func enumName(fd *desc.FieldDescriptor, number int32) string {
+	if value := fd.GetEnumType().FindValueByNumber(number); value != nil {
+		return value.GetName()
+	}
+	return strconv.FormatInt(int64(number), 10)
+}
+
+// path is a compiled path expression: field names separated by dots, with an
+// optional index for repeated fields, for example "samples[0].value".  A
+// leading "/" makes the path relative to the root message instead of the
+// selected message.
+type path struct {
+	expr     string
+	absolute bool
+	segments []segment
+	// field is the descriptor of the last field of the path.
+	field *desc.FieldDescriptor
+	// target is the message type a selection path leads to.
+	target *desc.MessageDescriptor
+}
+
+type segment struct {
+	field *desc.FieldDescriptor
+	index int
+}
+
+func (p *path) String() string {
+	return p.expr
+}
+
+// fieldName is the name of the field added for the path when adding all
+// fields of the message.
+func (p *path) fieldName() string {
+	if p.absolute {
+		return ""
+	}
+	names := make([]string, 0, len(p.segments))
+	for _, s := range p.segments {
+		names = append(names, s.field.GetName())
+	}
+	return strings.Join(names, "_")
+}
+
+func parsePath(md *desc.MessageDescriptor, expr string) (*path, error) {
+	p := &path{expr: expr, target: md}
+	if expr == "" {
+		return p, nil
+	}
+
+	for _, part := range strings.Split(expr, ".") {
+		if p.target == nil {
+			return nil, fmt.Errorf("%q is not a message field", p.field.GetName())
+		}
+
+		index := -1
+		name := part
+		if i := strings.IndexByte(part, '['); i >= 0 && strings.HasSuffix(part, "]") {
+			var err error
+			index, err = strconv.Atoi(part[i+1 : len(part)-1])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index in %q", part)
+			}
+			name = part[:i]
+		}
+
+		fd := p.target.FindFieldByName(name)
+		if fd == nil {
+			return nil, fmt.Errorf("message %s has no field %q", p.target.GetFullyQualifiedName(), name)
+		}
+		if index >= 0 && (!fd.IsRepeated() || fd.IsMap()) {
+			return nil, fmt.Errorf("%q is not a repeated field", name)
+		}
+		p.segments = append(p.segments, segment{field: fd, index: index})
+		p.field = fd
+		p.target = fd.GetMessageType()
+	}
+	return p, nil
+}
+
+func compileSelection(root *desc.MessageDescriptor, expr string) (*path, error) {
+	p, err := parsePath(root, expr)
+	if err != nil {
+		return nil, err
+	}
+	if p.target == nil || (p.field != nil && p.field.IsMap()) {
+		return nil, errors.New("must lead to a message")
+	}
+	return p, nil
+}
+
+func compilePath(root, selected *desc.MessageDescriptor, expr string) (*path, error) {
+	md := selected
+	absolute := strings.HasPrefix(expr, "/")
+	if absolute {
+		md = root
+	}
+
+	p, err := parsePath(md, strings.TrimPrefix(expr, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if p.field == nil {
+		return nil, errors.New("path is empty")
+	}
+	p.expr = expr
+	p.absolute = absolute
+
+	// Only the last field can be repeated, the elements are flattened.
+	for _, s := range p.segments[:len(p.segments)-1] {
+		if s.field.IsRepeated() && s.index < 0 {
+			return nil, fmt.Errorf("repeated field %q needs an index, or use the metric selection", s.field.GetName())
+		}
+	}
+	return p, nil
+}
+
+// selectMessages returns the messages the path leads to, every element of a
+// repeated field without index is selected.
+func (p *path) selectMessages(root *dynamic.Message) []*dynamic.Message {
+	current := []*dynamic.Message{root}
+	for _, s := range p.segments {
+		var next []*dynamic.Message
+		for _, msg := range current {
+			if !msg.HasField(s.field) {
+				continue
+			}
+			value := msg.GetField(s.field)
+			if items, ok := value.([]interface{}); ok {
+				if s.index >= 0 {
+					if s.index >= len(items) {
+						continue
+					}
+					items = items[s.index : s.index+1]
+				}
+				for _, item := range items {
+					if m := asMessage(item); m != nil {
+						next = append(next, m)
+					}
+				}
+				continue
+			}
+			if m := asMessage(value); m != nil {
+				next = append(next, m)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// value returns the value of the path, relative to the root or the selected
+// message.
+func (p *path) value(root, selected *dynamic.Message) (interface{}, bool, error) {
+	msg := selected
+	if p.absolute {
+		msg = root
+	}
+
+	for i, s := range p.segments {
+		last := i == len(p.segments)-1
+		proto3 := msg.GetMessageDescriptor().GetFile().IsProto3()
+		if !msg.HasField(s.field) && (!last || s.field.IsRepeated() || s.field.GetMessageType() != nil || !proto3) {
+			return nil, false, nil
+		}
+
+		value := msg.GetField(s.field)
+		if s.index >= 0 {
+			items := value.([]interface{})
+			if s.index >= len(items) {
+				return nil, false, nil
+			}
+			value = items[s.index]
+		}
+		if last {
+			return value, true, nil
+		}
+
+		msg = asMessage(value)
+		if msg == nil {
+			return nil, false, fmt.Errorf("unexpected value %T for %q", value, s.field.GetName())
+		}
+	}
+	return nil, false, nil
+}
+
+func asMessage(value interface{}) *dynamic.Message {
+	switch v := value.(type) {
+	case *dynamic.Message:
+		return v
+	case proto.Message:
+		if dm, err := dynamic.AsDynamicMessage(v); err == nil {
+			return dm
+		}
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package protobuf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/require"
+)
+
+func loadMessage(t *testing.T, name string) *desc.MessageDescriptor {
+	parser := protoparse.Parser{ImportPaths: []string{"testdata"}}
+	files, err := parser.ParseFiles("metrics.proto")
+	require.NoError(t, err)
+	md := files[0].FindMessage(name)
+	require.NotNil(t, md)
+	return md
+}
+
+// testBatch encodes a batch with two sensors.
+func testBatch(t *testing.T) []byte {
+	batchType := loadMessage(t, "telegraf.test.Batch")
+	sensorType := batchType.FindFieldByName("sensors").GetMessageType()
+	locationType := sensorType.FindFieldByName("location").GetMessageType()
+	timestampType := batchType.FindFieldByName("time").GetMessageType()
+
+	ts := dynamic.NewMessage(timestampType)
+	ts.SetFieldByName("seconds", int64(1577836800))
+	ts.SetFieldByName("nanos", int32(500))
+
+	location := dynamic.NewMessage(locationType)
+	location.SetFieldByName("site", "lab")
+	location.SetFieldByName("lat", 52.5)
+
+	first := dynamic.NewMessage(sensorType)
+	first.SetFieldByName("name", "cpu")
+	first.SetFieldByName("kind", int32(1))
+	first.SetFieldByName("value", 42.5)
+	first.SetFieldByName("count", int64(3))
+	first.SetFieldByName("flags", uint32(7))
+	first.SetFieldByName("ok", true)
+	first.SetFieldByName("location", location)
+	first.SetFieldByName("history", []float32{1, 2})
+	first.SetFieldByName("time_ms", int64(1577836801000))
+
+	second := dynamic.NewMessage(sensorType)
+	second.SetFieldByName("name", "room")
+	second.SetFieldByName("kind", int32(2))
+	second.SetFieldByName("value", 60.0)
+	second.SetFieldByName("time_ms", int64(1577836802000))
+
+	batch := dynamic.NewMessage(batchType)
+	batch.SetFieldByName("host", "server01")
+	batch.SetFieldByName("time", ts)
+	batch.SetFieldByName("sensors", []*dynamic.Message{first, second})
+
+	buf, err := batch.Marshal()
+	require.NoError(t, err)
+	return buf
+}
+
+func TestParseSelection(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:      "sensors",
+		File:            "testdata/metrics.proto",
+		ImportPaths:     []string{"testdata"},
+		MessageType:     "telegraf.test.Batch",
+		MetricSelection: "sensors",
+		Tags: map[string]string{
+			"host": "/host",
+			"name": "name",
+			"kind": "kind",
+		},
+		Fields: map[string]string{
+			"value": "value",
+			"site":  "location.site",
+		},
+		TimestampPath: "/time",
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(testBatch(t))
+	require.NoError(t, err)
+
+	ts := time.Unix(1577836800, 500).UTC()
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sensors",
+			map[string]string{"host": "server01", "name": "cpu", "kind": "TEMPERATURE"},
+			map[string]interface{}{"value": 42.5, "site": "lab"},
+			ts,
+		),
+		testutil.MustMetric("sensors",
+			map[string]string{"host": "server01", "name": "room", "kind": "HUMIDITY"},
+			map[string]interface{}{"value": 60.0},
+			ts,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseAllFields(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:      "sensors",
+		DescriptorSet:   "testdata/metrics.pb",
+		MessageType:     "telegraf.test.Batch",
+		MetricSelection: "sensors[0]",
+		Tags:            map[string]string{"name": "name"},
+		TimestampPath:   "time_ms",
+		TimestampFormat: "unix_ms",
+		DefaultTags:     map[string]string{"source": "test"},
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(testBatch(t))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sensors",
+			map[string]string{"name": "cpu", "source": "test"},
+			map[string]interface{}{
+				"kind":          "TEMPERATURE",
+				"value":         42.5,
+				"count":         int64(3),
+				"flags":         uint64(7),
+				"ok":            true,
+				"location_site": "lab",
+				"location_lat":  52.5,
+				"location_lon":  0.0,
+				"history_0":     1.0,
+				"history_1":     2.0,
+			},
+			time.Unix(1577836801, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseRootMessage(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:  "batch",
+		File:        "testdata/metrics.proto",
+		ImportPaths: []string{"testdata"},
+		MessageType: "telegraf.test.Batch",
+		Tags:        map[string]string{"host": "host"},
+		Fields: map[string]string{
+			"first":   "sensors[0].value",
+			"missing": "sensors[5].value",
+		},
+	})
+	require.NoError(t, err)
+	now := time.Unix(0, 0)
+	parser.TimeFunc = func() time.Time { return now }
+
+	metrics, err := parser.Parse(testBatch(t))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("batch",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"first": 42.5},
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseInvalidMessage(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:  "batch",
+		File:        "testdata/metrics.proto",
+		ImportPaths: []string{"testdata"},
+		MessageType: "telegraf.test.Batch",
+	})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte{0xff, 0xff, 0xff})
+	require.Error(t, err)
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{
+			name:   "no message type",
+			config: Config{File: "testdata/metrics.proto"},
+		},
+		{
+			name:   "no schema",
+			config: Config{MessageType: "telegraf.test.Batch"},
+		},
+		{
+			name: "unknown message type",
+			config: Config{
+				DescriptorSet: "testdata/metrics.pb",
+				MessageType:   "telegraf.test.Unknown",
+			},
+		},
+		{
+			name: "unknown field",
+			config: Config{
+				DescriptorSet: "testdata/metrics.pb",
+				MessageType:   "telegraf.test.Batch",
+				Fields:        map[string]string{"value": "sensors[0].unknown"},
+			},
+		},
+		{
+			name: "repeated field without index",
+			config: Config{
+				DescriptorSet: "testdata/metrics.pb",
+				MessageType:   "telegraf.test.Batch",
+				Fields:        map[string]string{"value": "sensors.value"},
+			},
+		},
+		{
+			name: "selection of scalar",
+			config: Config{
+				DescriptorSet:   "testdata/metrics.pb",
+				MessageType:     "telegraf.test.Batch",
+				MetricSelection: "host",
+			},
+		},
+		{
+			name: "message as tag",
+			config: Config{
+				DescriptorSet:   "testdata/metrics.pb",
+				MessageType:     "telegraf.test.Batch",
+				MetricSelection: "sensors",
+				Tags:            map[string]string{"location": "location"},
+			},
+		},
+		{
+			name: "scalar timestamp without format",
+			config: Config{
+				DescriptorSet:   "testdata/metrics.pb",
+				MessageType:     "telegraf.test.Batch",
+				MetricSelection: "sensors",
+				TimestampPath:   "time_ms",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&tt.config)
+			require.Error(t, err)
+		})
+	}
+}
@@ -2,8 +2,11 @@ package parsers
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/avro"
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
 	"github.com/influxdata/telegraf/plugins/parsers/csv"
 	"github.com/influxdata/telegraf/plugins/parsers/dropwizard"
@@ -12,10 +15,15 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers/grok"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json"
+	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
 	"github.com/influxdata/telegraf/plugins/parsers/logfmt"
+	"github.com/influxdata/telegraf/plugins/parsers/msgpack"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
+	"github.com/influxdata/telegraf/plugins/parsers/prometheusremotewrite"
+	"github.com/influxdata/telegraf/plugins/parsers/protobuf"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
 	"github.com/influxdata/telegraf/plugins/parsers/wavefront"
+	"github.com/influxdata/telegraf/plugins/parsers/xpath"
 )
 
 type ParserFunc func() (Parser, error)
@@ -56,6 +64,54 @@ type Parser interface {
 	SetDefaultTags(tags map[string]string)
 }
 
+// StreamParser is a Parser that is able to parse metrics from a stream
+// without reading the whole stream into memory first.
+type StreamParser interface {
+	Parser
+
+	// ParseStream reads the data from r and calls fn with each metric as soon
+	// as it is parsed.  Parsing stops at the first error returned by the
+	// parser or by fn; the metrics passed to fn until then are not recalled.
+	//
+	// Must be thread-safe.
+	ParseStream(r io.Reader, fn func(telegraf.Metric) error) error
+}
+
+// NotLineDelimitedParser is a StreamParser of a format that can not be split
+// into lines, such as a binary format.  Inputs reading a stream must pass it
+// to ParseStream as a whole instead of line by line.
+type NotLineDelimitedParser interface {
+	StreamParser
+
+	// NotLineDelimited marks the format as not line-delimited, it does
+	// nothing.
+	NotLineDelimited()
+}
+
+// ParseStream parses the metrics read from r with the parser and calls fn
+// with each of them.  If the parser is a StreamParser the data is streamed,
+// otherwise r is read into memory and parsed at once.
+func ParseStream(parser Parser, r io.Reader, fn func(telegraf.Metric) error) error {
+	if sp, ok := parser.(StreamParser); ok {
+		return sp.ParseStream(r, fn)
+	}
+
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	metrics, err := parser.Parse(buf)
+	if err != nil {
+		return err
+	}
+	for _, m := range metrics {
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Config is a struct that covers the data types needed for all parser types,
 // and can be used to instantiate _any_ of the parsers.
 type Config struct {
@@ -91,6 +147,9 @@ type Config struct {
 	// Whether to continue if a JSON object can't be coerced
 	JSONStrict bool `toml:"json_strict"`
 
+	// JSONV2Objects are the `[[json_v2.object]]` sections of the json_v2 format
+	JSONV2Objects []json_v2.Object `toml:"json_v2"`
+
 	// Authentication file for collectd
 	CollectdAuthFile string `toml:"collectd_auth_file"`
 	// One of none (default), sign, or encrypt
@@ -148,6 +207,34 @@ type Config struct {
 
 	// FormData configuration
 	FormUrlencodedTagKeys []string `toml:"form_urlencoded_tag_keys"`
+
+	// protobuf configuration
+	ProtobufFile            string            `toml:"protobuf_file"`
+	ProtobufDescriptorSet   string            `toml:"protobuf_descriptor_set"`
+	ProtobufImportPaths     []string          `toml:"protobuf_import_paths"`
+	ProtobufMessageType     string            `toml:"protobuf_message_type"`
+	ProtobufMetricSelection string            `toml:"protobuf_metric_selection"`
+	ProtobufTags            map[string]string `toml:"protobuf_tags"`
+	ProtobufFields          map[string]string `toml:"protobuf_fields"`
+	ProtobufTimestampPath   string            `toml:"protobuf_timestamp_path"`
+	ProtobufTimestampFormat string            `toml:"protobuf_timestamp_format"`
+
+	// avro configuration
+	AvroSchemaRegistry   string   `toml:"avro_schema_registry"`
+	AvroSchema           string   `toml:"avro_schema"`
+	AvroMeasurement      string   `toml:"avro_measurement"`
+	AvroMeasurementField string   `toml:"avro_measurement_field"`
+	AvroTags             []string `toml:"avro_tags"`
+	AvroFields           []string `toml:"avro_fields"`
+	AvroTimestamp        string   `toml:"avro_timestamp"`
+	AvroTimestampFormat  string   `toml:"avro_timestamp_format"`
+
+	// prometheus remote write configuration
+	PrometheusMetricVersion int `toml:"prometheus_metric_version"`
+
+	// XPathConfig are the `[[xpath]]` selector groups of the xml and
+	// xpath_json formats
+	XPathConfig []xpath.Config `toml:"xpath"`
 }
 
 // NewParser returns a Parser interface based on the given config.
@@ -170,6 +257,14 @@ func NewParser(config *Config) (Parser, error) {
 				Strict:       config.JSONStrict,
 			},
 		)
+	case "json_v2":
+		parser, err = json_v2.New(
+			&json_v2.Config{
+				MetricName:  config.MetricName,
+				Objects:     config.JSONV2Objects,
+				DefaultTags: config.DefaultTags,
+			},
+		)
 	case "value":
 		parser, err = NewValueParser(config.MetricName,
 			config.DataType, config.DefaultTags)
@@ -226,12 +321,62 @@ func NewParser(config *Config) (Parser, error) {
 		return csv.NewParser(config)
 	case "logfmt":
 		parser, err = NewLogFmtParser(config.MetricName, config.DefaultTags)
+	case "msgpack":
+		parser, err = NewMsgpackParser(config.DefaultTags)
 	case "form_urlencoded":
 		parser, err = NewFormUrlencodedParser(
 			config.MetricName,
 			config.DefaultTags,
 			config.FormUrlencodedTagKeys,
 		)
+	case "protobuf":
+		parser, err = protobuf.New(
+			&protobuf.Config{
+				MetricName:      config.MetricName,
+				File:            config.ProtobufFile,
+				DescriptorSet:   config.ProtobufDescriptorSet,
+				ImportPaths:     config.ProtobufImportPaths,
+				MessageType:     config.ProtobufMessageType,
+				MetricSelection: config.ProtobufMetricSelection,
+				Tags:            config.ProtobufTags,
+				Fields:          config.ProtobufFields,
+				TimestampPath:   config.ProtobufTimestampPath,
+				TimestampFormat: config.ProtobufTimestampFormat,
+				DefaultTags:     config.DefaultTags,
+			},
+		)
+	case "avro":
+		parser, err = avro.New(
+			&avro.Config{
+				MetricName:       config.MetricName,
+				SchemaRegistry:   config.AvroSchemaRegistry,
+				Schema:           config.AvroSchema,
+				Measurement:      config.AvroMeasurement,
+				MeasurementField: config.AvroMeasurementField,
+				Tags:             config.AvroTags,
+				Fields:           config.AvroFields,
+				Timestamp:        config.AvroTimestamp,
+				TimestampFormat:  config.AvroTimestampFormat,
+				DefaultTags:      config.DefaultTags,
+			},
+		)
+	case "prometheusremotewrite":
+		parser, err = prometheusremotewrite.NewParser(
+			config.PrometheusMetricVersion,
+			config.DefaultTags,
+		)
+	case "xml":
+		parser, err = xpath.NewXMLParser(
+			config.MetricName,
+			config.XPathConfig,
+			config.DefaultTags,
+		)
+	case "xpath_json":
+		parser, err = xpath.NewJSONParser(
+			config.MetricName,
+			config.XPathConfig,
+			config.DefaultTags,
+		)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -324,6 +469,10 @@ func NewLogFmtParser(metricName string, defaultTags map[string]string) (Parser,
 	return logfmt.NewParser(metricName, defaultTags), nil
 }
 
+func NewMsgpackParser(defaultTags map[string]string) (Parser, error) {
+	return &msgpack.Parser{DefaultTags: defaultTags}, nil
+}
+
 func NewWavefrontParser(defaultTags map[string]string) (Parser, error) {
 	return wavefront.NewWavefrontParser(defaultTags), nil
 }
@@ -0,0 +1,72 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewParserStreaming(t *testing.T) {
+	for _, format := range []string{"influx", "json", "csv", "logfmt"} {
+		parser, err := NewParser(&Config{
+			DataFormat:        format,
+			MetricName:        "test",
+			CSVHeaderRowCount: 1,
+		})
+		require.NoError(t, err)
+		require.Implements(t, (*StreamParser)(nil), parser, format)
+	}
+}
+
+func TestNewParserNotLineDelimited(t *testing.T) {
+	parser, err := NewParser(&Config{DataFormat: "msgpack"})
+	require.NoError(t, err)
+	require.Implements(t, (*NotLineDelimitedParser)(nil), parser)
+
+	parser, err = NewParser(&Config{DataFormat: "influx"})
+	require.NoError(t, err)
+	_, ok := parser.(NotLineDelimitedParser)
+	require.False(t, ok)
+}
+
+func TestParseStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		input  string
+	}{
+		{
+			name:   "streaming",
+			config: &Config{DataFormat: "influx"},
+			input:  "test value=42i\n",
+		},
+		{
+			name: "not streaming",
+			config: &Config{
+				DataFormat: "value",
+				MetricName: "test",
+				DataType:   "integer",
+			},
+			input: "42\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParser(tt.config)
+			require.NoError(t, err)
+
+			var metrics []telegraf.Metric
+			err = ParseStream(parser, strings.NewReader(tt.input), func(m telegraf.Metric) error {
+				metrics = append(metrics, m)
+				return nil
+			})
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.Equal(t, "test", metrics[0].Name())
+			require.Equal(t, map[string]interface{}{"value": int64(42)}, metrics[0].Fields())
+		})
+	}
+}
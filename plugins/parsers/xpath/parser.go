@@ -0,0 +1,345 @@
+package xpath
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antchfx/jsonquery"
+	"github.com/antchfx/xmlquery"
+	path "github.com/antchfx/xpath"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Config is a `[[xpath]]` selector group.  All expressions except the
+// selection are relative to the selected node, an expression starting with
+// a `/` is relative to the document instead.
+type Config struct {
+	// Selection selects the nodes that are turned into metrics, the whole
+	// document is a single metric if unset.
+	Selection         string            `toml:"metric_selection"`
+	MetricName        string            `toml:"metric_name"`
+	Timestamp         string            `toml:"timestamp"`
+	TimestampFormat   string            `toml:"timestamp_format"`
+	TimestampTimezone string            `toml:"timestamp_timezone"`
+	Tags              map[string]string `toml:"tags"`
+	// Fields have the type of the result of their expression, the string
+	// value of the nodes is converted for FieldsInt and FieldsFloat.
+	Fields      map[string]string `toml:"fields"`
+	FieldsInt   map[string]string `toml:"fields_int"`
+	FieldsFloat map[string]string `toml:"fields_float"`
+}
+
+// document parses a document into the root of its node tree.
+type document func(buf []byte) (path.NodeNavigator, error)
+
+func parseXML(buf []byte) (path.NodeNavigator, error) {
+	doc, err := xmlquery.Parse(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	return xmlquery.CreateXPathNavigator(doc), nil
+}
+
+func parseJSON(buf []byte) (path.NodeNavigator, error) {
+	doc, err := jsonquery.Parse(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	return jsonquery.CreateXPathNavigator(doc), nil
+}
+
+// selector is a compiled Config.
+type selector struct {
+	selection   *path.Expr
+	metricName  *path.Expr
+	timestamp   *path.Expr
+	tags        map[string]*path.Expr
+	fields      map[string]*path.Expr
+	fieldsInt   map[string]*path.Expr
+	fieldsFloat map[string]*path.Expr
+
+	timestampFormat   string
+	timestampTimezone string
+}
+
+// Parser creates metrics from the nodes of XML documents, or of JSON
+// documents in the xpath_json format, selected with XPath expressions.
+type Parser struct {
+	metricName  string
+	document    document
+	defaultTags map[string]string
+
+	// mu serializes the evaluation of the selectors, the compiled
+	// expressions keep state and are not safe for concurrent use.
+	mu        sync.Mutex
+	selectors []*selector
+
+	TimeFunc func() time.Time
+}
+
+// NewXMLParser returns a parser for XML documents.
+func NewXMLParser(metricName string, configs []Config, defaultTags map[string]string) (*Parser, error) {
+	return newParser(metricName, parseXML, configs, defaultTags)
+}
+
+// NewJSONParser returns a parser for JSON documents.  The objects and
+// arrays are elements with the keys as name, array elements have no name.
+func NewJSONParser(metricName string, configs []Config, defaultTags map[string]string) (*Parser, error) {
+	return newParser(metricName, parseJSON, configs, defaultTags)
+}
+
+func newParser(metricName string, doc document, configs []Config, defaultTags map[string]string) (*Parser, error) {
+	if len(configs) == 0 {
+		return nil, errors.New("at least one xpath section must be configured")
+	}
+
+	p := &Parser{
+		metricName:  metricName,
+		document:    doc,
+		defaultTags: defaultTags,
+		TimeFunc:    time.Now,
+	}
+	for i, config := range configs {
+		s, err := compile(config)
+		if err != nil {
+			return nil, fmt.Errorf("xpath %d: %w", i+1, err)
+		}
+		p.selectors = append(p.selectors, s)
+	}
+	return p, nil
+}
+
+func compile(config Config) (*selector, error) {
+	s := &selector{
+		timestampFormat:   config.TimestampFormat,
+		timestampTimezone: config.TimestampTimezone,
+	}
+	if config.Timestamp != "" && config.TimestampFormat == "" {
+		return nil, errors.New("timestamp_format is required with timestamp")
+	}
+
+	var err error
+	for _, e := range []struct {
+		expr   string
+		target **path.Expr
+	}{
+		{config.Selection, &s.selection},
+		{config.MetricName, &s.metricName},
+		{config.Timestamp, &s.timestamp},
+	} {
+		if e.expr == "" {
+			continue
+		}
+		if *e.target, err = path.Compile(e.expr); err != nil {
+			return nil, fmt.Errorf("invalid expression %q: %w", e.expr, err)
+		}
+	}
+
+	if s.tags, err = compileAll(config.Tags); err != nil {
+		return nil, err
+	}
+	if s.fields, err = compileAll(config.Fields); err != nil {
+		return nil, err
+	}
+	if s.fieldsInt, err = compileAll(config.FieldsInt); err != nil {
+		return nil, err
+	}
+	if s.fieldsFloat, err = compileAll(config.FieldsFloat); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func compileAll(exprs map[string]string) (map[string]*path.Expr, error) {
+	compiled := make(map[string]*path.Expr, len(exprs))
+	for name, expr := range exprs {
+		e, err := path.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression %q of %q: %w", expr, name, err)
+		}
+		compiled[name] = e
+	}
+	return compiled, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	root, err := p.document(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.TimeFunc()
+	metrics := make([]telegraf.Metric, 0)
+	for _, s := range p.selectors {
+		nodes := []path.NodeNavigator{root}
+		if s.selection != nil {
+			nodes = nodes[:0]
+			iter := s.selection.Select(root.Copy())
+			for iter.MoveNext() {
+				nodes = append(nodes, iter.Current().Copy())
+			}
+		}
+
+		for _, node := range nodes {
+			m, err := p.parseNode(s, node, now)
+			if err != nil {
+				return nil, err
+			}
+			if m != nil {
+				metrics = append(metrics, m)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) parseNode(s *selector, node path.NodeNavigator, now time.Time) (telegraf.Metric, error) {
+	name := p.metricName
+	if s.metricName != nil {
+		if v, ok := evaluate(s.metricName, node); ok {
+			name = toString(v)
+		}
+	}
+
+	tags := make(map[string]string, len(p.defaultTags)+len(s.tags))
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	for key, expr := range s.tags {
+		if v, ok := evaluate(expr, node); ok {
+			tags[key] = toString(v)
+		}
+	}
+
+	fields := make(map[string]interface{}, len(s.fields)+len(s.fieldsInt)+len(s.fieldsFloat))
+	for key, expr := range s.fields {
+		if v, ok := evaluate(expr, node); ok {
+			fields[key] = v
+		}
+	}
+	for key, expr := range s.fieldsInt {
+		v, ok := evaluate(expr, node)
+		if !ok {
+			continue
+		}
+		i, err := toInt(v)
+		if err != nil {
+			return nil, fmt.Errorf("converting field %q to integer: %w", key, err)
+		}
+		fields[key] = i
+	}
+	for key, expr := range s.fieldsFloat {
+		v, ok := evaluate(expr, node)
+		if !ok {
+			continue
+		}
+		f, err := toFloat(v)
+		if err != nil {
+			return nil, fmt.Errorf("converting field %q to float: %w", key, err)
+		}
+		fields[key] = f
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	timestamp := now
+	if s.timestamp != nil {
+		v, ok := evaluate(s.timestamp, node)
+		if !ok {
+			return nil, fmt.Errorf("timestamp %q not found", s.timestamp)
+		}
+		var err error
+		timestamp, err = internal.ParseTimestamp(s.timestampFormat, toString(v), s.timestampTimezone)
+		if err != nil {
+			return nil, fmt.Errorf("parsing timestamp %q: %w", s.timestamp, err)
+		}
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// evaluate returns the result of the expression, a number, boolean or
+// string.  A node set is the string value of its first node, the result is
+// not ok if the node set is empty.
+func evaluate(expr *path.Expr, node path.NodeNavigator) (interface{}, bool) {
+	switch v := expr.Evaluate(node.Copy()).(type) {
+	case *path.NodeIterator:
+		if !v.MoveNext() {
+			return nil, false
+		}
+		return v.Current().Value(), true
+	default:
+		return v, true
+	}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toInt(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case float64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("can not parse the line: %s, for data format: xpath ", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
@@ -0,0 +1,245 @@
+package xpath
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const upsStatus = `<?xml version="1.0"?>
+<Gateway>
+  <Name>ups-manager</Name>
+  <Timestamp>2020-11-02T12:00:00Z</Timestamp>
+  <Bus>
+    <Sensor name="Battery A" mode="ok">
+      <Variable temperature="20.0" power="100" consumers="3"/>
+    </Sensor>
+    <Sensor name="Battery B" mode="failed">
+      <Variable temperature="42.5" power="0" consumers="0"/>
+    </Sensor>
+    <Sensor name="Spare" mode="off"/>
+  </Bus>
+  <Summary uptime="3600"/>
+</Gateway>
+`
+
+func TestParseXML(t *testing.T) {
+	p, err := NewXMLParser("xml", []Config{
+		{
+			Selection:       "/Gateway/Bus/Sensor",
+			MetricName:      "string('sensor')",
+			Timestamp:       "/Gateway/Timestamp",
+			TimestampFormat: "2006-01-02T15:04:05Z",
+			Tags: map[string]string{
+				"name":    "substring-after(@name, ' ')",
+				"gateway": "/Gateway/Name",
+			},
+			Fields: map[string]string{
+				"power": "number(Variable/@power)",
+				"ok":    "@mode = 'ok'",
+				"mode":  "@mode",
+			},
+			FieldsInt: map[string]string{
+				"consumers": "Variable/@consumers",
+			},
+			FieldsFloat: map[string]string{
+				"temperature": "Variable/@temperature",
+			},
+		},
+		{
+			Fields: map[string]string{
+				"sensors": "count(/Gateway/Bus/Sensor)",
+			},
+			FieldsInt: map[string]string{
+				"uptime": "/Gateway/Summary/@uptime",
+			},
+		},
+	}, nil)
+	require.NoError(t, err)
+	p.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	metrics, err := p.Parse([]byte(upsStatus))
+	require.NoError(t, err)
+
+	ts := time.Date(2020, 11, 2, 12, 0, 0, 0, time.UTC)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sensor",
+			map[string]string{"name": "A", "gateway": "ups-manager"},
+			map[string]interface{}{
+				"temperature": 20.0,
+				"power":       100.0,
+				"ok":          true,
+				"mode":        "ok",
+				"consumers":   int64(3),
+			},
+			ts),
+		testutil.MustMetric("sensor",
+			map[string]string{"name": "B", "gateway": "ups-manager"},
+			map[string]interface{}{
+				"temperature": 42.5,
+				"power":       0.0,
+				"ok":          false,
+				"mode":        "failed",
+				"consumers":   int64(0),
+			},
+			ts),
+		testutil.MustMetric("sensor",
+			map[string]string{"name": "", "gateway": "ups-manager"},
+			map[string]interface{}{
+				"power": 0.0,
+				"ok":    false,
+				"mode":  "off",
+			},
+			ts),
+		testutil.MustMetric("xml",
+			map[string]string{},
+			map[string]interface{}{
+				"sensors": 3.0,
+				"uptime":  int64(3600),
+			},
+			time.Unix(42, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseJSON(t *testing.T) {
+	p, err := NewJSONParser("xpath_json", []Config{
+		{
+			Selection:       "//devices/*",
+			Timestamp:       "/updated",
+			TimestampFormat: "unix",
+			Tags: map[string]string{
+				"id": "id",
+			},
+			Fields: map[string]string{
+				"online": "online = 'true'",
+			},
+			FieldsFloat: map[string]string{
+				"temperature": "temperature",
+			},
+		},
+	}, map[string]string{"source": "api"})
+	require.NoError(t, err)
+
+	metrics, err := p.Parse([]byte(`{
+		"updated": 1604318400,
+		"devices": [
+			{"id": "dev1", "temperature": 20.5, "online": true},
+			{"id": "dev2", "online": false}
+		]
+	}`))
+	require.NoError(t, err)
+
+	ts := time.Unix(1604318400, 0)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("xpath_json",
+			map[string]string{"id": "dev1", "source": "api"},
+			map[string]interface{}{"temperature": 20.5, "online": true},
+			ts),
+		testutil.MustMetric("xpath_json",
+			map[string]string{"id": "dev2", "source": "api"},
+			map[string]interface{}{"online": false},
+			ts),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseConcurrent(t *testing.T) {
+	p, err := NewXMLParser("xml", []Config{
+		{
+			Selection: "/Gateway/Bus/Sensor",
+			Tags: map[string]string{
+				"name": "@name",
+			},
+			Fields: map[string]string{
+				"power": "number(Variable/@power)",
+			},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	// The require functions must not be called outside of the test
+	// goroutine, so the errors are collected and checked afterwards.
+	errs := make(chan error, 4*50)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				metrics, err := p.Parse([]byte(upsStatus))
+				if err != nil {
+					errs <- err
+					continue
+				}
+				if len(metrics) != 3 {
+					errs <- fmt.Errorf("expected 3 metrics, got %d", len(metrics))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		input  string
+		err    string
+	}{
+		{
+			name:   "invalid xml",
+			config: Config{Fields: map[string]string{"a": "a"}},
+			input:  `<a>`,
+			err:    "XML syntax error on line 1: unexpected EOF",
+		},
+		{
+			name:   "integer conversion",
+			config: Config{FieldsInt: map[string]string{"a": "/a"}},
+			input:  `<a>one</a>`,
+			err:    `converting field "a" to integer: strconv.ParseInt: parsing "one": invalid syntax`,
+		},
+		{
+			name:   "float conversion",
+			config: Config{FieldsFloat: map[string]string{"a": "/a"}},
+			input:  `<a>1.5.0</a>`,
+			err:    `converting field "a" to float: strconv.ParseFloat: parsing "1.5.0": invalid syntax`,
+		},
+		{
+			name:   "missing timestamp",
+			config: Config{Fields: map[string]string{"a": "/a"}, Timestamp: "/time", TimestampFormat: "unix"},
+			input:  `<a>1</a>`,
+			err:    `timestamp "/time" not found`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewXMLParser("xml", []Config{tt.config}, nil)
+			require.NoError(t, err)
+			_, err = p.Parse([]byte(tt.input))
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	_, err := NewXMLParser("xml", nil, nil)
+	require.EqualError(t, err, "at least one xpath section must be configured")
+
+	_, err = NewXMLParser("xml", []Config{{Selection: "//["}}, nil)
+	require.Error(t, err)
+
+	_, err = NewXMLParser("xml", []Config{{Timestamp: "/time"}}, nil)
+	require.EqualError(t, err, "xpath 1: timestamp_format is required with timestamp")
+}
@@ -0,0 +1,83 @@
+package encrypted_file
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/99designs/keyring"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/redact"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  ## Unique identifier of the store, used in references as "@{<id>:<key>}".
+  id = "keyring"
+
+  ## Directory of the keyring, containing one encrypted file per secret.
+  directory = "/etc/telegraf/secrets"
+
+  ## Password used to decrypt the secrets, use an environment variable to
+  ## keep it out of the configuration file.
+  password = "${TELEGRAF_KEYRING_PASSWORD}"
+`
+
+// EncryptedFile reads secrets from the JOSE encrypted files of the "file"
+// backend of github.com/99designs/keyring, the format used for example by
+// aws-vault.
+type EncryptedFile struct {
+	Directory string `toml:"directory"`
+	Password  string `toml:"password"`
+
+	ring keyring.Keyring
+}
+
+func (e *EncryptedFile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *EncryptedFile) Description() string {
+	return "Read secrets from an encrypted file keyring"
+}
+
+func (e *EncryptedFile) Init() error {
+	if e.Directory == "" {
+		return errors.New("directory is required")
+	}
+	if e.Password == "" {
+		return errors.New("password is required")
+	}
+	redact.Add(e.Password)
+
+	ring, err := keyring.Open(keyring.Config{
+		AllowedBackends:  []keyring.BackendType{keyring.FileBackend},
+		FileDir:          e.Directory,
+		FilePasswordFunc: e.password,
+	})
+	if err != nil {
+		return fmt.Errorf("opening keyring: %v", err)
+	}
+	e.ring = ring
+	return nil
+}
+
+func (e *EncryptedFile) password(string) (string, error) {
+	return e.Password, nil
+}
+
+func (e *EncryptedFile) Get(key string) ([]byte, error) {
+	item, err := e.ring.Get(key)
+	if err != nil {
+		if err == keyring.ErrKeyNotFound {
+			return nil, fmt.Errorf("secret %q not found", key)
+		}
+		return nil, fmt.Errorf("getting secret %q: %v", key, err)
+	}
+	return item.Data, nil
+}
+
+func init() {
+	secretstores.Add("encrypted_file", func() telegraf.SecretStore {
+		return &EncryptedFile{}
+	})
+}
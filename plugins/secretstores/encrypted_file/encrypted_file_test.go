@@ -0,0 +1,48 @@
+package encrypted_file
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/99designs/keyring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyring")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Create the secret the same way other keyring users do.
+	ring, err := keyring.Open(keyring.Config{
+		AllowedBackends: []keyring.BackendType{keyring.FileBackend},
+		FileDir:         dir,
+		FilePasswordFunc: func(string) (string, error) {
+			return "correct horse", nil
+		},
+	})
+	require.NoError(t, err)
+	err = ring.Set(keyring.Item{Key: "password", Data: []byte("hunter2")})
+	require.NoError(t, err)
+
+	plugin := &EncryptedFile{Directory: dir, Password: "correct horse"}
+	require.NoError(t, plugin.Init())
+
+	secret, err := plugin.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, []byte("hunter2"), secret)
+
+	_, err = plugin.Get("missing")
+	require.Error(t, err)
+
+	plugin = &EncryptedFile{Directory: dir, Password: "wrong"}
+	require.NoError(t, plugin.Init())
+	_, err = plugin.Get("password")
+	require.Error(t, err)
+}
+
+func TestInitRequiresPassword(t *testing.T) {
+	plugin := &EncryptedFile{Directory: "/etc/telegraf/secrets"}
+	require.Error(t, plugin.Init())
+}
@@ -0,0 +1,65 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  ## Unique identifier of the store, used in references as "@{<id>:<key>}".
+  id = "vault"
+
+  ## Command to run, the key is appended as the last argument.  The secret is
+  ## read from the standard output with trailing newlines removed.
+  command = ["/usr/bin/pass", "show"]
+
+  ## Timeout for the command to complete.
+  # timeout = "5s"
+`
+
+type Exec struct {
+	Command []string          `toml:"command"`
+	Timeout internal.Duration `toml:"timeout"`
+}
+
+func (e *Exec) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Exec) Description() string {
+	return "Read secrets from the output of a command"
+}
+
+func (e *Exec) Init() error {
+	if len(e.Command) == 0 {
+		return errors.New("command is required")
+	}
+	return nil
+}
+
+func (e *Exec) Get(key string) ([]byte, error) {
+	args := append(e.Command[1:len(e.Command):len(e.Command)], key)
+	cmd := exec.Command(e.Command[0], args...)
+
+	out, err := internal.StdOutputTimeout(cmd, e.Timeout.Duration)
+	if err != nil {
+		// The output is not included as it might contain the secret.
+		return nil, fmt.Errorf("getting secret %q: %v", key, err)
+	}
+	return bytes.TrimRight(out, "\r\n"), nil
+}
+
+func init() {
+	secretstores.Add("exec", func() telegraf.SecretStore {
+		return &Exec{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
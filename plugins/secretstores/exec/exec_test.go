@@ -0,0 +1,39 @@
+// +build !windows
+
+package exec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	plugin := &Exec{
+		Command: []string{"echo", "secret-for"},
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+	require.NoError(t, plugin.Init())
+
+	secret, err := plugin.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret-for password"), secret)
+}
+
+func TestGetCommandFails(t *testing.T) {
+	plugin := &Exec{
+		Command: []string{"false"},
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+	require.NoError(t, plugin.Init())
+
+	_, err := plugin.Get("password")
+	require.Error(t, err)
+}
+
+func TestInitNoCommand(t *testing.T) {
+	plugin := &Exec{}
+	require.Error(t, plugin.Init())
+}
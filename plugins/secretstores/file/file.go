@@ -0,0 +1,69 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  ## Unique identifier of the store, used in references as "@{<id>:<key>}".
+  id = "secrets"
+
+  ## Directory containing one file per secret, the file name being the key.
+  ## Trailing newlines are removed from the secret.
+  directory = "/run/secrets"
+`
+
+type File struct {
+	Directory string `toml:"directory"`
+}
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Read secrets from files in a directory"
+}
+
+func (f *File) Init() error {
+	if f.Directory == "" {
+		return errors.New("directory is required")
+	}
+	info, err := os.Stat(f.Directory)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", f.Directory)
+	}
+	return nil
+}
+
+func (f *File) Get(key string) ([]byte, error) {
+	if key == "" || key == "." || key == ".." || filepath.Base(key) != key {
+		return nil, fmt.Errorf("invalid key %q", key)
+	}
+
+	secret, err := ioutil.ReadFile(filepath.Join(f.Directory, key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("secret %q not found", key)
+		}
+		return nil, err
+	}
+	return bytes.TrimRight(secret, "\r\n"), nil
+}
+
+func init() {
+	secretstores.Add("file", func() telegraf.SecretStore {
+		return &File{}
+	})
+}
@@ -0,0 +1,51 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "password"), []byte("hunter2\n"), 0600)
+	require.NoError(t, err)
+
+	plugin := &File{Directory: dir}
+	require.NoError(t, plugin.Init())
+
+	secret, err := plugin.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, []byte("hunter2"), secret)
+
+	_, err = plugin.Get("missing")
+	require.Error(t, err)
+}
+
+func TestGetInvalidKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	plugin := &File{Directory: dir}
+	require.NoError(t, plugin.Init())
+
+	for _, key := range []string{"", "..", "../etc/passwd", "a/b"} {
+		_, err := plugin.Get(key)
+		require.Error(t, err, key)
+	}
+}
+
+func TestInitMissingDirectory(t *testing.T) {
+	plugin := &File{}
+	require.Error(t, plugin.Init())
+
+	plugin = &File{Directory: "/nonexistent/secrets"}
+	require.Error(t, plugin.Init())
+}
@@ -0,0 +1,250 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+)
+
+// DefaultColumnOrder are the column groups in their default order.
+var DefaultColumnOrder = []string{"timestamp", "name", "tags", "fields"}
+
+type Config struct {
+	// Delimiter is the character between the values, a comma by default.
+	Delimiter string
+	// Header enables a header row with the column names, written before
+	// the first row.
+	Header bool
+	// TimestampFormat is one of "unix", "unix_ms", "unix_us", "unix_ns" or
+	// "rfc3339", "unix" by default.
+	TimestampFormat string
+	// ColumnOrder is the order of the column groups "timestamp", "name",
+	// "tags" and "fields", groups that are left out are not written.
+	ColumnOrder []string
+}
+
+// Serializer writes metrics as rows of comma separated values.  The columns
+// are the tags and fields of the first serialized batch, each in the order of
+// their keys, so all rows have the same columns.  Metrics have empty values
+// for the columns they do not have, tags and fields that are not in the
+// columns are dropped.  The header is written before the first row, unless
+// the output writes it with Header.
+type Serializer struct {
+	delimiter       rune
+	header          bool
+	omitHeader      bool
+	timestampFormat string
+	columnOrder     []string
+
+	// tags and fields are the keys of the columns, nil until the first
+	// metric is serialized.
+	tags   []string
+	fields []string
+}
+
+func NewSerializer(config *Config) (*Serializer, error) {
+	s := &Serializer{
+		delimiter:       ',',
+		header:          config.Header,
+		timestampFormat: config.TimestampFormat,
+		columnOrder:     config.ColumnOrder,
+	}
+
+	if config.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(config.Delimiter)
+		if size != len(config.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return nil, fmt.Errorf("invalid delimiter %q", config.Delimiter)
+		}
+		s.delimiter = r
+	}
+
+	switch s.timestampFormat {
+	case "":
+		s.timestampFormat = "unix"
+	case "unix", "unix_ms", "unix_us", "unix_ns", "rfc3339":
+	default:
+		return nil, fmt.Errorf("invalid timestamp format %q", s.timestampFormat)
+	}
+
+	if len(s.columnOrder) == 0 {
+		s.columnOrder = DefaultColumnOrder
+	}
+	seen := make(map[string]bool, len(s.columnOrder))
+	for _, group := range s.columnOrder {
+		switch group {
+		case "timestamp", "name", "tags", "fields":
+		default:
+			return nil, fmt.Errorf("invalid column %q", group)
+		}
+		if seen[group] {
+			return nil, fmt.Errorf("duplicate column %q", group)
+		}
+		seen[group] = true
+	}
+
+	return s, nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if len(metrics) == 0 {
+		return []byte{}, nil
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = s.delimiter
+
+	if s.tags == nil {
+		s.tags, s.fields = columns(metrics)
+		if s.header && !s.omitHeader {
+			if err := w.Write(s.headerRow()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, m := range metrics {
+		if err := w.Write(s.row(m)); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Header returns the header row for the columns, nil without csv_header or
+// before the first metric is serialized.
+func (s *Serializer) Header() []byte {
+	if !s.header || s.tags == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = s.delimiter
+	if err := w.Write(s.headerRow()); err != nil {
+		return nil
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+// OmitHeader stops the serializer from writing the header before the first
+// row, the output writes it with Header instead.
+func (s *Serializer) OmitHeader() {
+	s.omitHeader = true
+}
+
+// columns returns the sorted keys of the tags and fields of the metrics.
+func columns(metrics []telegraf.Metric) ([]string, []string) {
+	tagKeys := make(map[string]bool)
+	fieldKeys := make(map[string]bool)
+	for _, m := range metrics {
+		for _, tag := range m.TagList() {
+			tagKeys[tag.Key] = true
+		}
+		for _, field := range m.FieldList() {
+			fieldKeys[field.Key] = true
+		}
+	}
+	return sortedKeys(tagKeys), sortedKeys(fieldKeys)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Serializer) headerRow() []string {
+	row := make([]string, 0, 2+len(s.tags)+len(s.fields))
+	for _, group := range s.columnOrder {
+		switch group {
+		case "timestamp":
+			row = append(row, "timestamp")
+		case "name":
+			row = append(row, "name")
+		case "tags":
+			row = append(row, s.tags...)
+		case "fields":
+			row = append(row, s.fields...)
+		}
+	}
+	return row
+}
+
+func (s *Serializer) row(m telegraf.Metric) []string {
+	row := make([]string, 0, 2+len(s.tags)+len(s.fields))
+	for _, group := range s.columnOrder {
+		switch group {
+		case "timestamp":
+			row = append(row, s.formatTimestamp(m.Time()))
+		case "name":
+			row = append(row, m.Name())
+		case "tags":
+			for _, key := range s.tags {
+				value, _ := m.GetTag(key)
+				row = append(row, value)
+			}
+		case "fields":
+			for _, key := range s.fields {
+				value, ok := m.GetField(key)
+				if !ok {
+					row = append(row, "")
+					continue
+				}
+				row = append(row, formatField(value))
+			}
+		}
+	}
+	return row
+}
+
+func (s *Serializer) formatTimestamp(t time.Time) string {
+	switch s.timestampFormat {
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	case "unix_us":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Microsecond), 10)
+	case "unix_ns":
+		return strconv.FormatInt(t.UnixNano(), 10)
+	case "rfc3339":
+		return t.UTC().Format(time.RFC3339Nano)
+	default:
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+}
+
+func formatField(value interface{}) string {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,200 @@
+package csv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var ts = time.Date(2020, 11, 2, 12, 0, 0, 500000000, time.UTC)
+
+func TestSerialize(t *testing.T) {
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{
+			"usage_user": 1.5,
+			"processes":  int64(3),
+			"uptime":     uint64(42),
+			"ok":         true,
+			"state":      "running, ok",
+		},
+		ts)
+
+	s, err := NewSerializer(&Config{})
+	require.NoError(t, err)
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "1604318400,cpu,cpu0,localhost,true,3,\"running, ok\",42,1.5\n", string(buf))
+}
+
+func TestSerializeHeaderOnce(t *testing.T) {
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 1.0},
+		ts)
+
+	s, err := NewSerializer(&Config{Header: true})
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "timestamp,name,host,value\n1604318400,cpu,localhost,1\n", string(buf))
+
+	buf, err = s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "1604318400,cpu,localhost,1\n", string(buf))
+}
+
+func TestSerializeBatchDifferentFields(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"user": 1.0, "system": 2.0},
+			ts),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "b", "cpu": "cpu0"},
+			map[string]interface{}{"idle": 97.0},
+			ts),
+	}
+
+	s, err := NewSerializer(&Config{Header: true})
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+	expected := "timestamp,name,cpu,host,idle,system,user\n" +
+		"1604318400,cpu,,a,,2,1\n" +
+		"1604318400,cpu,cpu0,b,97,,\n"
+	require.Equal(t, expected, string(buf))
+
+	// The columns are those of the first batch, new tags and fields are
+	// dropped and the header is not written again.
+	buf, err = s.SerializeBatch([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "c", "rack": "r1"},
+			map[string]interface{}{"user": 3.0, "steal": 1.0},
+			ts),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "1604318400,cpu,,c,,,3\n", string(buf))
+}
+
+func TestSerializeMixedMeasurements(t *testing.T) {
+	cpu := testutil.MustMetric("cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 1.5},
+		ts)
+	mem := testutil.MustMetric("mem",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"used": int64(42)},
+		ts)
+
+	s, err := NewSerializer(&Config{Header: true})
+	require.NoError(t, err)
+
+	var actual string
+	for _, m := range []telegraf.Metric{cpu, mem, cpu, mem} {
+		buf, err := s.Serialize(m)
+		require.NoError(t, err)
+		actual += string(buf)
+	}
+	expected := "timestamp,name,host,usage\n" +
+		"1604318400,cpu,a,1.5\n" +
+		"1604318400,mem,a,\n" +
+		"1604318400,cpu,a,1.5\n" +
+		"1604318400,mem,a,\n"
+	require.Equal(t, expected, actual)
+}
+
+func TestSerializeOmitHeader(t *testing.T) {
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 1.0},
+		ts)
+
+	s, err := NewSerializer(&Config{Header: true, Delimiter: ";"})
+	require.NoError(t, err)
+	s.OmitHeader()
+	require.Nil(t, s.Header())
+
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "1604318400;cpu;localhost;1\n", string(buf))
+	require.Equal(t, "timestamp;name;host;value\n", string(s.Header()))
+
+	s, err = NewSerializer(&Config{})
+	require.NoError(t, err)
+	_, err = s.Serialize(m)
+	require.NoError(t, err)
+	require.Nil(t, s.Header())
+}
+
+func TestSerializeOptions(t *testing.T) {
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 1.0},
+		ts)
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{
+			name:     "rfc3339",
+			config:   Config{TimestampFormat: "rfc3339"},
+			expected: "2020-11-02T12:00:00.5Z,cpu,localhost,1\n",
+		},
+		{
+			name:     "unix_ms",
+			config:   Config{TimestampFormat: "unix_ms"},
+			expected: "1604318400500,cpu,localhost,1\n",
+		},
+		{
+			name:     "unix_ns",
+			config:   Config{TimestampFormat: "unix_ns"},
+			expected: "1604318400500000000,cpu,localhost,1\n",
+		},
+		{
+			name:     "delimiter",
+			config:   Config{Delimiter: ";"},
+			expected: "1604318400;cpu;localhost;1\n",
+		},
+		{
+			name:     "column order",
+			config:   Config{ColumnOrder: []string{"name", "fields", "tags"}, Header: true},
+			expected: "name,value,host\ncpu,1,localhost\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSerializer(&tt.config)
+			require.NoError(t, err)
+			buf, err := s.Serialize(m)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(buf))
+		})
+	}
+}
+
+func TestNewSerializerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		err    string
+	}{
+		{"delimiter", Config{Delimiter: ",,"}, `invalid delimiter ",,"`},
+		{"quote delimiter", Config{Delimiter: `"`}, `invalid delimiter "\""`},
+		{"timestamp format", Config{TimestampFormat: "iso"}, `invalid timestamp format "iso"`},
+		{"column", Config{ColumnOrder: []string{"time"}}, `invalid column "time"`},
+		{"duplicate column", Config{ColumnOrder: []string{"tags", "tags"}}, `duplicate column "tags"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSerializer(&tt.config)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package msgpack
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// muint8 is the marker of an 8 bit unsigned integer.  Unsigned values below
+// 128 are written with it, instead of the positive fixint that is read back
+// as a signed integer.
+const muint8 = 0xcc
+
+// Serializer writes each metric as a MessagePack map with the keys "name",
+// "time" in nanoseconds, "tags" and "fields".  Integers are written in the
+// signed and unsigned families of MessagePack so that their types survive.
+type Serializer struct{}
+
+func NewSerializer() (*Serializer, error) {
+	return &Serializer{}, nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return appendMetric(nil, metric)
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf []byte
+	for _, m := range metrics {
+		var err error
+		buf, err = appendMetric(buf, m)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func appendMetric(buf []byte, m telegraf.Metric) ([]byte, error) {
+	buf = msgp.AppendMapHeader(buf, 4)
+	buf = msgp.AppendString(buf, "name")
+	buf = msgp.AppendString(buf, m.Name())
+	buf = msgp.AppendString(buf, "time")
+	buf = msgp.AppendInt64(buf, m.Time().UnixNano())
+
+	buf = msgp.AppendString(buf, "tags")
+	tags := m.TagList()
+	buf = msgp.AppendMapHeader(buf, uint32(len(tags)))
+	for _, tag := range tags {
+		buf = msgp.AppendString(buf, tag.Key)
+		buf = msgp.AppendString(buf, tag.Value)
+	}
+
+	buf = msgp.AppendString(buf, "fields")
+	fields := m.FieldList()
+	buf = msgp.AppendMapHeader(buf, uint32(len(fields)))
+	for _, field := range fields {
+		buf = msgp.AppendString(buf, field.Key)
+		switch v := field.Value.(type) {
+		case int64:
+			buf = msgp.AppendInt64(buf, v)
+		case uint64:
+			if v <= 127 {
+				buf = append(buf, muint8, byte(v))
+			} else {
+				buf = msgp.AppendUint64(buf, v)
+			}
+		case float64:
+			buf = msgp.AppendFloat64(buf, v)
+		case bool:
+			buf = msgp.AppendBool(buf, v)
+		case string:
+			buf = msgp.AppendString(buf, v)
+		default:
+			return nil, fmt.Errorf("unsupported type %T of field %q", v, field.Key)
+		}
+	}
+	return buf, nil
+}
@@ -0,0 +1,108 @@
+package msgpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestSerialize(t *testing.T) {
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage": 42.5},
+		time.Unix(1604318400, 123456789))
+
+	s, err := NewSerializer()
+	require.NoError(t, err)
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	var expected []byte
+	expected = msgp.AppendMapHeader(expected, 4)
+	expected = msgp.AppendString(expected, "name")
+	expected = msgp.AppendString(expected, "cpu")
+	expected = msgp.AppendString(expected, "time")
+	expected = msgp.AppendInt64(expected, 1604318400123456789)
+	expected = msgp.AppendString(expected, "tags")
+	expected = msgp.AppendMapStrStr(expected, map[string]string{"host": "localhost"})
+	expected = msgp.AppendString(expected, "fields")
+	expected = msgp.AppendMapHeader(expected, 1)
+	expected = msgp.AppendString(expected, "usage")
+	expected = msgp.AppendFloat64(expected, 42.5)
+	require.Equal(t, expected, buf)
+}
+
+func TestSerializeIntegerTypes(t *testing.T) {
+	m := testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{
+			"int":  int64(1),
+			"uint": uint64(1),
+		},
+		time.Unix(0, 0))
+
+	s, err := NewSerializer()
+	require.NoError(t, err)
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	fields := make(map[string]msgp.Type)
+	buf, err = skipTo(buf, "fields")
+	require.NoError(t, err)
+	n, buf, err := msgp.ReadMapHeaderBytes(buf)
+	require.NoError(t, err)
+	for i := uint32(0); i < n; i++ {
+		var key string
+		key, buf, err = msgp.ReadStringBytes(buf)
+		require.NoError(t, err)
+		fields[key] = msgp.NextType(buf)
+		buf, err = msgp.Skip(buf)
+		require.NoError(t, err)
+	}
+	require.Equal(t, map[string]msgp.Type{"int": msgp.IntType, "uint": msgp.UintType}, fields)
+}
+
+func TestSerializeBatch(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+	}
+
+	s, err := NewSerializer()
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	var expected []byte
+	for _, m := range metrics {
+		single, err := s.Serialize(m)
+		require.NoError(t, err)
+		expected = append(expected, single...)
+	}
+	require.Equal(t, expected, buf)
+}
+
+// skipTo returns the buffer at the value of the key of the map.
+func skipTo(buf []byte, key string) ([]byte, error) {
+	n, buf, err := msgp.ReadMapHeaderBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < n; i++ {
+		var k string
+		if k, buf, err = msgp.ReadStringBytes(buf); err != nil {
+			return nil, err
+		}
+		if k == key {
+			return buf, nil
+		}
+		if buf, err = msgp.Skip(buf); err != nil {
+			return nil, err
+		}
+	}
+	return nil, msgp.ErrShortBytes
+}
@@ -0,0 +1,206 @@
+package prometheusremotewrite
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/prompb"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
+)
+
+// MetricSortOrder controls if the output is sorted.
+type MetricSortOrder int
+
+const (
+	NoSortMetrics MetricSortOrder = iota
+	SortMetrics
+)
+
+// StringHandling defines how to process string fields.
+type StringHandling int
+
+const (
+	DiscardStrings StringHandling = iota
+	StringAsLabel
+)
+
+type FormatConfig struct {
+	MetricSortOrder MetricSortOrder
+	StringHandling  StringHandling
+}
+
+// Serializer creates snappy compressed Prometheus remote write requests.
+type Serializer struct {
+	config FormatConfig
+}
+
+func NewSerializer(config FormatConfig) (*Serializer, error) {
+	s := &Serializer{config: config}
+	return s, nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	req, err := s.WriteRequest(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, data), nil
+}
+
+// WriteRequest converts the metrics into an uncompressed remote write
+// request.  Every numeric field becomes a sample of the time series named
+// after the measurement and field key, following the naming of the
+// prometheus serializer.  Samples of the same series are kept together in
+// the order of their timestamps.
+func (s *Serializer) WriteRequest(metrics []telegraf.Metric) (*prompb.WriteRequest, error) {
+	series := make(map[string]*prompb.TimeSeries)
+	keys := make([]string, 0)
+
+	for _, metric := range metrics {
+		labels := s.createLabels(metric)
+		for _, field := range metric.FieldList() {
+			value, ok := prometheus.SampleValue(field.Value)
+			if !ok {
+				continue
+			}
+
+			name, ok := prometheus.SanitizeMetricName(metricName(metric, field.Key))
+			if !ok {
+				continue
+			}
+
+			sampleLabels := make([]prompb.Label, 0, len(labels)+1)
+			sampleLabels = append(sampleLabels, prompb.Label{Name: "__name__", Value: name})
+			for _, label := range labels {
+				if !keepLabel(metric.Type(), field.Key, label.Name) {
+					continue
+				}
+				sampleLabels = append(sampleLabels, label)
+			}
+			sort.Slice(sampleLabels, func(i, j int) bool {
+				return sampleLabels[i].Name < sampleLabels[j].Name
+			})
+
+			key := seriesKey(sampleLabels)
+			ts, ok := series[key]
+			if !ok {
+				ts = &prompb.TimeSeries{Labels: sampleLabels}
+				series[key] = ts
+				keys = append(keys, key)
+			}
+			ts.Samples = append(ts.Samples, prompb.Sample{
+				Value:     value,
+				Timestamp: metric.Time().UnixNano() / 1000000,
+			})
+		}
+	}
+
+	if s.config.MetricSortOrder == SortMetrics {
+		sort.Strings(keys)
+	}
+
+	req := &prompb.WriteRequest{
+		Timeseries: make([]prompb.TimeSeries, 0, len(keys)),
+	}
+	for _, key := range keys {
+		ts := series[key]
+		sort.SliceStable(ts.Samples, func(i, j int) bool {
+			return ts.Samples[i].Timestamp < ts.Samples[j].Timestamp
+		})
+		req.Timeseries = append(req.Timeseries, *ts)
+	}
+	return req, nil
+}
+
+func (s *Serializer) createLabels(metric telegraf.Metric) []prompb.Label {
+	labels := make([]prompb.Label, 0, len(metric.TagList()))
+	for _, tag := range metric.TagList() {
+		name, ok := prometheus.SanitizeLabelName(tag.Key)
+		if !ok {
+			continue
+		}
+		labels = append(labels, prompb.Label{Name: name, Value: tag.Value})
+	}
+
+	if s.config.StringHandling != StringAsLabel {
+		return labels
+	}
+
+	for _, field := range metric.FieldList() {
+		value, ok := field.Value.(string)
+		if !ok {
+			continue
+		}
+
+		name, ok := prometheus.SanitizeLabelName(field.Key)
+		if !ok {
+			continue
+		}
+
+		// If there is a tag with the same name as the string field, discard
+		// the field and use the tag instead.
+		if hasLabel(name, labels) {
+			continue
+		}
+
+		labels = append(labels, prompb.Label{Name: name, Value: value})
+	}
+	return labels
+}
+
+// metricName returns the series name of the field.  Unlike the text format,
+// remote write has no metric families so the _bucket, _sum and _count
+// suffixes of histograms and summaries are kept in the series name.
+func metricName(metric telegraf.Metric, fieldKey string) string {
+	if metric.Name() == "prometheus" {
+		return fieldKey
+	}
+	return metric.Name() + "_" + fieldKey
+}
+
+// keepLabel reports if the label belongs to the series of the field.  The le
+// and quantile tags only apply to the bucket and quantile samples.
+func keepLabel(valueType telegraf.ValueType, fieldKey, name string) bool {
+	switch valueType {
+	case telegraf.Histogram:
+		if name == "le" {
+			return strings.HasSuffix(fieldKey, "_bucket")
+		}
+	case telegraf.Summary:
+		if name == "quantile" {
+			return !strings.HasSuffix(fieldKey, "_sum") && !strings.HasSuffix(fieldKey, "_count")
+		}
+	}
+	return true
+}
+
+func hasLabel(name string, labels []prompb.Label) bool {
+	for _, label := range labels {
+		if name == label.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func seriesKey(labels []prompb.Label) string {
+	var b strings.Builder
+	for _, label := range labels {
+		b.WriteString(label.Name)
+		b.WriteByte(0)
+		b.WriteString(label.Value)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
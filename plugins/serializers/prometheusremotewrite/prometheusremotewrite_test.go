@@ -0,0 +1,269 @@
+package prometheusremotewrite
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/prompb"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, data []byte) []prompb.TimeSeries {
+	buf, err := snappy.Decode(nil, data)
+	require.NoError(t, err)
+
+	var req prompb.WriteRequest
+	require.NoError(t, req.Unmarshal(buf))
+	return req.Timeseries
+}
+
+func series(labels map[string]string, samples ...prompb.Sample) prompb.TimeSeries {
+	ts := prompb.TimeSeries{Samples: samples}
+	for name, value := range labels {
+		ts.Labels = append(ts.Labels, prompb.Label{Name: name, Value: value})
+	}
+	sort.Slice(ts.Labels, func(i, j int) bool {
+		return ts.Labels[i].Name < ts.Labels[j].Name
+	})
+	return ts
+}
+
+func TestSerializeBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   FormatConfig
+		metrics  []telegraf.Metric
+		expected []prompb.TimeSeries
+	}{
+		{
+			name: "simple",
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host": "example.org",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(1, 0),
+				),
+			},
+			expected: []prompb.TimeSeries{
+				series(map[string]string{"__name__": "cpu_time_idle", "host": "example.org"},
+					prompb.Sample{Value: 42, Timestamp: 1000}),
+			},
+		},
+		{
+			name: "prometheus input untyped",
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{
+						"code":   "400",
+						"method": "post",
+					},
+					map[string]interface{}{
+						"http_requests_total": 3.0,
+					},
+					time.Unix(0, 0),
+					telegraf.Untyped,
+				),
+			},
+			expected: []prompb.TimeSeries{
+				series(map[string]string{"__name__": "http_requests_total", "code": "400", "method": "post"},
+					prompb.Sample{Value: 3, Timestamp: 0}),
+			},
+		},
+		{
+			name: "samples are grouped by series in time order",
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"time_idle": 43,
+					},
+					time.Unix(2, 0),
+				),
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"time_idle": 42,
+					},
+					time.Unix(1, 0),
+				),
+			},
+			expected: []prompb.TimeSeries{
+				series(map[string]string{"__name__": "cpu_time_idle"},
+					prompb.Sample{Value: 42, Timestamp: 1000},
+					prompb.Sample{Value: 43, Timestamp: 2000}),
+			},
+		},
+		{
+			name: "invalid names are sanitized",
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu:xyzzy",
+					map[string]string{
+						"host-name": "example.org",
+					},
+					map[string]interface{}{
+						"time-idle": uint64(42),
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{
+						{Name: "__name__", Value: "cpu:xyzzy_time_idle"},
+						{Name: "host_name", Value: "example.org"},
+					},
+					Samples: []prompb.Sample{{Value: 42, Timestamp: 0}},
+				},
+			},
+		},
+		{
+			name: "strings are discarded",
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"state":     "idle",
+						"time_idle": true,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []prompb.TimeSeries{
+				series(map[string]string{"__name__": "cpu_time_idle"},
+					prompb.Sample{Value: 1, Timestamp: 0}),
+			},
+		},
+		{
+			name: "string as label",
+			config: FormatConfig{
+				StringHandling: StringAsLabel,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"state":     "idle",
+						"time_idle": 42,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []prompb.TimeSeries{
+				series(map[string]string{"__name__": "cpu_time_idle", "state": "idle"},
+					prompb.Sample{Value: 42, Timestamp: 0}),
+			},
+		},
+		{
+			name: "prometheus histogram",
+			config: FormatConfig{
+				MetricSortOrder: SortMetrics,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{},
+					map[string]interface{}{
+						"http_request_duration_seconds_sum":   53423,
+						"http_request_duration_seconds_count": 144320,
+					},
+					time.Unix(0, 0),
+					telegraf.Histogram,
+				),
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{"le": "0.5"},
+					map[string]interface{}{
+						"http_request_duration_seconds_bucket": 129389.0,
+					},
+					time.Unix(0, 0),
+					telegraf.Histogram,
+				),
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{"le": "+Inf"},
+					map[string]interface{}{
+						"http_request_duration_seconds_bucket": 144320.0,
+					},
+					time.Unix(0, 0),
+					telegraf.Histogram,
+				),
+			},
+			expected: []prompb.TimeSeries{
+				series(map[string]string{"__name__": "http_request_duration_seconds_bucket", "le": "+Inf"},
+					prompb.Sample{Value: 144320, Timestamp: 0}),
+				series(map[string]string{"__name__": "http_request_duration_seconds_bucket", "le": "0.5"},
+					prompb.Sample{Value: 129389, Timestamp: 0}),
+				series(map[string]string{"__name__": "http_request_duration_seconds_count"},
+					prompb.Sample{Value: 144320, Timestamp: 0}),
+				series(map[string]string{"__name__": "http_request_duration_seconds_sum"},
+					prompb.Sample{Value: 53423, Timestamp: 0}),
+			},
+		},
+		{
+			name: "summary with quantile tag",
+			config: FormatConfig{
+				MetricSortOrder: SortMetrics,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{"quantile": "0.5"},
+					map[string]interface{}{
+						"rpc_duration_seconds":       3102.0,
+						"rpc_duration_seconds_count": 2693,
+					},
+					time.Unix(0, 0),
+					telegraf.Summary,
+				),
+			},
+			expected: []prompb.TimeSeries{
+				series(map[string]string{"__name__": "rpc_duration_seconds", "quantile": "0.5"},
+					prompb.Sample{Value: 3102, Timestamp: 0}),
+				series(map[string]string{"__name__": "rpc_duration_seconds_count"},
+					prompb.Sample{Value: 2693, Timestamp: 0}),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSerializer(tt.config)
+			require.NoError(t, err)
+
+			data, err := s.SerializeBatch(tt.metrics)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, decode(t, data))
+		})
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	s, err := NewSerializer(FormatConfig{})
+	require.NoError(t, err)
+
+	data, err := s.Serialize(testutil.MustMetric(
+		"cpu",
+		map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"time_idle": 42.0},
+		time.Unix(0, 1500000),
+	))
+	require.NoError(t, err)
+	require.Equal(t, []prompb.TimeSeries{
+		series(map[string]string{"__name__": "cpu_time_idle", "cpu": "cpu0"},
+			prompb.Sample{Value: 42, Timestamp: 1}),
+	}, decode(t, data))
+}
@@ -6,11 +6,14 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/serializers/carbon2"
+	"github.com/influxdata/telegraf/plugins/serializers/csv"
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
 	"github.com/influxdata/telegraf/plugins/serializers/nowmetric"
 	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheusremotewrite"
 	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
 	"github.com/influxdata/telegraf/plugins/serializers/wavefront"
 )
@@ -22,6 +25,31 @@ type SerializerOutput interface {
 	SetSerializer(serializer Serializer)
 }
 
+// BatchSerializerOutput is an interface for serializer outputs that tell
+// whether they serialize the metrics of a write as a batch.  Outputs not
+// implementing it are assumed to serialize each metric on its own.
+type BatchSerializerOutput interface {
+	// UsesBatchFormat returns true if the metrics of a write are serialized
+	// with SerializeBatch.
+	UsesBatchFormat() bool
+}
+
+// HeaderSerializer is a Serializer of a format starting with a header, such
+// as csv.  By default the header is written before the first serialized
+// metric.  Outputs writing to files can write it at the start of each file
+// instead.
+type HeaderSerializer interface {
+	Serializer
+
+	// Header returns the header for the metrics serialized so far, nil if
+	// there is none yet.
+	Header() []byte
+
+	// OmitHeader stops the serializer from writing the header before the
+	// first serialized metric.
+	OmitHeader()
+}
+
 // Serializer is an interface defining functions that a serializer plugin must
 // satisfy.
 //
@@ -77,6 +105,19 @@ type Config struct {
 	// Templates same Template, but multiple
 	Templates []string `toml:"templates"`
 
+	// Character between the values of the csv format, a comma by default
+	CSVDelimiter string `toml:"csv_delimiter"`
+
+	// Write a header row at the start of the csv format
+	CSVHeader bool `toml:"csv_header"`
+
+	// Timestamp format of the csv format
+	CSVTimestampFormat string `toml:"csv_timestamp_format"`
+
+	// Order of the timestamp, name, tags and fields columns of the csv
+	// format
+	CSVColumnOrder []string `toml:"csv_column_order"`
+
 	// Timestamp units to use for JSON formatted output
 	TimestampUnits time.Duration `toml:"timestamp_units"`
 
@@ -124,8 +165,14 @@ func NewSerializer(config *Config) (Serializer, error) {
 		serializer, err = NewCarbon2Serializer(config.Carbon2Format)
 	case "wavefront":
 		serializer, err = NewWavefrontSerializer(config.Prefix, config.WavefrontUseStrict, config.WavefrontSourceOverride)
+	case "msgpack":
+		serializer, err = NewMsgpackSerializer()
+	case "csv":
+		serializer, err = NewCSVSerializer(config)
 	case "prometheus":
 		serializer, err = NewPrometheusSerializer(config)
+	case "prometheusremotewrite":
+		serializer, err = NewPrometheusRemoteWriteSerializer(config)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -155,6 +202,23 @@ func NewPrometheusSerializer(config *Config) (Serializer, error) {
 	})
 }
 
+func NewPrometheusRemoteWriteSerializer(config *Config) (Serializer, error) {
+	sortMetrics := prometheusremotewrite.NoSortMetrics
+	if config.PrometheusSortMetrics {
+		sortMetrics = prometheusremotewrite.SortMetrics
+	}
+
+	stringAsLabels := prometheusremotewrite.DiscardStrings
+	if config.PrometheusStringAsLabel {
+		stringAsLabels = prometheusremotewrite.StringAsLabel
+	}
+
+	return prometheusremotewrite.NewSerializer(prometheusremotewrite.FormatConfig{
+		MetricSortOrder: sortMetrics,
+		StringHandling:  stringAsLabels,
+	})
+}
+
 func NewWavefrontSerializer(prefix string, useStrict bool, sourceOverride []string) (Serializer, error) {
 	return wavefront.NewSerializer(prefix, useStrict, sourceOverride)
 }
@@ -163,6 +227,19 @@ func NewJsonSerializer(timestampUnits time.Duration) (Serializer, error) {
 	return json.NewSerializer(timestampUnits)
 }
 
+func NewMsgpackSerializer() (Serializer, error) {
+	return msgpack.NewSerializer()
+}
+
+func NewCSVSerializer(config *Config) (Serializer, error) {
+	return csv.NewSerializer(&csv.Config{
+		Delimiter:       config.CSVDelimiter,
+		Header:          config.CSVHeader,
+		TimestampFormat: config.CSVTimestampFormat,
+		ColumnOrder:     config.CSVColumnOrder,
+	})
+}
+
 func NewCarbon2Serializer(carbon2format string) (Serializer, error) {
 	return carbon2.NewSerializer(carbon2format)
 }
@@ -0,0 +1,10 @@
+package telegraf
+
+// SecretStore is a source of secrets, such as passwords and tokens, that are
+// referenced in the plugin configuration as `@{<id>:<key>}`.
+type SecretStore interface {
+	PluginDescriber
+
+	// Get returns the secret stored under the key.
+	Get(key string) ([]byte, error)
+}